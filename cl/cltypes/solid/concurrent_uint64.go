@@ -0,0 +1,314 @@
+package solid
+
+import (
+	"io"
+	"sync"
+
+	"github.com/ledgerwatch/erigon-lib/types/clonable"
+)
+
+// concurrentUint64SSZ guards a uint64 list with a RWMutex, so that it can be read by many goroutines (e.g.
+// the beacon API serving balances of the head state) while a single one mutates it. Hashing takes the write lock, as
+// it updates the memoized merkle tree.
+type concurrentUint64SSZ struct {
+	mu sync.RWMutex
+	u  Uint64ListSSZ
+}
+
+// NewConcurrentUint64SSZ wraps the list u, making it safe for concurrent use. u must not be used directly
+// anymore. Functions passed to the Range methods run with the read lock held, so they must not mutate the container.
+func NewConcurrentUint64SSZ(u Uint64ListSSZ) Uint64ListSSZ {
+	return &concurrentUint64SSZ{u: u}
+}
+
+func (c *concurrentUint64SSZ) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.u.Clear()
+}
+
+// CopyTo never holds both locks: a concurrent target gets a copy-on-write snapshot of c, taken under the write lock as
+// it flags c shared, so that a.CopyTo(b) racing b.CopyTo(a) can't deadlock. The elements are copied once, into t.
+func (c *concurrentUint64SSZ) CopyTo(target IterableSSZ[uint64]) {
+	t, ok := target.(*concurrentUint64SSZ)
+	if !ok {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		c.u.CopyTo(target)
+		return
+	}
+	if t == c {
+		return
+	}
+	c.mu.Lock()
+	snapshot := c.u.CloneShared()
+	c.mu.Unlock()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	snapshot.CopyTo(t.u)
+}
+
+func (c *concurrentUint64SSZ) Range(fn func(index int, value uint64, length int) bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	c.u.Range(fn)
+}
+
+func (c *concurrentUint64SSZ) RangeFrom(start int, fn func(index int, value uint64, length int) bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	c.u.RangeFrom(start, fn)
+}
+
+func (c *concurrentUint64SSZ) RangeReverse(fn func(index int, value uint64, length int) bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	c.u.RangeReverse(fn)
+}
+
+func (c *concurrentUint64SSZ) Get(index int) uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.u.Get(index)
+}
+
+func (c *concurrentUint64SSZ) GetErr(index int) (uint64, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.u.GetErr(index)
+}
+
+func (c *concurrentUint64SSZ) Set(index int, v uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.u.Set(index, v)
+}
+
+func (c *concurrentUint64SSZ) SetErr(index int, v uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.u.SetErr(index, v)
+}
+
+func (c *concurrentUint64SSZ) SetAligned(start int, vals []uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.u.SetAligned(start, vals)
+}
+
+func (c *concurrentUint64SSZ) SetRange(start int, vals []uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.u.SetRange(start, vals)
+}
+
+func (c *concurrentUint64SSZ) Length() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.u.Length()
+}
+
+func (c *concurrentUint64SSZ) Cap() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.u.Cap()
+}
+
+// Bytes returns a copy of the encoded elements, the backing buffer can't be handed out without the lock.
+func (c *concurrentUint64SSZ) Bytes() []byte {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]byte(nil), c.u.Bytes()...)
+}
+
+func (c *concurrentUint64SSZ) Pop() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.u.Pop()
+}
+
+func (c *concurrentUint64SSZ) Append(v uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.u.Append(v)
+}
+
+func (c *concurrentUint64SSZ) Insert(index int, v uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.u.Insert(index, v)
+}
+
+func (c *concurrentUint64SSZ) RemoveAt(index int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.u.RemoveAt(index)
+}
+
+func (c *concurrentUint64SSZ) Truncate(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.u.Truncate(n)
+}
+
+func (c *concurrentUint64SSZ) AppendMultiple(vals ...uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.u.AppendMultiple(vals...)
+}
+
+func (c *concurrentUint64SSZ) Static() bool {
+	return c.u.Static()
+}
+
+func (c *concurrentUint64SSZ) EncodeSSZ(buf []byte) ([]byte, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.u.EncodeSSZ(buf)
+}
+
+func (c *concurrentUint64SSZ) EncodingSizeSSZ() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.u.EncodingSizeSSZ()
+}
+
+func (c *concurrentUint64SSZ) DecodeSSZ(buf []byte, version int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.u.DecodeSSZ(buf, version)
+}
+
+func (c *concurrentUint64SSZ) Clone() clonable.Clonable {
+	return NewConcurrentUint64SSZ(c.u.Clone().(Uint64ListSSZ))
+}
+
+func (c *concurrentUint64SSZ) HashSSZ() ([32]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.u.HashSSZ()
+}
+
+func (c *concurrentUint64SSZ) HashSSZDelta(prevRoot [32]byte, changed []int) ([32]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.u.HashSSZDelta(prevRoot, changed)
+}
+
+func (c *concurrentUint64SSZ) MarkDirty(index int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.u.MarkDirty(index)
+}
+
+func (c *concurrentUint64SSZ) ProveIndex(i int) ([][32]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.u.ProveIndex(i)
+}
+
+func (c *concurrentUint64SSZ) ProveIndices(indices ...int) ([][][32]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.u.ProveIndices(indices...)
+}
+
+// CloneShared returns a concurrency-safe copy-on-write copy, the write lock is taken as the copy is flagged shared.
+func (c *concurrentUint64SSZ) CloneShared() IterableSSZ[uint64] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return NewConcurrentUint64SSZ(c.u.CloneShared().(Uint64ListSSZ))
+}
+
+// Begin returns a batch reading through the read lock, and committing under the write lock.
+func (c *concurrentUint64SSZ) Begin() *MutationBatch {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return &MutationBatch{target: c, base: c.u.Length(), sets: map[int]uint64{}}
+}
+
+func (c *concurrentUint64SSZ) commitBatch(base int, sets map[int]uint64, appends []uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.u.(batchCommitter).commitBatch(base, sets, appends)
+}
+
+func (c *concurrentUint64SSZ) Release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.u.Release()
+}
+
+func (c *concurrentUint64SSZ) AddAt(index int, delta int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.u.AddAt(index, delta)
+}
+
+func (c *concurrentUint64SSZ) ApplyDelta(deltas []int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.u.ApplyDelta(deltas)
+}
+
+func (c *concurrentUint64SSZ) IsSorted() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.u.IsSorted()
+}
+
+func (c *concurrentUint64SSZ) BinarySearch(v uint64) (int, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.u.BinarySearch(v)
+}
+
+func (c *concurrentUint64SSZ) InsertSorted(v uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.u.InsertSorted(v)
+}
+
+func (c *concurrentUint64SSZ) EncodeSnapshot(w io.Writer, compress bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.u.EncodeSnapshot(w, compress)
+}
+
+func (c *concurrentUint64SSZ) DecodeSnapshot(r io.Reader) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.u.DecodeSnapshot(r)
+}
+
+// Equal and Diff compare under the read lock, other being locked element by element if it is concurrent too.
+func (c *concurrentUint64SSZ) Equal(other IterableSSZ[uint64]) bool {
+	if other == IterableSSZ[uint64](c) {
+		return true
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.u.Equal(other)
+}
+
+func (c *concurrentUint64SSZ) Diff(other IterableSSZ[uint64]) []IndexedChange {
+	if other == IterableSSZ[uint64](c) {
+		return nil
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.u.Diff(other)
+}
+
+func (c *concurrentUint64SSZ) MarshalJSON() ([]byte, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.u.MarshalJSON()
+}
+
+func (c *concurrentUint64SSZ) UnmarshalJSON(buf []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.u.UnmarshalJSON(buf)
+}
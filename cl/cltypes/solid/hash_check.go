@@ -0,0 +1,163 @@
+package solid
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/ledgerwatch/erigon-lib/common/dbg"
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon/cl/merkle_tree"
+	"github.com/ledgerwatch/erigon/cl/utils"
+)
+
+// ErrHashMismatch is returned when the (cached) root of a solid container diverges from the reference merkleization.
+var ErrHashMismatch = errors.New("solid: hash_tree_root mismatch with reference merkleization")
+
+// hashCheck makes every cached HashSSZ double-check its result against the reference merkleization.
+// It roughly doubles hashing time, so it is meant for debugging sync issues only.
+var hashCheck atomic.Bool
+
+func init() {
+	hashCheck.Store(dbg.EnvBool("CAPLIN_SOLID_HASH_CHECK", false))
+}
+
+// SetHashCheck toggles the runtime differential hashing assertion of the solid containers.
+func SetHashCheck(enabled bool) {
+	hashCheck.Store(enabled)
+}
+
+// HashCheckEnabled returns whether the runtime differential hashing assertion is on.
+func HashCheckEnabled() bool {
+	return hashCheck.Load()
+}
+
+// CheckHashSSZ computes the root of obj through its own HashSSZ and through the reference merkleization,
+// returning ErrHashMismatch if they differ.
+func CheckHashSSZ(obj interface{ HashSSZ() ([32]byte, error) }) error {
+	root, err := obj.HashSSZ()
+	if err != nil {
+		return err
+	}
+	return verifyHashSSZ(obj, root)
+}
+
+// ReferenceHashSSZ computes the hash tree root of a solid container straight from its SSZ encoding, following the
+// spec definitions literally: no caches, no flat-buffer tricks and no gohashtree.
+func ReferenceHashSSZ(obj any) ([32]byte, error) {
+	switch o := obj.(type) {
+	case interface{ referenceHashSSZ() [32]byte }: // generic containers
+		return o.referenceHashSSZ(), nil
+	case Checkpoint:
+		return referenceContainerRoot(checkpointLeaves(o)), nil
+	case Validator:
+		return referenceContainerRoot(validatorLeaves(o)), nil
+	case *uint64ListSSZ:
+		return referenceMixInLength(referenceMerkleize(referencePack(o.Bytes()), (uint64(o.Cap())*8+31)/32), o.Length()), nil
+	case *RawUint64List:
+		return referenceMixInLength(referenceMerkleize(referencePack(o.Bytes()), (uint64(o.Cap())*8+31)/32), o.Length()), nil
+	case *uint64VectorSSZ:
+		chunks := referencePack(o.Bytes())
+		return referenceMerkleize(chunks, uint64(len(chunks))), nil
+	case *hashList:
+		return referenceMixInLength(referenceMerkleize(referencePack(o.Bytes()), uint64(o.Cap())), o.Length()), nil
+	case *hashVector:
+		return referenceMerkleize(referencePack(o.Bytes()), uint64(o.Length())), nil
+	case *ValidatorSet:
+		roots := make([][32]byte, o.Length())
+		for i := range roots {
+			roots[i] = referenceContainerRoot(validatorLeaves(o.Get(i)))
+		}
+		return referenceMixInLength(referenceMerkleize(roots, uint64(o.Cap())), o.Length()), nil
+	}
+	return [32]byte{}, fmt.Errorf("solid: no reference merkleization for %T", obj)
+}
+
+// verifyHashSSZ compares an already computed root of obj with the reference merkleization.
+func verifyHashSSZ(obj any, root [32]byte) error {
+	expected, err := ReferenceHashSSZ(obj)
+	if err != nil {
+		return err
+	}
+	if expected != root {
+		return fmt.Errorf("%w: %T has %x, reference %x", ErrHashMismatch, obj, root, expected)
+	}
+	return nil
+}
+
+// assertHashSSZ is called by the cached HashSSZ implementations, it is a no-op unless the hash check is enabled.
+func assertHashSSZ(obj any, root [32]byte, err error) ([32]byte, error) {
+	if err != nil || !hashCheck.Load() {
+		return root, err
+	}
+	if err := verifyHashSSZ(obj, root); err != nil {
+		return [32]byte{}, err
+	}
+	return root, nil
+}
+
+func checkpointLeaves(c Checkpoint) [][32]byte {
+	leaves := make([][32]byte, 2)
+	copy(leaves[0][:], c.RawEpoch())
+	copy(leaves[1][:], c.RawBlockRoot())
+	return leaves
+}
+
+func validatorLeaves(v Validator) [][32]byte {
+	leaves := make([][32]byte, 8)
+	leaves[0] = referenceMerkleize(referencePack(v[:48]), 2)
+	copy(leaves[1][:], v[48:80])
+	copy(leaves[2][:], v[80:88])
+	leaves[3][0] = v[88]
+	copy(leaves[4][:], v[89:97])
+	copy(leaves[5][:], v[97:105])
+	copy(leaves[6][:], v[105:113])
+	copy(leaves[7][:], v[113:121])
+	return leaves
+}
+
+// referencePack splits serialized basic values into zero-padded 32-byte chunks.
+func referencePack(b []byte) [][32]byte {
+	chunks := make([][32]byte, (len(b)+length.Hash-1)/length.Hash)
+	for i := range chunks {
+		copy(chunks[i][:], b[i*length.Hash:])
+	}
+	return chunks
+}
+
+func referenceContainerRoot(leaves [][32]byte) [32]byte {
+	return referenceMerkleize(leaves, uint64(len(leaves)))
+}
+
+// referenceMerkleize pads chunks with zero chunks up to next_pow_of_two(limit) and hashes the tree pairwise.
+func referenceMerkleize(chunks [][32]byte, limit uint64) [32]byte {
+	if limit < uint64(len(chunks)) {
+		limit = uint64(len(chunks))
+	}
+	depth := 0
+	for (uint64(1) << depth) < limit {
+		depth++
+	}
+	var zero [32]byte
+	layer := append([][32]byte{}, chunks...)
+	for d := 0; d < depth; d++ {
+		if len(layer)%2 == 1 {
+			layer = append(layer, zero)
+		}
+		next := make([][32]byte, len(layer)/2)
+		for i := range next {
+			next[i] = utils.Sha256(layer[2*i][:], layer[2*i+1][:])
+		}
+		layer = next
+		zero = utils.Sha256(zero[:], zero[:])
+	}
+	if len(layer) == 0 {
+		return zero
+	}
+	return layer[0]
+}
+
+func referenceMixInLength(root [32]byte, l int) [32]byte {
+	lengthRoot := merkle_tree.Uint64Root(uint64(l))
+	return utils.Sha256(root[:], lengthRoot[:])
+}
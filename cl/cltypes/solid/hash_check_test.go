@@ -0,0 +1,72 @@
+package solid
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReferenceHashMatchesCachedHash checks the cached merkleization against the reference one on containers of
+// random capacities and lengths, most of them not powers of two.
+func TestReferenceHashMatchesCachedHash(t *testing.T) {
+	seed := time.Now().UnixNano()
+	t.Logf("seed %d", seed)
+	r := rand.New(rand.NewSource(seed))
+
+	for i := 0; i < 200; i++ {
+		capacity := 1 + r.Intn(1500)
+		n := r.Intn(capacity + 1)
+		list := NewUint64ListSSZ(capacity)
+		raw := NewRawUint64List(capacity, nil)
+		vector := NewUint64VectorSSZ(capacity)
+		hashes := NewHashList(capacity)
+		hashVec := NewHashVector(capacity)
+		validators := NewValidatorSet(capacity)
+		roots := NewSlice(RootCodec, capacity)
+		pubkeys := NewVectorSlice(PubkeyCodec, capacity)
+		for j := 0; j < n; j++ {
+			v := r.Uint64()
+			list.Append(v)
+			raw.Append(v)
+			vector.Set(j, v)
+			hashes.Append(libcommon.Hash{byte(j), byte(v)})
+			hashVec.Set(j, libcommon.Hash{byte(v)})
+			validators.Append(NewValidatorFromParameters([48]byte{byte(j)}, libcommon.Hash{byte(v)}, v, j%2 == 0, 1, 2, 3, 4))
+			roots.Append(libcommon.Hash{byte(v), byte(j)})
+			pubkeys.Set(j, libcommon.Bytes48{byte(v)})
+		}
+		for _, obj := range []interface{ HashSSZ() ([32]byte, error) }{
+			list, raw, vector, hashes, hashVec, validators, roots, pubkeys,
+		} {
+			require.NoError(t, CheckHashSSZ(obj), "%T of capacity %d and length %d", obj, capacity, n)
+		}
+	}
+	require.NoError(t, CheckHashSSZ(NewCheckpointFromParameters(libcommon.Hash{1}, 9)))
+	require.NoError(t, CheckHashSSZ(NewValidatorFromParameters([48]byte{1}, libcommon.Hash{2}, 3, true, 1, 2, 3, 4)))
+}
+
+func TestHashCheckDetectsStaleCache(t *testing.T) {
+	SetHashCheck(true)
+	defer SetHashCheck(false)
+
+	list := NewUint64ListSSZ(128).(*uint64ListSSZ)
+	for i := 0; i < 20; i++ {
+		list.Append(uint64(i))
+	}
+	_, err := list.HashSSZ()
+	require.NoError(t, err)
+
+	// mutate the backing buffer behind the cache's back
+	list.u.u[0] = 0xff
+	_, err = list.HashSSZ()
+	require.True(t, errors.Is(err, ErrHashMismatch))
+}
+
+func TestReferenceHashUnsupported(t *testing.T) {
+	_, err := ReferenceHashSSZ(NewBitList(0, 10))
+	require.Error(t, err)
+}
@@ -156,7 +156,7 @@ func (h *hashList) HashSSZ() ([32]byte, error) {
 		}
 	}
 	lengthRoot := merkle_tree.Uint64Root(uint64(h.l))
-	return utils.Sha256(baseRoot[:], lengthRoot[:]), nil
+	return assertHashSSZ(h, utils.Sha256(baseRoot[:], lengthRoot[:]), nil)
 }
 
 func (h *hashList) Range(fn func(int, libcommon.Hash, int) bool) {
@@ -90,7 +90,8 @@ func (h *hashVector) Set(index int, newValue libcommon.Hash) {
 }
 
 func (h *hashVector) HashSSZ() ([32]byte, error) {
-	return h.u.hashVectorSSZ()
+	root, err := h.u.hashVectorSSZ()
+	return assertHashSSZ(h, root, err)
 }
 
 func (h *hashVector) Range(fn func(int, libcommon.Hash, int) bool) {
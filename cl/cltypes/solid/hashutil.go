@@ -1,5 +1,7 @@
 package solid
 
+import "github.com/ledgerwatch/erigon/cl/merkle_tree"
+
 type hashBuf struct {
 	buf []byte
 }
@@ -12,21 +14,7 @@ func (arr *hashBuf) makeBuf(size int) {
 	arr.buf = arr.buf[:size]
 }
 
+// GetDepth is merkle_tree.GetDepth: the depth of a merkle tree with v leaves, padded to the next power of two.
 func GetDepth(v uint64) uint8 {
-	// If there are 0 or 1 nodes, the depth is 0.
-	if v <= 1 {
-		return 0
-	}
-
-	// Initialize the depth to 0.
-	depth := uint8(0)
-
-	// Divide the number of nodes by 2 until it is less than or equal to 1.
-	// The number of iterations is the depth of the tree.
-	for v > 1 {
-		v >>= 1
-		depth++
-	}
-
-	return depth
+	return merkle_tree.GetDepth(v)
 }
@@ -8,23 +8,24 @@ import (
 )
 
 func TestGetDepth(t *testing.T) {
-	// Test cases with expected depths
+	// Test cases with expected depths, the leaves being padded to the next power of two
 	testCases := map[uint64]uint8{
-		0:  0,
-		1:  0,
-		2:  1,
-		3:  1,
-		4:  2,
-		5:  2,
-		6:  2,
-		7:  2,
-		8:  3,
-		9:  3,
-		10: 3,
-		16: 4,
-		17: 4,
-		32: 5,
-		33: 5,
+		0:    0,
+		1:    0,
+		2:    1,
+		3:    2,
+		4:    2,
+		5:    3,
+		6:    3,
+		7:    3,
+		8:    3,
+		9:    4,
+		10:   4,
+		16:   4,
+		17:   5,
+		32:   5,
+		33:   6,
+		9192: 14,
 	}
 
 	for v, expectedDepth := range testCases {
@@ -2,6 +2,7 @@ package solid
 
 import (
 	"encoding/json"
+	"io"
 
 	"github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/types/ssz"
@@ -25,16 +26,63 @@ type IterableSSZ[T any] interface {
 	ssz.HashableSSZ
 }
 
-type Uint64ListSSZ interface {
+// Uint64SSZ is implemented by both the uint64 lists and vectors: reads, and writes keeping the length.
+type Uint64SSZ interface {
 	IterableSSZ[uint64]
 	json.Marshaler
 	json.Unmarshaler
+
+	// GetErr and SetErr are Get and Set returning ErrIndexOutOfRange rather than panicking.
+	GetErr(index int) (uint64, error)
+	SetErr(index int, v uint64) error
+	// SetRange is Set of several consecutive elements at once.
+	SetRange(start int, vals []uint64)
+	// Release returns the backing buffer to a pool, the container must not be used afterwards.
+	Release()
+	// MarkDirty and HashSSZDelta rehash only the elements modified outside of the setters, e.g. through Bytes.
+	MarkDirty(index int)
+	HashSSZDelta(prevRoot [32]byte, changed []int) ([32]byte, error)
+	// AddAt and ApplyDelta add signed deltas to elements, e.g. rewards and penalties, saturating at 0 and MaxUint64.
+	AddAt(index int, delta int64)
+	ApplyDelta(deltas []int64)
+	// IsSorted and BinarySearch operate on elements kept in non-decreasing order.
+	IsSorted() bool
+	BinarySearch(v uint64) (int, bool)
+	// EncodeSnapshot and DecodeSnapshot persist the elements together with their memoized merkle tree.
+	EncodeSnapshot(w io.Writer, compress bool) error
+	DecodeSnapshot(r io.Reader) error
+	// Equal and Diff compare the elements with the ones of other, see IndexedChange.
+	Equal(other IterableSSZ[uint64]) bool
+	Diff(other IterableSSZ[uint64]) []IndexedChange
+	// SetAligned writes vals from start on in whole 32-byte chunks, see ErrUnalignedWrite.
+	SetAligned(start int, vals []uint64) error
+	// RangeFrom is Range starting at index start, RangeReverse is Range from the last element to the first one.
+	RangeFrom(start int, fn func(index int, value uint64, length int) bool)
+	RangeReverse(fn func(index int, value uint64, length int) bool)
+	// ProveIndex and ProveIndices return the merkle branches of elements, ordered from the leaf chunk up.
+	ProveIndex(i int) ([][32]byte, error)
+	ProveIndices(indices ...int) ([][][32]byte, error)
+	// CloneShared returns a copy-on-write copy, sharing the elements until either copy is mutated.
+	CloneShared() IterableSSZ[uint64]
+	// Begin returns a batch staging Set and Append operations until committed, see MutationBatch.
+	Begin() *MutationBatch
+}
+
+type Uint64ListSSZ interface {
+	Uint64SSZ
+
+	// Insert, RemoveAt and Truncate shift the elements following the ones inserted or removed.
+	Insert(index int, v uint64)
+	RemoveAt(index int)
+	Truncate(n int)
+	// AppendMultiple is Append of several elements at once.
+	AppendMultiple(vals ...uint64)
+	// InsertSorted inserts v keeping the elements in non-decreasing order, see IsSorted.
+	InsertSorted(v uint64)
 }
 
 type Uint64VectorSSZ interface {
-	IterableSSZ[uint64]
-	json.Marshaler
-	json.Unmarshaler
+	Uint64SSZ
 }
 
 type HashListSSZ interface {
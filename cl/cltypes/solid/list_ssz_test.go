@@ -4,7 +4,9 @@ import (
 	"testing"
 
 	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon/cl/utils"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Mock data
@@ -82,6 +84,9 @@ func TestUint64VectorSSZ(t *testing.T) {
 	assert.Equal(t, size, arr.Length())
 	// Test Static
 	assert.True(t, arr.Static())
+	// the length can't change, so the length-changing mutators of lists are missing
+	_, isList := arr.(Uint64ListSSZ)
+	assert.False(t, isList)
 
 	// Test CopyTo
 	otherArr := NewUint64VectorSSZ(size)
@@ -130,3 +135,25 @@ func TestUint64VectorSSZ(t *testing.T) {
 	encodingSize := arr.EncodingSizeSSZ()
 	assert.Equal(t, expectedEncodingSize, encodingSize)
 }
+
+func TestListSSZElementProof(t *testing.T) {
+	for _, limit := range []int{16, 9192} { // 9192 is not a power of two, its leaves are padded to 16384
+		list := NewDynamicListSSZ[Checkpoint](limit)
+		for i := 0; i < 5; i++ {
+			list.Append(NewCheckpointFromParameters(common.Hash{byte(i + 1)}, uint64(i)))
+		}
+		root, err := list.HashSSZ()
+		require.NoError(t, err)
+		for _, i := range []int{0, 3, 4} {
+			leaf, err := list.Get(i).HashSSZ()
+			require.NoError(t, err)
+			branch := list.ElementProof(i)
+			require.Len(t, branch, int(GetDepth(uint64(limit)))+1)
+			hashes := make([]common.Hash, len(branch))
+			for j := range branch {
+				hashes[j] = branch[j]
+			}
+			require.True(t, utils.IsValidMerkleBranch(leaf, hashes, uint64(len(branch)), uint64(i), root), "limit %d, index %d", limit, i)
+		}
+	}
+}
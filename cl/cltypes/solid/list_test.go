@@ -59,6 +59,8 @@ func TestByteBasedUint64Slice(t *testing.T) {
 	newUint64Slice := NewUint64Slice(sliceSize)
 	err = newUint64Slice.DecodeSSZ(buf, 0)
 	assert.NoError(t, err)
+	assert.True(t, newUint64Slice.checked) // only decoded slices are checked
+	newUint64Slice.checked = false
 	assert.Equal(t, uint64Slice, newUint64Slice)
 
 	// Test HashSSZ
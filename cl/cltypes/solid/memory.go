@@ -0,0 +1,132 @@
+package solid
+
+import (
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"github.com/ledgerwatch/erigon-lib/metrics"
+)
+
+// MemoryReporter is implemented by the solid containers, and by whoever holds them (states, caches), to report the
+// bytes their backing buffers take. The capacity of the buffers is reported, as that is what is actually allocated.
+type MemoryReporter interface {
+	MemoryUsage() int
+}
+
+var memoryReporters = struct {
+	sync.Mutex
+	m map[string]MemoryReporter
+}{m: map[string]MemoryReporter{}}
+
+// RegisterMemoryReporter adds r to the reporters aggregated by MemoryUsage, replacing any other reporter registered
+// with the same name. The returned function removes it.
+func RegisterMemoryReporter(name string, r MemoryReporter) (unregister func()) {
+	memoryReporters.Lock()
+	defer memoryReporters.Unlock()
+	memoryReporters.m[name] = r
+	return func() {
+		memoryReporters.Lock()
+		defer memoryReporters.Unlock()
+		if memoryReporters.m[name] == r {
+			delete(memoryReporters.m, name)
+		}
+	}
+}
+
+// MemoryUsage returns the bytes used by all the registered reporters, and updates the caplin_solid_memory_bytes
+// gauge of each of them.
+func MemoryUsage() int {
+	total := 0
+	for name, usage := range MemoryUsageByName() {
+		metrics.GetOrCreateGauge(fmt.Sprintf(`caplin_solid_memory_bytes{name="%s"}`, name)).SetInt(usage)
+		total += usage
+	}
+	return total
+}
+
+// MemoryUsageByName returns the bytes used by each registered reporter.
+func MemoryUsageByName() map[string]int {
+	memoryReporters.Lock()
+	defer memoryReporters.Unlock()
+	usages := make(map[string]int, len(memoryReporters.m))
+	for name, r := range memoryReporters.m {
+		usages[name] = r.MemoryUsage()
+	}
+	return usages
+}
+
+// MemoryUsageOf sums the memory used by objs; the ones not implementing MemoryReporter are skipped.
+func MemoryUsageOf(objs ...any) int {
+	total := 0
+	for _, obj := range objs {
+		if r, ok := obj.(MemoryReporter); ok {
+			total += r.MemoryUsage()
+		}
+	}
+	return total
+}
+
+func (arr *byteBasedUint64Slice) MemoryUsage() int {
+	if arr == nil {
+		return 0
+	}
+	return cap(arr.u) + arr.layers.memoryUsage()
+}
+
+func (arr *uint64ListSSZ) MemoryUsage() int {
+	if arr == nil {
+		return 0
+	}
+	return arr.u.MemoryUsage()
+}
+
+func (arr *uint64VectorSSZ) MemoryUsage() int {
+	if arr == nil {
+		return 0
+	}
+	return arr.u.MemoryUsage()
+}
+
+func (arr *RawUint64List) MemoryUsage() int {
+	if arr == nil {
+		return 0
+	}
+	return cap(arr.u)*8 + cap(arr.hahsBuffer)
+}
+
+func (arr *hashList) MemoryUsage() int {
+	if arr == nil {
+		return 0
+	}
+	return cap(arr.u) + cap(arr.buf)
+}
+
+func (arr *hashVector) MemoryUsage() int {
+	if arr == nil {
+		return 0
+	}
+	return arr.u.MemoryUsage()
+}
+
+func (u *BitList) MemoryUsage() int {
+	if u == nil {
+		return 0
+	}
+	return cap(u.u) + cap(u.buf)
+}
+
+func (v *ValidatorSet) MemoryUsage() int {
+	if v == nil {
+		return 0
+	}
+	return cap(v.buffer) + cap(v.treeCacheBuffer) + cap(v.attesterBits) + cap(v.buf) +
+		cap(v.phase0Data)*int(unsafe.Sizeof(Phase0Data{}))
+}
+
+func (s *Scratch) MemoryUsage() int {
+	if s == nil {
+		return 0
+	}
+	return cap(s.buf) + cap(s.leaves) + cap(s.layer) + cap(s.uint64s)*8
+}
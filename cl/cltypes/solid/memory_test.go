@@ -0,0 +1,36 @@
+package solid_test
+
+import (
+	"testing"
+
+	"github.com/ledgerwatch/erigon/cl/cltypes/solid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryUsage(t *testing.T) {
+	list := solid.NewUint64ListSSZ(1 << 10)
+	validators := solid.NewValidatorSet(1 << 10)
+	var nilValidators *solid.ValidatorSet
+	require.Zero(t, solid.MemoryUsageOf(list, validators, nilValidators, 42))
+
+	for i := 0; i < 100; i++ {
+		list.Append(uint64(i))
+		validators.Append(solid.NewValidator())
+	}
+	_, err := list.HashSSZ()
+	require.NoError(t, err)
+	listUsage, validatorsUsage := solid.MemoryUsageOf(list), solid.MemoryUsageOf(validators)
+	require.GreaterOrEqual(t, listUsage, 100*8)
+	require.GreaterOrEqual(t, validatorsUsage, 100*121)
+
+	unregisterList := solid.RegisterMemoryReporter("test_list", list.(solid.MemoryReporter))
+	unregisterValidators := solid.RegisterMemoryReporter("test_validators", validators)
+	usages := solid.MemoryUsageByName()
+	require.Equal(t, listUsage, usages["test_list"])
+	require.Equal(t, validatorsUsage, usages["test_validators"])
+	require.GreaterOrEqual(t, solid.MemoryUsage(), listUsage+validatorsUsage)
+
+	unregisterList()
+	unregisterValidators()
+	require.NotContains(t, solid.MemoryUsageByName(), "test_list")
+}
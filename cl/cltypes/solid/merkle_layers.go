@@ -0,0 +1,263 @@
+package solid
+
+import (
+	"math/bits"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon/cl/merkle_tree"
+)
+
+// merkleLayers memoizes the internal nodes of the merkle tree over the 32-byte leaves of a flat container, so that
+// hashing after a few mutations only recomputes the branches of the leaves marked dirty since the last update.
+// Odd layers are padded with the zero hash of their height, as the full merkleization does.
+type merkleLayers struct {
+	// layers[i] holds the populated nodes at height i+1
+	layers [][]byte
+	// dirty leaves since the last update, as a bitset
+	dirty      []uint64
+	dirtyCount int
+	// valid is false until the first update, and after reset: the whole tree must be recomputed
+	valid bool
+	// shared layers are also referenced by a copy-on-write clone, so they are copied before being written
+	shared bool
+
+	// buffers of the incremental update
+	indices, parents []int
+	pairs, roots     []byte
+}
+
+// reset forgets all the memoized nodes, e.g. after the leaves have been replaced.
+func (m *merkleLayers) reset() {
+	m.valid = false
+	if m.shared {
+		m.layers, m.shared = nil, false
+	}
+	clear(m.dirty)
+	m.dirtyCount = 0
+}
+
+// markDirty records that the leaf at index changed (was set, appended or removed).
+func (m *merkleLayers) markDirty(leaf int) {
+	if !m.valid {
+		return
+	}
+	word := leaf / 64
+	if word >= len(m.dirty) {
+		m.dirty = append(m.dirty, make([]uint64, word-len(m.dirty)+1)...)
+	}
+	if m.dirty[word]&(1<<(leaf%64)) == 0 {
+		m.dirty[word] |= 1 << (leaf % 64)
+		m.dirtyCount++
+	}
+}
+
+func (m *merkleLayers) copyTo(t *merkleLayers) {
+	t.reset()
+	if !m.valid {
+		return
+	}
+	if len(t.layers) != len(m.layers) {
+		t.layers = make([][]byte, len(m.layers))
+	}
+	for i, layer := range m.layers {
+		t.layers[i] = append(t.layers[i][:0], layer...)
+	}
+	t.dirty = append(t.dirty[:0], m.dirty...)
+	t.dirtyCount = m.dirtyCount
+	t.valid = true
+}
+
+// share returns layers referencing the nodes of m, both being copied on their next write.
+func (m *merkleLayers) share() merkleLayers {
+	if !m.valid {
+		return merkleLayers{}
+	}
+	m.shared = true
+	return merkleLayers{
+		layers:     append([][]byte(nil), m.layers...),
+		dirty:      append([]uint64(nil), m.dirty...),
+		dirtyCount: m.dirtyCount,
+		valid:      true,
+		shared:     true,
+	}
+}
+
+// own copies the shared nodes, so that they can be written.
+func (m *merkleLayers) own() {
+	if !m.shared {
+		return
+	}
+	for i, layer := range m.layers {
+		m.layers[i] = append([]byte(nil), layer...)
+	}
+	m.shared = false
+}
+
+func (m *merkleLayers) memoryUsage() int {
+	size := cap(m.dirty)*8 + (cap(m.indices)+cap(m.parents))*8 + cap(m.pairs) + cap(m.roots)
+	for _, layer := range m.layers {
+		size += cap(layer)
+	}
+	return size
+}
+
+// layerLength returns the amount of populated nodes at height h of a tree over n leaves.
+func layerLength(n, h int) int {
+	return (n + 1<<h - 1) >> h
+}
+
+// node returns the node at height h and position idx, leaves being at height 0.
+func (m *merkleLayers) node(leaves []byte, h, idx int) [32]byte {
+	layer := leaves
+	if h > 0 {
+		layer = m.layers[h-1]
+	}
+	if (idx+1)*length.Hash > len(layer) {
+		return merkle_tree.ZeroHashes[h]
+	}
+	return [32]byte(layer[idx*length.Hash : (idx+1)*length.Hash])
+}
+
+// update brings the memoized layers of a tree of the given depth up to date with leaves, and returns its root:
+// the first node at height depth.
+func (m *merkleLayers) update(leaves []byte, depth uint8) ([32]byte, error) {
+	n := len(leaves) / length.Hash
+	if int(depth) != len(m.layers) {
+		m.valid = false
+	}
+	begin := time.Now()
+	if !m.valid || m.dirtyCount*2 >= n {
+		if m.shared { // all the nodes are recomputed, no need to copy them
+			m.layers, m.shared = nil, false
+		}
+		if err := m.rebuild(leaves, depth); err != nil {
+			return [32]byte{}, err
+		}
+		hashCallsRebuild.Inc()
+		hashLeavesRebuild.AddInt(n)
+		hashDurationRebuild.ObserveDuration(begin)
+	} else if m.dirtyCount > 0 {
+		m.own()
+		if err := m.rehashDirty(leaves, depth); err != nil {
+			return [32]byte{}, err
+		}
+		hashCallsIncremental.Inc()
+		hashLeavesIncremental.AddInt(m.dirtyCount)
+		hashDurationIncremental.ObserveDuration(begin)
+	} else {
+		hashCallsCached.Inc()
+	}
+	clear(m.dirty)
+	m.dirtyCount = 0
+	m.valid = true
+	return m.node(leaves, int(depth), 0), nil
+}
+
+// rebuild computes all the layers from scratch, taking the full subtrees from the subtree cache if one is set.
+func (m *merkleLayers) rebuild(leaves []byte, depth uint8) error {
+	if len(m.layers) != int(depth) {
+		m.layers = make([][]byte, depth)
+	}
+	n := len(leaves) / length.Hash
+	for h := 1; h <= int(depth); h++ {
+		m.layers[h-1] = growBytes(m.layers[h-1], layerLength(n, h)*length.Hash)
+	}
+	start := 1
+	if cache := loadSubtreeCache(); cache != nil && int(depth) >= subtreeCacheHeight && n >= 1<<subtreeCacheHeight {
+		full := n >> subtreeCacheHeight
+		if err := m.fillSubtrees(cache, leaves, full); err != nil {
+			return err
+		}
+		// the nodes after the full subtrees, if any
+		for h := 1; h <= subtreeCacheHeight; h++ {
+			if err := m.hashNodes(leaves, h, full<<(subtreeCacheHeight-h)); err != nil {
+				return err
+			}
+		}
+		start = subtreeCacheHeight + 1
+	}
+	for h := start; h <= int(depth); h++ {
+		if err := m.hashNodes(leaves, h, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hashNodes computes the nodes at height h from position idx on, the layer below being up to date.
+func (m *merkleLayers) hashNodes(leaves []byte, h, idx int) error {
+	prev := leaves
+	if h > 1 {
+		prev = m.layers[h-2]
+	}
+	prev, layer := prev[2*idx*length.Hash:], m.layers[h-1][idx*length.Hash:]
+	even := len(prev) / (2 * length.Hash) * (2 * length.Hash)
+	if even > 0 {
+		if err := hashPairs(layer[:even/2], prev[:even], h); err != nil {
+			return err
+		}
+	}
+	if even < len(prev) { // odd layer - last node is paired with the zero hash
+		m.pairs = append(append(growBytes(m.pairs, 0), prev[even:]...), merkle_tree.ZeroHashes[h-1][:]...)
+		if err := merkle_tree.HashByteSlice(layer[even/2:], m.pairs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rehashDirty recomputes the parents of the dirty leaves, layer by layer, hashing each layer in one batch.
+func (m *merkleLayers) rehashDirty(leaves []byte, depth uint8) error {
+	n := len(leaves) / length.Hash
+	m.indices = m.indices[:0]
+	for w, word := range m.dirty {
+		for word != 0 {
+			m.indices = append(m.indices, w*64+bits.TrailingZeros64(word))
+			word &= word - 1
+		}
+	}
+	for h := 1; h <= int(depth); h++ {
+		// the leaves may have been added or removed, so the layers are resized first
+		m.layers[h-1] = growBytes(m.layers[h-1], layerLength(n, h)*length.Hash)
+		// parents of removed nodes are removed too, but they are still propagated: their own parents may exist
+		m.parents = m.parents[:0]
+		populated := 0
+		for _, idx := range m.indices {
+			if p := idx / 2; len(m.parents) == 0 || m.parents[len(m.parents)-1] != p {
+				m.parents = append(m.parents, p)
+				if p < layerLength(n, h) {
+					populated++
+				}
+			}
+		}
+		m.indices, m.parents = m.parents, m.indices
+		if populated == 0 {
+			continue
+		}
+		m.pairs = growBytes(m.pairs, populated*2*length.Hash)
+		for i, p := range m.indices[:populated] {
+			left, right := m.node(leaves, h-1, 2*p), m.node(leaves, h-1, 2*p+1)
+			copy(m.pairs[2*i*length.Hash:], left[:])
+			copy(m.pairs[(2*i+1)*length.Hash:], right[:])
+		}
+		m.roots = growBytes(m.roots, populated*length.Hash)
+		if err := hashPairs(m.roots, m.pairs, h); err != nil {
+			return err
+		}
+		for i, p := range m.indices[:populated] {
+			copy(m.layers[h-1][p*length.Hash:], m.roots[i*length.Hash:(i+1)*length.Hash])
+		}
+	}
+	return nil
+}
+
+// growBytes returns b resized to n bytes, reusing its capacity.
+func growBytes(b []byte, n int) []byte {
+	if cap(b) < n {
+		nb := make([]byte, n, n+n/4)
+		copy(nb, b)
+		return nb
+	}
+	return b[:n]
+}
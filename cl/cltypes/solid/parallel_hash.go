@@ -0,0 +1,58 @@
+package solid
+
+import (
+	"runtime"
+	"sync/atomic"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/ledgerwatch/erigon-lib/common/dbg"
+	"github.com/ledgerwatch/erigon/cl/merkle_tree"
+)
+
+// parallelHashThreshold is the amount of node pairs below which a merkle layer is hashed sequentially: smaller
+// layers are hashed faster than goroutines are spawned.
+const parallelHashThreshold = 1 << 14
+
+// hashWorkers is the max amount of goroutines hashing a merkle layer of a large container (e.g. the balances).
+var hashWorkers atomic.Int64
+
+func init() {
+	SetHashWorkers(dbg.EnvInt("CAPLIN_SOLID_HASH_WORKERS", runtime.GOMAXPROCS(-1)))
+}
+
+// SetHashWorkers sets the max amount of goroutines hashing a merkle layer of a large container, 1 disables
+// parallel hashing.
+func SetHashWorkers(n int) {
+	if n < 1 {
+		n = 1
+	}
+	hashWorkers.Store(int64(n))
+}
+
+// HashWorkers returns the max amount of goroutines hashing a merkle layer of a large container.
+func HashWorkers() int {
+	return int(hashWorkers.Load())
+}
+
+// hashLayer hashes the node pairs of in into out, like merkle_tree.HashByteSlice, splitting large layers into
+// contiguous ranges hashed concurrently.
+func hashLayer(out, in []byte) error {
+	pairs, workers := len(in)/64, HashWorkers()
+	if workers <= 1 || pairs < parallelHashThreshold {
+		hashLayersSequential.Inc()
+		return merkle_tree.HashByteSlice(out, in)
+	}
+	hashLayersParallel.Inc()
+	if workers > pairs/(parallelHashThreshold/4) {
+		workers = pairs / (parallelHashThreshold / 4)
+	}
+	var g errgroup.Group
+	for w := 0; w < workers; w++ {
+		from, to := pairs*w/workers, pairs*(w+1)/workers
+		g.Go(func() error {
+			return merkle_tree.HashByteSlice(out[from*32:to*32], in[from*64:to*64])
+		})
+	}
+	return g.Wait()
+}
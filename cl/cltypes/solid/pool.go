@@ -0,0 +1,36 @@
+package solid
+
+import (
+	"math/bits"
+	"sync"
+)
+
+// minPooledBuffer is the size below which backing buffers are neither pooled nor taken from the pool.
+const minPooledBuffer = 4096
+
+// bufferPools holds released backing buffers by size class: bufferPools[k] only holds buffers of capacity >= 1<<k.
+var bufferPools [bits.UintSize]sync.Pool
+
+// getBuffer returns a zeroed buffer of n bytes, reusing a released buffer if possible.
+func getBuffer(n int) (b []byte, pooled bool) {
+	if n < minPooledBuffer {
+		return make([]byte, n), false
+	}
+	class := bits.Len(uint(n - 1)) // smallest k with 1<<k >= n
+	if p, ok := bufferPools[class].Get().(*[]byte); ok {
+		b = (*p)[:n]
+		clear(b)
+		return b, true
+	}
+	return make([]byte, n, 1<<class), true
+}
+
+// putBuffer makes b available to getBuffer, b must not be used afterwards.
+func putBuffer(b []byte) {
+	if cap(b) < minPooledBuffer {
+		return
+	}
+	class := bits.Len(uint(cap(b))) - 1 // largest k with 1<<k <= cap(b)
+	b = b[:0]
+	bufferPools[class].Put(&b)
+}
@@ -0,0 +1,27 @@
+//go:build solid_debug
+
+package solid
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync/atomic"
+
+	"github.com/ledgerwatch/log/v3"
+)
+
+// leakedBuffers counts the containers collected while holding a pooled buffer, i.e. without Release.
+var leakedBuffers atomic.Int64
+
+// trackBuffer reports arr when it is collected before releasing its pooled buffer, with the stack that got it.
+func trackBuffer(arr *byteBasedUint64Slice) {
+	stack := debug.Stack()
+	runtime.SetFinalizer(arr, func(*byteBasedUint64Slice) {
+		leakedBuffers.Add(1)
+		log.Warn("[solid] pooled buffer leaked, container collected without Release", "stack", string(stack))
+	})
+}
+
+func untrackBuffer(arr *byteBasedUint64Slice) {
+	runtime.SetFinalizer(arr, nil)
+}
@@ -0,0 +1,30 @@
+//go:build solid_debug
+
+package solid
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPooledBufferLeak(t *testing.T) {
+	released := NewUint64ListSSZ(1 << 20)
+	require.NoError(t, released.DecodeSSZ(make([]byte, 8*1024), 0))
+	released.Release()
+	collect := func() {
+		for i := 0; i < 5; i++ {
+			runtime.GC()
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	collect()
+	require.Zero(t, leakedBuffers.Load())
+
+	leaked := NewUint64ListSSZ(1 << 20)
+	require.NoError(t, leaked.DecodeSSZ(make([]byte, 8*1024), 0))
+	collect()
+	require.Equal(t, int64(1), leakedBuffers.Load())
+}
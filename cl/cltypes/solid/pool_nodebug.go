@@ -0,0 +1,7 @@
+//go:build !solid_debug
+
+package solid
+
+// trackBuffer and untrackBuffer detect leaked pooled buffers in builds with the solid_debug tag only.
+func trackBuffer(*byteBasedUint64Slice)   {}
+func untrackBuffer(*byteBasedUint64Slice) {}
@@ -0,0 +1,72 @@
+package solid
+
+import (
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon/cl/merkle_tree"
+)
+
+const validatorFieldsCount = 8
+
+// Generalized indices of the validator fields, relative to the validator root.
+const (
+	ValidatorPublicKeyGeneralizedIndex = 8 + iota
+	ValidatorWithdrawalCredentialsGeneralizedIndex
+	ValidatorEffectiveBalanceGeneralizedIndex
+	ValidatorSlashedGeneralizedIndex
+	ValidatorActivationEligibilityEpochGeneralizedIndex
+	ValidatorActivationEpochGeneralizedIndex
+	ValidatorExitEpochGeneralizedIndex
+	ValidatorWithdrawableEpochGeneralizedIndex
+)
+
+// ProofTree returns the merkle tree of the validator, with the public key expanded into its two chunks.
+func (v Validator) ProofTree() (*merkle_tree.ProofTree, error) {
+	leaves := make([]byte, validatorFieldsCount*length.Hash)
+	if err := v.CopyHashBufferTo(leaves); err != nil {
+		return nil, err
+	}
+	t := merkle_tree.NewProofTree(splitChunks(leaves), validatorFieldsCount)
+	var pubKey [2][32]byte
+	copy(pubKey[0][:], v[:32])
+	copy(pubKey[1][:], v[32:48])
+	if err := t.Attach(0, merkle_tree.NewProofTree(pubKey[:], 2)); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ProofTree returns the merkle tree of the validator set, with the validators at indices expanded.
+// It hashes every validator, so it is meant for proof generation only and not for the state transition.
+func (v *ValidatorSet) ProofTree(indices ...int) (*merkle_tree.ProofTree, error) {
+	roots := make([][32]byte, v.l)
+	for i := range roots {
+		var err error
+		if roots[i], err = v.Get(i).HashSSZ(); err != nil {
+			return nil, err
+		}
+	}
+	t := merkle_tree.NewListProofTree(roots, uint64(v.c), uint64(v.l))
+	for _, idx := range indices {
+		sub, err := v.Get(idx).ProofTree()
+		if err != nil {
+			return nil, err
+		}
+		if err := t.Attach(uint64(idx), sub); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// ValidatorGeneralizedIndex returns the generalized index of the validator at idx, relative to the set root.
+func (v *ValidatorSet) ValidatorGeneralizedIndex(idx int) uint64 {
+	return merkle_tree.ConcatGeneralizedIndices(2, merkle_tree.NextPowerOfTwo(uint64(v.c))+uint64(idx))
+}
+
+func splitChunks(leaves []byte) [][32]byte {
+	chunks := make([][32]byte, len(leaves)/length.Hash)
+	for i := range chunks {
+		copy(chunks[i][:], leaves[i*length.Hash:])
+	}
+	return chunks
+}
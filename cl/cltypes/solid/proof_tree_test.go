@@ -0,0 +1,40 @@
+package solid
+
+import (
+	"testing"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon/cl/merkle_tree"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatorSetProofTree(t *testing.T) {
+	validators := NewValidatorSet(1 << 20)
+	for i := 0; i < 21; i++ {
+		validators.Append(NewValidatorFromParameters([48]byte{byte(i), 47: 0xff}, libcommon.Hash{byte(i)}, uint64(i)*32, false, 1, 2, 3, 4))
+	}
+	root, err := validators.HashSSZ()
+	require.NoError(t, err)
+
+	tree, err := validators.ProofTree(3, 17)
+	require.NoError(t, err)
+	treeRoot, err := tree.Root()
+	require.NoError(t, err)
+	require.Equal(t, root, treeRoot)
+
+	pubKeyIndex := merkle_tree.ConcatGeneralizedIndices(validators.ValidatorGeneralizedIndex(3), ValidatorPublicKeyGeneralizedIndex)
+	indices := []uint64{
+		merkle_tree.ConcatGeneralizedIndices(pubKeyIndex, 2), // first 32 bytes of the pubkey
+		merkle_tree.ConcatGeneralizedIndices(pubKeyIndex, 3),
+		merkle_tree.ConcatGeneralizedIndices(validators.ValidatorGeneralizedIndex(17), ValidatorEffectiveBalanceGeneralizedIndex),
+	}
+	leaves, proof, err := tree.MultiProof(indices...)
+	require.NoError(t, err)
+	require.Equal(t, [32]byte{3}, leaves[0])
+	require.Equal(t, [32]byte{15: 0xff}, leaves[1])
+	require.Equal(t, merkle_tree.Uint64Root(17*32), libcommon.Hash(leaves[2]))
+	require.True(t, merkle_tree.VerifyMerkleMultiproof(leaves, proof, indices, root))
+
+	_, _, err = tree.MultiProof(merkle_tree.ConcatGeneralizedIndices(validators.ValidatorGeneralizedIndex(4), ValidatorSlashedGeneralizedIndex))
+	require.Error(t, err)
+}
@@ -0,0 +1,87 @@
+package solid
+
+import (
+	"sync"
+
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon/cl/merkle_tree"
+)
+
+// Scratch owns the temporary buffers needed by heavy operations over solid containers (hashing, shuffling, ...).
+// It is not thread-safe: each goroutine processing a state should own its Scratch, so that concurrent epoch
+// processing of different states never contends on package-level buffers or locks.
+type Scratch struct {
+	// merkle layers above the tree caches
+	hashBuf
+	// flat leaves of a single element (e.g. the 8 fields of a validator)
+	leaves []byte
+	// roots of one tree-cache group
+	layer []byte
+	// general purpose uint64 buffer, e.g. the output of a shuffle
+	uint64s []uint64
+}
+
+// NewScratch creates a Scratch with buffers pre-sized for a state with validatorCount validators.
+func NewScratch(validatorCount int) *Scratch {
+	s := &Scratch{}
+	s.makeBuf(getTreeCacheSize(validatorCount, validatorTreeCacheGroupLayer) * length.Hash)
+	s.leavesBuf(8 * length.Hash)
+	s.layerBuf(convertDepthToChunkSize(validatorTreeCacheGroupLayer) * length.Hash)
+	s.Uint64s(validatorCount)
+	return s
+}
+
+// ScratchHasher is implemented by the containers whose hashing can reuse the temporary buffers of a Scratch.
+type ScratchHasher interface {
+	HashSSZWithScratch(s *Scratch) ([32]byte, error)
+}
+
+var scratchPool = sync.Pool{New: func() any { return &Scratch{} }}
+
+// AcquireScratch returns a Scratch from a package-level pool, for the callers without a Scratch of their own (e.g.
+// a plain HashSSZ). It must be given back with ReleaseScratch, and none of its buffers retained.
+func AcquireScratch() *Scratch {
+	return scratchPool.Get().(*Scratch)
+}
+
+// ReleaseScratch puts s back into the pool, s must not be used afterwards.
+func ReleaseScratch(s *Scratch) {
+	scratchPool.Put(s)
+}
+
+// Uint64s returns a reusable buffer of n uint64s. Its content is undefined and it is only valid until the next call.
+func (s *Scratch) Uint64s(n int) []uint64 {
+	if cap(s.uint64s) < n {
+		s.uint64s = make([]uint64, n)
+	}
+	s.uint64s = s.uint64s[:n]
+	return s.uint64s
+}
+
+func (s *Scratch) leavesBuf(n int) []byte {
+	if cap(s.leaves) < n {
+		s.leaves = make([]byte, n)
+	}
+	s.leaves = s.leaves[:n]
+	return s.leaves
+}
+
+func (s *Scratch) layerBuf(n int) []byte {
+	if cap(s.layer) < n {
+		s.layer = make([]byte, n)
+	}
+	s.layer = s.layer[:n]
+	return s.layer
+}
+
+// merkleizeFlatInPlace hashes a power-of-two amount of flat leaves down to their root, which ends up in leaves[:32].
+// Unlike merkle_tree.MerkleRootFromFlatLeaves it does not go through the package-level hasher and its lock.
+func merkleizeFlatInPlace(leaves []byte) error {
+	for len(leaves) > length.Hash {
+		if err := merkle_tree.HashByteSlice(leaves, leaves); err != nil {
+			return err
+		}
+		leaves = leaves[:len(leaves)/2]
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package solid
+
+import (
+	"testing"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScratchHashing(t *testing.T) {
+	validators := NewValidatorSet(1 << 10)
+	balances := NewUint64ListSSZ(1 << 10)
+	mixes := NewUint64VectorSSZ(64)
+	pubkeys := NewSlice(PubkeyCodec, 1<<10)
+	for i := 0; i < 100; i++ {
+		validators.Append(NewValidatorFromParameters([48]byte{byte(i)}, libcommon.Hash{byte(i)}, uint64(i), false, 1, 2, 3, 4))
+		balances.Append(uint64(i))
+		if i < 64 {
+			mixes.Set(i, uint64(i*i))
+		}
+		pubkeys.Append(libcommon.Bytes48{byte(i)})
+	}
+	s := NewScratch(validators.Length())
+	for _, obj := range []interface {
+		HashSSZ() ([32]byte, error)
+		ScratchHasher
+	}{validators, balances.(*uint64ListSSZ), mixes.(*uint64VectorSSZ), pubkeys} {
+		expected, err := ReferenceHashSSZ(obj)
+		require.NoError(t, err)
+		withScratch, err := obj.HashSSZWithScratch(s)
+		require.NoError(t, err)
+		require.Equal(t, expected, withScratch, "%T", obj)
+		own, err := obj.HashSSZ()
+		require.NoError(t, err)
+		require.Equal(t, expected, own)
+	}
+
+	require.Len(t, s.Uint64s(10), 10)
+	require.Len(t, s.Uint64s(1000), 1000)
+}
+
+func TestPooledScratchHashing(t *testing.T) {
+	validators := NewValidatorSet(1 << 10)
+	for i := 0; i < 100; i++ {
+		validators.Append(NewValidatorFromParameters([48]byte{byte(i)}, libcommon.Hash{byte(i)}, uint64(i), false, 1, 2, 3, 4))
+	}
+	_, err := validators.HashSSZ()
+	require.NoError(t, err)
+	// the temporary buffers come from the pooled Scratch, so rehashing a validator does not allocate them again
+	allocs := testing.AllocsPerRun(100, func() {
+		validators.Get(7).SetEffectiveBalance(7)
+		validators.zeroTreeHash(7)
+		_, err = validators.HashSSZ()
+	})
+	require.NoError(t, err)
+	require.Zero(t, allocs)
+}
@@ -0,0 +1,264 @@
+package solid
+
+import (
+	"fmt"
+	"math/bits"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/types/clonable"
+	"github.com/ledgerwatch/erigon-lib/types/ssz"
+	"github.com/ledgerwatch/erigon/cl/merkle_tree"
+	"github.com/ledgerwatch/erigon/cl/utils"
+)
+
+// ElementCodec describes how the fixed-size elements of a Slice are laid out in its flat buffer.
+type ElementCodec[T any] struct {
+	// Size is the length of the SSZ encoding of an element, which is also its layout in the buffer.
+	Size int
+	Put  func(dst []byte, v T)
+	Get  func(src []byte) T
+
+	// CopyHashBufferTo is set for containers: it writes the field roots of the element encoded in src into dst,
+	// HashBufferSize bytes long, which are merkleized into the element root. Other elements are byte vectors.
+	HashBufferSize   int
+	CopyHashBufferTo func(dst, src []byte) error
+}
+
+// RootCodec lays out 32-byte roots, e.g. block and state roots.
+var RootCodec = ElementCodec[libcommon.Hash]{
+	Size: length.Hash,
+	Put:  func(dst []byte, v libcommon.Hash) { copy(dst, v[:]) },
+	Get:  func(src []byte) libcommon.Hash { return libcommon.Hash(src) },
+}
+
+// PubkeyCodec lays out 48-byte BLS public keys.
+var PubkeyCodec = ElementCodec[libcommon.Bytes48]{
+	Size: length.Bytes48,
+	Put:  func(dst []byte, v libcommon.Bytes48) { copy(dst, v[:]) },
+	Get:  func(src []byte) libcommon.Bytes48 { return libcommon.Bytes48(src) },
+}
+
+// Slice is a list or a vector of fixed-size composite elements stored back to back in a flat buffer, with the
+// merkle tree over the element roots memoized like the uint64 containers: only the branches of the elements changed
+// since the previous hash are recomputed.
+type Slice[T any] struct {
+	codec  ElementCodec[T]
+	u      []byte
+	l, c   int
+	vector bool
+
+	// roots of the elements, unless they are 32 bytes long: then they are their own root
+	roots  []byte
+	layers merkleLayers
+}
+
+// NewSlice returns an empty list of at most limit elements.
+func NewSlice[T any](codec ElementCodec[T], limit int) *Slice[T] {
+	return &Slice[T]{codec: codec, c: limit}
+}
+
+// NewVectorSlice returns a vector of size zero elements.
+func NewVectorSlice[T any](codec ElementCodec[T], size int) *Slice[T] {
+	return &Slice[T]{codec: codec, u: make([]byte, size*codec.Size), l: size, c: size, vector: true}
+}
+
+func (s *Slice[T]) Length() int {
+	return s.l
+}
+
+func (s *Slice[T]) Cap() int {
+	return s.c
+}
+
+func (s *Slice[T]) Bytes() []byte {
+	return s.u[:s.l*s.codec.Size]
+}
+
+func (s *Slice[T]) Static() bool {
+	return s.vector
+}
+
+func (s *Slice[T]) Clone() clonable.Clonable {
+	if s.vector {
+		return NewVectorSlice(s.codec, s.c)
+	}
+	return NewSlice(s.codec, s.c)
+}
+
+func (s *Slice[T]) Get(index int) T {
+	if index < 0 || index >= s.l {
+		panic(fmt.Errorf("%w: index %d, length %d", ErrIndexOutOfRange, index, s.l))
+	}
+	return s.codec.Get(s.u[index*s.codec.Size : (index+1)*s.codec.Size])
+}
+
+func (s *Slice[T]) Set(index int, v T) {
+	if index < 0 || index >= s.l {
+		panic(fmt.Errorf("%w: index %d, length %d", ErrIndexOutOfRange, index, s.l))
+	}
+	s.codec.Put(s.u[index*s.codec.Size:(index+1)*s.codec.Size], v)
+	s.layers.markDirty(index)
+}
+
+func (s *Slice[T]) Append(v T) {
+	if s.vector {
+		panic("not implemented")
+	}
+	if len(s.u) < (s.l+1)*s.codec.Size {
+		s.u = append(s.u, make([]byte, s.codec.Size)...)
+	}
+	s.codec.Put(s.u[s.l*s.codec.Size:(s.l+1)*s.codec.Size], v)
+	s.layers.markDirty(s.l)
+	s.l++
+}
+
+func (s *Slice[T]) Pop() T {
+	if s.vector {
+		panic("not implemented")
+	}
+	v := s.Get(s.l - 1)
+	clear(s.u[(s.l-1)*s.codec.Size : s.l*s.codec.Size])
+	s.l--
+	s.layers.markDirty(s.l)
+	return v
+}
+
+func (s *Slice[T]) Clear() {
+	if s.vector {
+		clear(s.u)
+	} else {
+		s.l = 0
+	}
+	s.layers.reset()
+}
+
+func (s *Slice[T]) Range(fn func(index int, value T, length int) bool) {
+	for i := 0; i < s.l; i++ {
+		if !fn(i, s.Get(i), s.l) {
+			break
+		}
+	}
+}
+
+func (s *Slice[T]) CopyTo(target IterableSSZ[T]) {
+	t := target.(*Slice[T])
+	t.codec, t.l, t.c, t.vector = s.codec, s.l, s.c, s.vector
+	t.u = append(t.u[:0], s.u...)
+	t.roots = append(t.roots[:0], s.roots...)
+	s.layers.copyTo(&t.layers)
+}
+
+func (s *Slice[T]) EncodeSSZ(buf []byte) ([]byte, error) {
+	return append(buf, s.Bytes()...), nil
+}
+
+func (s *Slice[T]) EncodingSizeSSZ() int {
+	return s.l * s.codec.Size
+}
+
+func (s *Slice[T]) DecodeSSZ(buf []byte, _ int) error {
+	if len(buf)%s.codec.Size > 0 {
+		return ssz.ErrBadDynamicLength
+	}
+	if s.vector && len(buf) != s.c*s.codec.Size {
+		return ssz.ErrLowBufferSize
+	}
+	if len(buf)/s.codec.Size > s.c {
+		return ssz.ErrTooBigList
+	}
+	s.u = libcommon.Copy(buf)
+	s.l = len(buf) / s.codec.Size
+	s.layers.reset()
+	return nil
+}
+
+// leaves returns the roots of the elements, recomputing the ones of the elements changed since the previous hash.
+func (s *Slice[T]) leaves(scratch *Scratch) ([]byte, error) {
+	if s.codec.Size == length.Hash && s.codec.CopyHashBufferTo == nil {
+		return s.u[:s.l*length.Hash], nil
+	}
+	s.roots = growBytes(s.roots, s.l*length.Hash)
+	chunks := (s.codec.Size + length.Hash - 1) / length.Hash
+	size := (1 << bits.Len(uint(chunks-1))) * length.Hash
+	if s.codec.CopyHashBufferTo != nil {
+		size = s.codec.HashBufferSize
+	}
+	buf := scratch.leavesBuf(size)
+	hashElement := func(i int) error {
+		clear(buf)
+		if s.codec.CopyHashBufferTo != nil {
+			if err := s.codec.CopyHashBufferTo(buf, s.u[i*s.codec.Size:(i+1)*s.codec.Size]); err != nil {
+				return err
+			}
+		} else {
+			copy(buf, s.u[i*s.codec.Size:(i+1)*s.codec.Size])
+		}
+		if err := merkleizeFlatInPlace(buf); err != nil {
+			return err
+		}
+		copy(s.roots[i*length.Hash:], buf[:length.Hash])
+		return nil
+	}
+	if !s.layers.valid {
+		for i := 0; i < s.l; i++ {
+			if err := hashElement(i); err != nil {
+				return nil, err
+			}
+		}
+		return s.roots, nil
+	}
+	for w, word := range s.layers.dirty {
+		for ; word != 0; word &= word - 1 {
+			if i := w*64 + bits.TrailingZeros64(word); i < s.l {
+				if err := hashElement(i); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return s.roots, nil
+}
+
+func (s *Slice[T]) HashSSZ() ([32]byte, error) {
+	scratch := AcquireScratch()
+	defer ReleaseScratch(scratch)
+	return s.HashSSZWithScratch(scratch)
+}
+
+// HashSSZWithScratch is HashSSZ, merkleizing the elements in the buffers of scratch.
+func (s *Slice[T]) HashSSZWithScratch(scratch *Scratch) ([32]byte, error) {
+	leaves, err := s.leaves(scratch)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	root, err := s.layers.update(leaves, GetDepth(uint64(s.c)))
+	if err != nil || s.vector {
+		return assertHashSSZ(s, root, err)
+	}
+	lengthRoot := merkle_tree.Uint64Root(uint64(s.l))
+	return assertHashSSZ(s, utils.Sha256(root[:], lengthRoot[:]), nil)
+}
+
+// referenceHashSSZ is the spec merkleization of the slice, see ReferenceHashSSZ.
+func (s *Slice[T]) referenceHashSSZ() [32]byte {
+	chunks := uint64((s.codec.Size + length.Hash - 1) / length.Hash)
+	roots := make([][32]byte, s.l)
+	for i := range roots {
+		element := s.u[i*s.codec.Size : (i+1)*s.codec.Size]
+		if s.codec.CopyHashBufferTo == nil {
+			roots[i] = referenceMerkleize(referencePack(element), chunks)
+			continue
+		}
+		leaves := make([]byte, s.codec.HashBufferSize)
+		if err := s.codec.CopyHashBufferTo(leaves, element); err != nil {
+			panic(err)
+		}
+		roots[i] = referenceContainerRoot(referencePack(leaves))
+	}
+	root := referenceMerkleize(roots, uint64(s.c))
+	if s.vector {
+		return root
+	}
+	return referenceMixInLength(root, s.l)
+}
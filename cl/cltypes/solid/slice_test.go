@@ -0,0 +1,99 @@
+package solid_test
+
+import (
+	"math/rand"
+	"testing"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon/cl/cltypes/solid"
+	"github.com/stretchr/testify/require"
+)
+
+func checkSliceHash[T any](t *testing.T, obj *solid.Slice[T]) {
+	root, err := obj.HashSSZ()
+	require.NoError(t, err)
+	expected, err := solid.ReferenceHashSSZ(obj)
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+}
+
+func TestSliceHash(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	roots := solid.NewSlice(solid.RootCodec, 1<<10)
+	pubkeys := solid.NewSlice(solid.PubkeyCodec, 1<<10)
+	vector := solid.NewVectorSlice(solid.PubkeyCodec, 64)
+	for i := 0; i < 500; i++ {
+		var root libcommon.Hash
+		var pubkey libcommon.Bytes48
+		rnd.Read(root[:])
+		rnd.Read(pubkey[:])
+		switch {
+		case rnd.Intn(4) > 0 || roots.Length() == 0:
+			roots.Append(root)
+			pubkeys.Append(pubkey)
+		case rnd.Intn(2) == 0:
+			idx := rnd.Intn(roots.Length())
+			roots.Set(idx, root)
+			pubkeys.Set(idx, pubkey)
+		default:
+			roots.Pop()
+			pubkeys.Pop()
+		}
+		vector.Set(rnd.Intn(64), pubkey)
+		if i%5 == 0 {
+			checkSliceHash(t, roots)
+			checkSliceHash(t, pubkeys)
+			checkSliceHash(t, vector)
+		}
+	}
+	// roots are laid out and merkleized like the hash list
+	hashList := solid.NewHashList(1 << 10)
+	roots.Range(func(_ int, root libcommon.Hash, _ int) bool {
+		hashList.Append(root)
+		return true
+	})
+	expected, err := hashList.HashSSZ()
+	require.NoError(t, err)
+	root, err := roots.HashSSZ()
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+
+	copied := solid.NewSlice(solid.PubkeyCodec, 1<<10)
+	pubkeys.CopyTo(copied)
+	checkSliceHash(t, copied)
+	require.Equal(t, pubkeys.Get(3), copied.Get(3))
+
+	encoded, err := pubkeys.EncodeSSZ(nil)
+	require.NoError(t, err)
+	decoded := solid.NewSlice(solid.PubkeyCodec, 1<<10)
+	require.NoError(t, decoded.DecodeSSZ(encoded, 0))
+	checkSliceHash(t, decoded)
+	require.Error(t, decoded.DecodeSSZ(encoded[:47], 0))
+}
+
+func TestSliceOfContainers(t *testing.T) {
+	codec := solid.ElementCodec[solid.Checkpoint]{
+		Size:             solid.CheckpointSize,
+		Put:              func(dst []byte, v solid.Checkpoint) { copy(dst, v) },
+		Get:              func(src []byte) solid.Checkpoint { return solid.Checkpoint(src).Copy() },
+		HashBufferSize:   64,
+		CopyHashBufferTo: func(dst, src []byte) error { return solid.Checkpoint(src).CopyHashBufferTo(dst) },
+	}
+	checkpoints := solid.NewSlice(codec, 1<<10)
+	list := solid.NewStaticListSSZ[solid.Checkpoint](1<<10, solid.CheckpointSize)
+	for i := 0; i < 100; i++ {
+		checkpoint := solid.NewCheckpointFromParameters(libcommon.Hash{byte(i)}, uint64(i))
+		checkpoints.Append(checkpoint)
+		list.Append(checkpoint)
+	}
+	checkSliceHash(t, checkpoints)
+	checkpoints.Set(42, solid.NewCheckpointFromParameters(libcommon.Hash{1}, 1))
+	checkSliceHash(t, checkpoints)
+
+	checkpoints.Set(42, list.Get(42))
+	expected, err := list.HashSSZ()
+	require.NoError(t, err)
+	root, err := checkpoints.HashSSZ()
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+}
@@ -0,0 +1,312 @@
+package solid
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+
+	"github.com/golang/snappy"
+	"github.com/ledgerwatch/erigon-lib/common/length"
+)
+
+// snapshotVersion is the version of the snapshot format, snapshots of other versions are rejected.
+const snapshotVersion byte = 2
+
+const snapshotFlagSnappy byte = 1 << 0
+
+var (
+	// ErrSnapshotVersion is returned when decoding a snapshot written in an unknown format version.
+	ErrSnapshotVersion = errors.New("solid: unsupported snapshot version")
+	// ErrBadSnapshot is returned when decoding a truncated, oversized or corrupt snapshot, or one not matching the
+	// container.
+	ErrBadSnapshot = errors.New("solid: malformed snapshot")
+)
+
+// A snapshot is: version byte, flags byte, body length (uint64), crc32 of the body (Castagnoli) and the body, optionally
+// compressed with snappy. The body is a sequence of uint64s and length-prefixed byte sections, specific to every
+// container, all big endian. The checksum covers the stored roots and merkle layers, which are not rehashed on load.
+
+var snapshotCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+type snapshotWriter struct {
+	body []byte
+}
+
+func (s *snapshotWriter) uint64(v uint64) {
+	s.body = binary.BigEndian.AppendUint64(s.body, v)
+}
+
+func (s *snapshotWriter) section(b []byte) {
+	s.uint64(uint64(len(b)))
+	s.body = append(s.body, b...)
+}
+
+func (s *snapshotWriter) writeTo(w io.Writer, compress bool) error {
+	header, body := []byte{snapshotVersion, 0}, s.body
+	if compress {
+		header[1] |= snapshotFlagSnappy
+		body = snappy.Encode(nil, body)
+	}
+	header = binary.BigEndian.AppendUint64(header, uint64(len(body)))
+	header = binary.BigEndian.AppendUint32(header, crc32.Checksum(body, snapshotCRCTable))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(body)
+	return err
+}
+
+type snapshotReader struct {
+	body []byte
+	err  error
+}
+
+// readSnapshot reads a snapshot whose body, once decompressed, is at most maxSize bytes long. The body is read as it
+// comes rather than allocated upfront, so that a forged length can't allocate more than the snapshot actually holds.
+func readSnapshot(r io.Reader, maxSize int) (*snapshotReader, error) {
+	header := make([]byte, 14)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("%w: header: %w", ErrBadSnapshot, err)
+	}
+	if header[0] != snapshotVersion {
+		return nil, fmt.Errorf("%w: %d, want %d", ErrSnapshotVersion, header[0], snapshotVersion)
+	}
+	compressed := header[1]&snapshotFlagSnappy != 0
+	maxBody := maxSize
+	if compressed {
+		if maxBody = snappy.MaxEncodedLen(maxSize); maxBody < 0 { // too big for snappy to have written it
+			maxBody = math.MaxUint32
+		}
+	}
+	size := binary.BigEndian.Uint64(header[2:10])
+	if size > uint64(maxBody) {
+		return nil, fmt.Errorf("%w: body of %d bytes, max %d", ErrBadSnapshot, size, maxBody)
+	}
+	body, err := io.ReadAll(io.LimitReader(r, int64(size)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: body: %w", ErrBadSnapshot, err)
+	}
+	if uint64(len(body)) != size {
+		return nil, fmt.Errorf("%w: body: %w", ErrBadSnapshot, io.ErrUnexpectedEOF)
+	}
+	if sum := crc32.Checksum(body, snapshotCRCTable); sum != binary.BigEndian.Uint32(header[10:]) {
+		return nil, fmt.Errorf("%w: checksum %08x, want %08x", ErrBadSnapshot, sum, binary.BigEndian.Uint32(header[10:]))
+	}
+	if compressed {
+		if n, err := snappy.DecodedLen(body); err != nil || n > maxSize {
+			return nil, fmt.Errorf("%w: decoded body of %d bytes, max %d: %v", ErrBadSnapshot, n, maxSize, err)
+		}
+		if body, err = snappy.Decode(nil, body); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrBadSnapshot, err)
+		}
+	}
+	return &snapshotReader{body: body}, nil
+}
+
+func (s *snapshotReader) uint64() uint64 {
+	if s.err != nil {
+		return 0
+	}
+	if len(s.body) < 8 {
+		s.err = fmt.Errorf("%w: truncated", ErrBadSnapshot)
+		return 0
+	}
+	v := binary.BigEndian.Uint64(s.body)
+	s.body = s.body[8:]
+	return v
+}
+
+// section returns the next section, which must be size bytes long.
+func (s *snapshotReader) section(size int) []byte {
+	n := s.uint64()
+	if s.err != nil {
+		return nil
+	}
+	if n != uint64(size) || uint64(len(s.body)) < n {
+		s.err = fmt.Errorf("%w: section of %d bytes, want %d", ErrBadSnapshot, n, size)
+		return nil
+	}
+	b := s.body[:n]
+	s.body = s.body[n:]
+	return b
+}
+
+// sectionUpTo returns the next section, which must be at most limit bytes long.
+func (s *snapshotReader) sectionUpTo(limit int) []byte {
+	n := s.int(limit)
+	if s.err != nil {
+		return nil
+	}
+	if len(s.body) < n {
+		s.err = fmt.Errorf("%w: truncated", ErrBadSnapshot)
+		return nil
+	}
+	b := s.body[:n]
+	s.body = s.body[n:]
+	return b
+}
+
+func (s *snapshotReader) int(limit int) int {
+	v := s.uint64()
+	if s.err == nil && v > uint64(limit) {
+		s.err = fmt.Errorf("%w: %d exceeds %d", ErrBadSnapshot, v, limit)
+	}
+	return int(v)
+}
+
+// encodeSnapshot writes the elements together with the memoized tree, which must be up to date.
+func (arr *byteBasedUint64Slice) encodeSnapshot(w io.Writer, compress bool) error {
+	s := &snapshotWriter{}
+	s.uint64(uint64(arr.c))
+	s.uint64(uint64(arr.l))
+	s.section(arr.u[:length.Hash*((arr.l+3)/4)])
+	s.section(arr.root[:])
+	if !arr.layers.valid { // e.g. empty lists are not merkleized
+		s.uint64(0)
+		return s.writeTo(w, compress)
+	}
+	s.uint64(uint64(len(arr.layers.layers)))
+	for _, layer := range arr.layers.layers {
+		s.section(layer)
+	}
+	return s.writeTo(w, compress)
+}
+
+// snapshotMaxSize returns the size of the snapshot body of a full container.
+func (arr *byteBasedUint64Slice) snapshotMaxSize() int {
+	n := (arr.c + 3) / 4
+	depth := int(GetDepth((uint64(arr.c)*8 + length.Hash - 1) / length.Hash))
+	size := 3*8 + (8 + length.Hash*n) + (8 + length.Hash)
+	for h := 1; h <= depth; h++ {
+		size += 8 + layerLength(n, h)*length.Hash
+	}
+	return size
+}
+
+// decodeSnapshot restores the elements and the memoized tree written by encodeSnapshot, vectors must be full.
+func (arr *byteBasedUint64Slice) decodeSnapshot(r io.Reader, vector bool) error {
+	s, err := readSnapshot(r, arr.snapshotMaxSize())
+	if err != nil {
+		return err
+	}
+	if c := s.int(arr.c); s.err == nil && c != arr.c {
+		return fmt.Errorf("%w: capacity %d, want %d", ErrBadSnapshot, c, arr.c)
+	}
+	l := s.int(arr.c)
+	if s.err == nil && vector && l != arr.c {
+		return fmt.Errorf("%w: length %d, want %d", ErrBadSnapshot, l, arr.c)
+	}
+	n := (l + 3) / 4
+	elements := s.section(length.Hash * n)
+	var root [32]byte
+	copy(root[:], s.section(length.Hash))
+	depth := GetDepth((uint64(arr.c)*8 + length.Hash - 1) / length.Hash)
+	count := s.int(int(depth))
+	if s.err == nil && count != 0 && count != int(depth) {
+		return fmt.Errorf("%w: %d layers, want %d", ErrBadSnapshot, count, depth)
+	}
+	layers := make([][]byte, count)
+	for h := 1; h <= count; h++ {
+		layers[h-1] = append([]byte(nil), s.section(layerLength(n, h)*length.Hash)...)
+	}
+	if s.err != nil {
+		return s.err
+	}
+	arr.Release()
+	arr.allocate(len(elements))
+	copy(arr.u, elements)
+	arr.l, arr.root = l, root
+	arr.layers = merkleLayers{layers: layers, valid: count > 0}
+	return nil
+}
+
+// maxPendingAttestationSize is the size of a pending attestation of a full committee.
+const maxPendingAttestationSize = pendingAttestationStaticBufferSize + 2048/8 + 1
+
+// EncodeSnapshot writes the validators, with their cached hashes, attester bits and phase0 data, in the snapshot format.
+// The phase0 data is written as the amount of validators having some, then the index of each and its two pending
+// attestations, empty for a missing one. Validators sharing an attestation get their own copy of it once restored.
+func (v *ValidatorSet) EncodeSnapshot(w io.Writer, compress bool) error {
+	if _, err := v.HashSSZ(); err != nil {
+		return err
+	}
+	s := &snapshotWriter{}
+	s.uint64(uint64(v.c))
+	s.uint64(uint64(v.l))
+	s.section(v.buffer[:v.l*validatorSize])
+	s.section(v.treeCacheBuffer[:getTreeCacheSize(v.l, validatorTreeCacheGroupLayer)*length.Hash])
+	s.section(v.attesterBits[:v.l])
+	var withPhase0 []int
+	for i := 0; i < v.l && i < len(v.phase0Data); i++ {
+		if d := v.phase0Data[i]; d.MinCurrentInclusionDelayAttestation != nil || d.MinPreviousInclusionDelayAttestation != nil {
+			withPhase0 = append(withPhase0, i)
+		}
+	}
+	s.uint64(uint64(len(withPhase0)))
+	for _, i := range withPhase0 {
+		s.uint64(uint64(i))
+		d := v.phase0Data[i]
+		for _, a := range []*PendingAttestation{d.MinCurrentInclusionDelayAttestation, d.MinPreviousInclusionDelayAttestation} {
+			if a == nil {
+				s.section(nil)
+				continue
+			}
+			enc, err := a.EncodeSSZ(nil)
+			if err != nil {
+				return err
+			}
+			s.section(enc)
+		}
+	}
+	return s.writeTo(w, compress)
+}
+
+// DecodeSnapshot restores the validators written by EncodeSnapshot.
+func (v *ValidatorSet) DecodeSnapshot(r io.Reader) error {
+	maxSize := 2*8 + (8 + v.c*validatorSize) + (8 + getTreeCacheSize(v.c, validatorTreeCacheGroupLayer)*length.Hash) + (8 + v.c) +
+		8 + v.c*(8+2*(8+maxPendingAttestationSize))
+	s, err := readSnapshot(r, maxSize)
+	if err != nil {
+		return err
+	}
+	if c := s.int(v.c); s.err == nil && c != v.c {
+		return fmt.Errorf("%w: capacity %d, want %d", ErrBadSnapshot, c, v.c)
+	}
+	l := s.int(v.c)
+	buffer := s.section(l * validatorSize)
+	treeCache := s.section(getTreeCacheSize(l, validatorTreeCacheGroupLayer) * length.Hash)
+	attesterBits := s.section(l)
+	phase0Data := make([]Phase0Data, l)
+	count := s.int(l)
+	for c, next := 0, 0; c < count && s.err == nil; c++ {
+		i := s.int(l - 1)
+		if s.err != nil {
+			break
+		}
+		if i < next {
+			return fmt.Errorf("%w: phase0 data of validator %d out of order", ErrBadSnapshot, i)
+		}
+		next = i + 1
+		for _, a := range []**PendingAttestation{&phase0Data[i].MinCurrentInclusionDelayAttestation, &phase0Data[i].MinPreviousInclusionDelayAttestation} {
+			if enc := s.sectionUpTo(maxPendingAttestationSize); s.err == nil && len(enc) > 0 {
+				*a = &PendingAttestation{}
+				if err := (*a).DecodeSSZ(enc, 0); err != nil {
+					return fmt.Errorf("%w: phase0 data of validator %d: %w", ErrBadSnapshot, i, err)
+				}
+			}
+		}
+	}
+	if s.err != nil {
+		return s.err
+	}
+	v.expandBuffer(l)
+	copy(v.buffer, buffer)
+	copy(v.treeCacheBuffer, treeCache)
+	v.l = l
+	v.phase0Data = phase0Data
+	v.attesterBits = append(v.attesterBits[:0], attesterBits...)
+	return nil
+}
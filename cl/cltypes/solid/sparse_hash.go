@@ -0,0 +1,108 @@
+package solid
+
+import (
+	"bytes"
+	"sync/atomic"
+
+	"github.com/ledgerwatch/erigon-lib/common/dbg"
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/metrics"
+	"github.com/ledgerwatch/erigon/cl/merkle_tree"
+)
+
+// sparseMinRun is the amount of consecutive zero node pairs from which they are not hashed in sparse mode: shorter
+// runs are hashed along with their neighbours, splitting the layer costing more than hashing them.
+const sparseMinRun = 8
+
+// zeroPairs[h] is the pair of zero hashes at height h, the children of the zero hash at height h+1.
+var zeroPairs [len(merkle_tree.ZeroHashes) - 1][2 * length.Hash]byte
+
+var sparseHashing atomic.Bool
+
+// nodes set to a zero hash instead of being hashed, in sparse mode
+var zeroNodesSkipped = metrics.GetOrCreateCounter(`caplin_solid_zero_nodes_skipped`)
+
+func init() {
+	for h := range zeroPairs {
+		copy(zeroPairs[h][:], merkle_tree.ZeroHashes[h][:])
+		copy(zeroPairs[h][length.Hash:], merkle_tree.ZeroHashes[h][:])
+	}
+	SetSparseHashing(dbg.EnvBool("CAPLIN_SOLID_SPARSE_HASHING", true))
+}
+
+// SetSparseHashing enables or disables the sparse mode of the memoized merkle trees, in which the subtrees of zero
+// leaves are set to the precomputed zero hash of their height instead of being hashed: hashing a mostly empty
+// container (e.g. the freshly grown slashings) then costs about as much as hashing its non-zero leaves.
+func SetSparseHashing(enabled bool) {
+	sparseHashing.Store(enabled)
+}
+
+// SparseHashing returns whether the memoized merkle trees skip the subtrees of zero leaves.
+func SparseHashing() bool {
+	return sparseHashing.Load()
+}
+
+// hashPairs hashes the node pairs at height h-1 of in into out, like hashLayer. In sparse mode, the runs of pairs of
+// zero hashes are set to the zero hash at height h instead.
+func hashPairs(out, in []byte, h int) error {
+	if !SparseHashing() {
+		return hashLayer(out, in)
+	}
+	zero, pairs := zeroPairs[h-1][:], len(in)/(2*length.Hash)
+	isZero := func(i int) bool {
+		return bytes.Equal(in[i*2*length.Hash:(i+1)*2*length.Hash], zero)
+	}
+	dense := 0 // first pair of the current run of pairs to hash
+	for i := 0; i < pairs; i++ {
+		if !isZero(i) {
+			continue
+		}
+		j := i + 1
+		for j < pairs && isZero(j) {
+			j++
+		}
+		if j-i < sparseMinRun {
+			i = j
+			continue
+		}
+		if dense < i {
+			if err := hashLayer(out[dense*length.Hash:i*length.Hash], in[dense*2*length.Hash:i*2*length.Hash]); err != nil {
+				return err
+			}
+		}
+		fillZeroHashes(out[i*length.Hash:j*length.Hash], h)
+		zeroNodesSkipped.AddInt(j - i)
+		dense, i = j, j
+	}
+	if dense < pairs {
+		return hashLayer(out[dense*length.Hash:], in[dense*2*length.Hash:])
+	}
+	return nil
+}
+
+// fillZeroHashes sets all the nodes of layer to the zero hash at height h.
+func fillZeroHashes(layer []byte, h int) {
+	if len(layer) == 0 {
+		return
+	}
+	filled := copy(layer, merkle_tree.ZeroHashes[h][:])
+	for filled < len(layer) {
+		filled += copy(layer[filled:], layer[:filled])
+	}
+}
+
+// isZeroBytes returns whether all the bytes of b are zero.
+func isZeroBytes(b []byte) bool {
+	for len(b) >= len(zeroPairs[0]) {
+		if !bytes.Equal(b[:len(zeroPairs[0])], zeroPairs[0][:]) {
+			return false
+		}
+		b = b[len(zeroPairs[0]):]
+	}
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+package solid
+
+import (
+	"crypto/sha256"
+	"sync/atomic"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/metrics"
+	"github.com/ledgerwatch/erigon/cl/merkle_tree"
+)
+
+// subtreeCacheHeight is the height of the subtrees stored in the subtree cache: 1024 leaves, e.g. 4096 balances.
+const subtreeCacheHeight = 10
+
+// SubtreeCache stores the internal nodes of full subtrees of the memoized merkle trees, keyed by the sha256 of their
+// leaves: looking them up when rebuilding a tree (e.g. after decoding a state on restart, or the one of a reorged
+// branch) costs a quarter of the merkleization of the leaves. Implementations must be safe for concurrent use, and
+// may drop entries at will.
+type SubtreeCache interface {
+	// Get returns the nodes of the subtree, from the lowest layer up, or false if they are not cached.
+	Get(key [32]byte) ([]byte, bool)
+	// Put stores the nodes of the subtree, which must not be retained as given.
+	Put(key [32]byte, nodes []byte)
+}
+
+type subtreeCacheHolder struct {
+	SubtreeCache
+}
+
+var subtreeCache atomic.Pointer[subtreeCacheHolder]
+
+var (
+	subtreeCacheHits   = metrics.GetOrCreateCounter(`caplin_solid_subtree_cache{result="hit"}`)
+	subtreeCacheMisses = metrics.GetOrCreateCounter(`caplin_solid_subtree_cache{result="miss"}`)
+)
+
+// SetSubtreeCache sets the cache used by the trees rebuilt from then on, nil disables it.
+func SetSubtreeCache(c SubtreeCache) {
+	if c == nil {
+		subtreeCache.Store(nil)
+		return
+	}
+	subtreeCache.Store(&subtreeCacheHolder{c})
+}
+
+func loadSubtreeCache() SubtreeCache {
+	if h := subtreeCache.Load(); h != nil {
+		return h.SubtreeCache
+	}
+	return nil
+}
+
+// subtreeNodesLength is the length of the nodes of a cached subtree: all but the leaves.
+const subtreeNodesLength = (1<<subtreeCacheHeight - 1) * length.Hash
+
+// fillSubtrees computes the layers of the first full subtrees, up to subtreeCacheHeight, from the cache if possible.
+func (m *merkleLayers) fillSubtrees(cache SubtreeCache, leaves []byte, full int) error {
+	var g errgroup.Group
+	g.SetLimit(HashWorkers())
+	for s := 0; s < full; s++ {
+		s := s
+		g.Go(func() error {
+			return m.fillSubtree(cache, leaves, s)
+		})
+	}
+	return g.Wait()
+}
+
+func (m *merkleLayers) fillSubtree(cache SubtreeCache, leaves []byte, s int) error {
+	const size = 1 << subtreeCacheHeight
+	prev := leaves[s*size*length.Hash : (s+1)*size*length.Hash]
+	if SparseHashing() && isZeroBytes(prev) { // not worth caching
+		for h := 1; h <= subtreeCacheHeight; h++ {
+			count := (size >> h) * length.Hash
+			fillZeroHashes(m.layers[h-1][s*count:(s+1)*count], h)
+			zeroNodesSkipped.AddInt(size >> h)
+		}
+		return nil
+	}
+	key := sha256.Sum256(prev)
+	nodes, ok := cache.Get(key)
+	if ok && len(nodes) == subtreeNodesLength {
+		subtreeCacheHits.Inc()
+		for h, offset := 1, 0; h <= subtreeCacheHeight; h++ {
+			count := (size >> h) * length.Hash
+			copy(m.layers[h-1][s*count:(s+1)*count], nodes[offset:offset+count])
+			offset += count
+		}
+		return nil
+	}
+	subtreeCacheMisses.Inc()
+	nodes = make([]byte, 0, subtreeNodesLength)
+	for h := 1; h <= subtreeCacheHeight; h++ {
+		count := (size >> h) * length.Hash
+		layer := m.layers[h-1][s*count : (s+1)*count]
+		if err := merkle_tree.HashByteSlice(layer, prev); err != nil {
+			return err
+		}
+		nodes = append(nodes, layer...)
+		prev = layer
+	}
+	cache.Put(key, nodes)
+	return nil
+}
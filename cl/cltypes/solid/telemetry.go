@@ -0,0 +1,37 @@
+package solid
+
+import (
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/metrics"
+)
+
+// Hashing telemetry of the memoized merkle trees, shared by all the containers: a call either finds the tree up to
+// date ("cached"), rehashes the branches of the dirty leaves ("incremental") or recomputes it ("rebuild"). The cache
+// hit ratio is cached / (cached + incremental + rebuild), and the leaves counters tell how much was rehashed.
+var (
+	hashCallsCached      = metrics.GetOrCreateCounter(`caplin_solid_hash_calls{path="cached"}`)
+	hashCallsIncremental = metrics.GetOrCreateCounter(`caplin_solid_hash_calls{path="incremental"}`)
+	hashCallsRebuild     = metrics.GetOrCreateCounter(`caplin_solid_hash_calls{path="rebuild"}`)
+
+	hashLeavesIncremental = metrics.GetOrCreateCounter(`caplin_solid_hash_leaves{path="incremental"}`)
+	hashLeavesRebuild     = metrics.GetOrCreateCounter(`caplin_solid_hash_leaves{path="rebuild"}`)
+
+	hashDurationIncremental = metrics.GetOrCreateHistogram(`caplin_solid_hash_seconds{path="incremental"}`)
+	hashDurationRebuild     = metrics.GetOrCreateHistogram(`caplin_solid_hash_seconds{path="rebuild"}`)
+
+	// the validator set caches the roots of groups of validators instead, any change rehashing its whole group
+	validatorGroupsCached   = metrics.GetOrCreateCounter(`caplin_solid_validator_groups{path="cached"}`)
+	validatorGroupsRehashed = metrics.GetOrCreateCounter(`caplin_solid_validator_groups{path="rehashed"}`)
+	validatorsHashed        = metrics.GetOrCreateCounter(`caplin_solid_validators_hashed`)
+
+	// layers hashed by hashLayer, large ones being split across goroutines
+	hashLayersParallel   = metrics.GetOrCreateCounter(`caplin_solid_hash_layers{mode="parallel"}`)
+	hashLayersSequential = metrics.GetOrCreateCounter(`caplin_solid_hash_layers{mode="sequential"}`)
+)
+
+// ObserveFieldHash records the time spent hashing a field of a container since begin, in the
+// caplin_solid_field_hash_seconds histogram labelled with the field name.
+func ObserveFieldHash(field string, begin time.Time) {
+	metrics.GetOrCreateHistogram(`caplin_solid_field_hash_seconds{field="` + field + `"}`).ObserveDuration(begin)
+}
@@ -0,0 +1,122 @@
+package solid
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/common/length"
+)
+
+// ErrBatchConflict is returned when committing a batch to a container resized since the batch began.
+var ErrBatchConflict = errors.New("solid: container resized since the batch began")
+
+// batchCommitter is implemented by the uint64 containers MutationBatch can commit to.
+type batchCommitter interface {
+	commitBatch(base int, sets map[int]uint64, appends []uint64) error
+}
+
+// MutationBatch stages Set and Append operations on a uint64 list or vector, so that e.g. a speculative state
+// transition can be reverted at no cost by discarding its batch. Staged values are visible through the batch only;
+// Commit writes them to the container at once, marking each touched chunk dirty once. The container must not be
+// resized while a batch is open, and a batch is not safe for concurrent use even if the container is.
+type MutationBatch struct {
+	target  Uint64SSZ
+	base    int // length of the container when the batch began
+	sets    map[int]uint64
+	appends []uint64
+}
+
+func newMutationBatch(target Uint64SSZ) *MutationBatch {
+	return &MutationBatch{target: target, base: target.Length(), sets: map[int]uint64{}}
+}
+
+// Length returns the length of the container with the staged appends.
+func (b *MutationBatch) Length() int {
+	return b.base + len(b.appends)
+}
+
+// Get returns the staged value of the element at index, or the one in the container if none is staged.
+func (b *MutationBatch) Get(index int) uint64 {
+	if index < 0 || index >= b.Length() {
+		panic(fmt.Errorf("%w: index %d, length %d", ErrIndexOutOfRange, index, b.Length()))
+	}
+	if index >= b.base {
+		return b.appends[index-b.base]
+	}
+	if v, ok := b.sets[index]; ok {
+		return v
+	}
+	return b.target.Get(index)
+}
+
+// Set stages the replacement of the element at index.
+func (b *MutationBatch) Set(index int, v uint64) {
+	if index < 0 || index >= b.Length() {
+		panic(fmt.Errorf("%w: index %d, length %d", ErrIndexOutOfRange, index, b.Length()))
+	}
+	if index >= b.base {
+		b.appends[index-b.base] = v
+		return
+	}
+	b.sets[index] = v
+}
+
+// Append stages v at the end of the list, it panics on vectors.
+func (b *MutationBatch) Append(v uint64) {
+	if b.target.Static() {
+		panic("not implemented")
+	}
+	if b.Length() >= b.target.Cap() {
+		panic(fmt.Errorf("%w: append beyond capacity %d", ErrIndexOutOfRange, b.target.Cap()))
+	}
+	b.appends = append(b.appends, v)
+}
+
+// Staged returns the amount of staged operations.
+func (b *MutationBatch) Staged() int {
+	return len(b.sets) + len(b.appends)
+}
+
+// Commit applies the staged operations to the container and empties the batch, which can then be reused.
+func (b *MutationBatch) Commit() error {
+	if b.Staged() > 0 {
+		if err := b.target.(batchCommitter).commitBatch(b.base, b.sets, b.appends); err != nil {
+			return err
+		}
+	}
+	b.base = b.target.Length()
+	b.Discard()
+	return nil
+}
+
+// Discard drops the staged operations, leaving the container untouched.
+func (b *MutationBatch) Discard() {
+	clear(b.sets)
+	b.appends = b.appends[:0]
+}
+
+func (arr *byteBasedUint64Slice) commitBatch(base int, sets map[int]uint64, appends []uint64) error {
+	if arr.l != base {
+		return fmt.Errorf("%w: length %d, was %d", ErrBatchConflict, arr.l, base)
+	}
+	arr.own()
+	end := arr.l + len(appends)
+	if size := length.Hash * ((end + 3) / 4); len(arr.u) < size {
+		arr.u = append(arr.u, make([]byte, size-len(arr.u))...)
+	}
+	for index, v := range sets {
+		binary.LittleEndian.PutUint64(arr.u[index*8:], v)
+		arr.layers.markDirty(index / 4)
+	}
+	for i, v := range appends {
+		binary.LittleEndian.PutUint64(arr.u[(arr.l+i)*8:], v)
+	}
+	if len(appends) > 0 {
+		for chunk := arr.l / 4; chunk < (end+3)/4; chunk++ {
+			arr.layers.markDirty(chunk)
+		}
+	}
+	arr.l = end
+	return nil
+}
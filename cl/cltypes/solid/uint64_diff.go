@@ -0,0 +1,101 @@
+package solid
+
+import "encoding/binary"
+
+// IndexedChange is an element differing between two uint64 containers, Old being the value in the receiver of Diff.
+// Elements past the end of the shorter container are compared as zeros, so appends and removals are told apart by
+// the lengths.
+type IndexedChange struct {
+	Index    int
+	Old, New uint64
+}
+
+// backingUint64Slice returns the flat slice behind the uint64 containers of this package, nil for other ones.
+func backingUint64Slice(s IterableSSZ[uint64]) *byteBasedUint64Slice {
+	switch s := s.(type) {
+	case *uint64ListSSZ:
+		return s.u
+	case *uint64VectorSSZ:
+		return s.u
+	}
+	return nil
+}
+
+func (arr *byteBasedUint64Slice) getOrZero(index int) uint64 {
+	if index >= arr.l {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(arr.u[index*8:])
+}
+
+// clean returns whether the memoized tree matches the elements.
+func (arr *byteBasedUint64Slice) clean() bool {
+	return arr.layers.valid && arr.layers.dirtyCount == 0
+}
+
+// diff calls fn with the indices of the elements differing in other, until it returns false. If both memoized trees
+// are up to date, subtrees with equal roots are skipped.
+func (arr *byteBasedUint64Slice) diff(other IterableSSZ[uint64], fn func(index int, old, new uint64) bool) {
+	b := backingUint64Slice(other)
+	if b == nil {
+		for i := 0; i < max(arr.l, other.Length()); i++ {
+			var v uint64
+			if i < other.Length() {
+				v = other.Get(i)
+			}
+			if old := arr.getOrZero(i); old != v && !fn(i, old, v) {
+				return
+			}
+		}
+		return
+	}
+	n := max(arr.l, b.l)
+	if !arr.clean() || !b.clean() || len(arr.layers.layers) != len(b.layers.layers) {
+		for i := 0; i < n; i++ {
+			if old, v := arr.getOrZero(i), b.getOrZero(i); old != v && !fn(i, old, v) {
+				return
+			}
+		}
+		return
+	}
+	leavesA, leavesB := arr.u[:32*((arr.l+3)/4)], b.u[:32*((b.l+3)/4)]
+	var walk func(h, idx int) bool
+	walk = func(h, idx int) bool {
+		if arr.layers.node(leavesA, h, idx) == b.layers.node(leavesB, h, idx) {
+			return true
+		}
+		if h > 0 {
+			return walk(h-1, 2*idx) && walk(h-1, 2*idx+1)
+		}
+		for i := idx * 4; i < idx*4+4 && i < n; i++ {
+			if old, v := arr.getOrZero(i), b.getOrZero(i); old != v && !fn(i, old, v) {
+				return false
+			}
+		}
+		return true
+	}
+	walk(len(arr.layers.layers), 0)
+}
+
+// equal returns whether other has the same length and elements.
+func (arr *byteBasedUint64Slice) equal(other IterableSSZ[uint64]) bool {
+	if arr.l != other.Length() {
+		return false
+	}
+	equal := true
+	arr.diff(other, func(int, uint64, uint64) bool {
+		equal = false
+		return false
+	})
+	return equal
+}
+
+// changes collects the elements differing in other.
+func (arr *byteBasedUint64Slice) changes(other IterableSSZ[uint64]) []IndexedChange {
+	var changes []IndexedChange
+	arr.diff(other, func(index int, old, new uint64) bool {
+		changes = append(changes, IndexedChange{Index: index, Old: old, New: new})
+		return true
+	})
+	return changes
+}
@@ -2,6 +2,7 @@ package solid
 
 import (
 	"encoding/json"
+	"io"
 
 	"github.com/ledgerwatch/erigon-lib/types/clonable"
 )
@@ -38,6 +39,11 @@ func NewUint64ListSSZFromSlice(limit int, slice []uint64) Uint64ListSSZ {
 	return x
 }
 
+// Release returns the backing buffer to the pool, see byteBasedUint64Slice.Release.
+func (arr *uint64ListSSZ) Release() {
+	arr.u.Release()
+}
+
 func (arr *uint64ListSSZ) Clear() {
 	arr.u.Clear()
 }
@@ -58,6 +64,14 @@ func (arr *uint64ListSSZ) Range(fn func(index int, value uint64, length int) boo
 	arr.u.Range(fn)
 }
 
+func (arr *uint64ListSSZ) RangeFrom(start int, fn func(index int, value uint64, length int) bool) {
+	arr.u.RangeFrom(start, fn)
+}
+
+func (arr *uint64ListSSZ) RangeReverse(fn func(index int, value uint64, length int) bool) {
+	arr.u.RangeReverse(fn)
+}
+
 func (arr *uint64ListSSZ) Get(index int) uint64 {
 	return arr.u.Get(index)
 }
@@ -66,6 +80,54 @@ func (arr *uint64ListSSZ) Set(index int, v uint64) {
 	arr.u.Set(index, v)
 }
 
+func (arr *uint64ListSSZ) GetErr(index int) (uint64, error) {
+	return arr.u.GetErr(index)
+}
+
+func (arr *uint64ListSSZ) SetErr(index int, v uint64) error {
+	return arr.u.SetErr(index, v)
+}
+
+func (arr *uint64ListSSZ) SetRange(start int, vals []uint64) {
+	arr.u.SetRange(start, vals)
+}
+
+func (arr *uint64ListSSZ) SetAligned(start int, vals []uint64) error {
+	return arr.u.SetAligned(start, vals)
+}
+
+func (arr *uint64ListSSZ) AddAt(index int, delta int64) {
+	arr.u.AddAt(index, delta)
+}
+
+func (arr *uint64ListSSZ) ApplyDelta(deltas []int64) {
+	arr.u.ApplyDelta(deltas)
+}
+
+func (arr *uint64ListSSZ) IsSorted() bool {
+	return arr.u.IsSorted()
+}
+
+func (arr *uint64ListSSZ) BinarySearch(v uint64) (int, bool) {
+	return arr.u.BinarySearch(v)
+}
+
+func (arr *uint64ListSSZ) InsertSorted(v uint64) {
+	arr.u.InsertSorted(v)
+}
+
+func (arr *uint64ListSSZ) Insert(index int, v uint64) {
+	arr.u.Insert(index, v)
+}
+
+func (arr *uint64ListSSZ) RemoveAt(index int) {
+	arr.u.RemoveAt(index)
+}
+
+func (arr *uint64ListSSZ) Truncate(n int) {
+	arr.u.Truncate(n)
+}
+
 func (arr *uint64ListSSZ) Length() int {
 	return arr.u.Length()
 }
@@ -75,13 +137,97 @@ func (arr *uint64ListSSZ) Cap() int {
 }
 
 func (arr *uint64ListSSZ) HashSSZ() ([32]byte, error) {
-	return arr.u.HashListSSZ()
+	root, err := arr.u.HashListSSZ()
+	return assertHashSSZ(arr, root, err)
+}
+
+// HashSSZDelta is HashSSZ when only the elements at changed were modified since prevRoot was computed, possibly
+// through Bytes: just their branches are recomputed.
+func (arr *uint64ListSSZ) HashSSZDelta(prevRoot [32]byte, changed []int) ([32]byte, error) {
+	root, err := arr.u.hashDelta(prevRoot, changed, arr.u.HashListSSZ)
+	return assertHashSSZ(arr, root, err)
+}
+
+// MarkDirty records that the element at index was modified through Bytes, see HashSSZDelta.
+func (arr *uint64ListSSZ) MarkDirty(index int) {
+	arr.u.MarkDirty(index)
+}
+
+// HashSSZWithScratch is HashSSZ, the list keeps its own memoized merkle layers so s is not needed.
+func (arr *uint64ListSSZ) HashSSZWithScratch(_ *Scratch) ([32]byte, error) {
+	return arr.HashSSZ()
+}
+
+// HashSSZWithBranches computes the root of the list and, in the same merkleization pass, the merkle branch of the
+// chunk holding each element at indices. Every branch ends with the length mix-in, so it can be verified against
+// the returned root with depth GetDepth(chunks limit)+1.
+func (arr *uint64ListSSZ) HashSSZWithBranches(indices ...int) ([32]byte, [][][32]byte, error) {
+	root, branches, err := arr.u.hashListSSZWithBranches(indices)
+	if err != nil {
+		return [32]byte{}, nil, err
+	}
+	root, err = assertHashSSZ(arr, root, err)
+	return root, branches, err
+}
+
+// ProveIndex returns the merkle branch of the element at index i against the root of the list, see
+// HashSSZWithBranches.
+func (arr *uint64ListSSZ) ProveIndex(i int) ([][32]byte, error) {
+	_, branches, err := arr.HashSSZWithBranches(i)
+	if err != nil {
+		return nil, err
+	}
+	return branches[0], nil
+}
+
+// ProveIndices returns the merkle branches of the elements at indices, all computed in one merkleization pass.
+func (arr *uint64ListSSZ) ProveIndices(indices ...int) ([][][32]byte, error) {
+	_, branches, err := arr.HashSSZWithBranches(indices...)
+	return branches, err
+}
+
+// CloneShared returns a copy-on-write copy of the list: it shares the backing buffer and the memoized merkle tree
+// with arr until either of them is mutated, so it costs no copy of the elements.
+func (arr *uint64ListSSZ) CloneShared() IterableSSZ[uint64] {
+	return &uint64ListSSZ{u: arr.u.cloneShared()}
 }
 
 func (arr *uint64ListSSZ) Clone() clonable.Clonable {
 	return NewUint64ListSSZ(arr.Cap())
 }
 
+// Equal returns whether other has the same elements, skipping the equal subtrees of the memoized merkle trees.
+func (arr *uint64ListSSZ) Equal(other IterableSSZ[uint64]) bool {
+	return arr.u.equal(other)
+}
+
+// Diff returns the elements of other differing from the list, in ascending index order.
+func (arr *uint64ListSSZ) Diff(other IterableSSZ[uint64]) []IndexedChange {
+	return arr.u.changes(other)
+}
+
+// Begin returns a batch staging mutations of the list.
+func (arr *uint64ListSSZ) Begin() *MutationBatch {
+	return newMutationBatch(arr)
+}
+
+func (arr *uint64ListSSZ) commitBatch(base int, sets map[int]uint64, appends []uint64) error {
+	return arr.u.commitBatch(base, sets, appends)
+}
+
+// EncodeSnapshot writes the elements, with the memoized merkle tree, in the snapshot format.
+func (arr *uint64ListSSZ) EncodeSnapshot(w io.Writer, compress bool) error {
+	if _, err := arr.HashSSZ(); err != nil {
+		return err
+	}
+	return arr.u.encodeSnapshot(w, compress)
+}
+
+// DecodeSnapshot restores the elements and the memoized merkle tree written by EncodeSnapshot.
+func (arr *uint64ListSSZ) DecodeSnapshot(r io.Reader) error {
+	return arr.u.decodeSnapshot(r, false)
+}
+
 func (arr *uint64ListSSZ) EncodeSSZ(buf []byte) (dst []byte, err error) {
 	return arr.u.EncodeSSZ(buf)
 }
@@ -102,6 +248,10 @@ func (arr *uint64ListSSZ) Append(v uint64) {
 	arr.u.Append(v)
 }
 
+func (arr *uint64ListSSZ) AppendMultiple(vals ...uint64) {
+	arr.u.AppendMultiple(vals...)
+}
+
 // Check if it is sorted and check if there are duplicates. O(N) complexity.
 func IsUint64SortedSet(set IterableSSZ[uint64]) bool {
 	for i := 0; i < set.Length()-1; i++ {
@@ -0,0 +1,79 @@
+package solid
+
+import "container/heap"
+
+// MergedUint64Iterator yields the values of several sorted uint64 slices in global ascending order,
+// without materializing the merged slice. Equal values are yielded in the order of their slices.
+type MergedUint64Iterator struct {
+	h      mergeHeap
+	length int
+}
+
+type mergeCursor struct {
+	src, pos int
+	s        IterableSSZ[uint64]
+}
+
+type mergeHeap []mergeCursor
+
+func (h mergeHeap) Len() int { return len(h) }
+func (h mergeHeap) Less(i, j int) bool {
+	vi, vj := h[i].s.Get(h[i].pos), h[j].s.Get(h[j].pos)
+	if vi != vj {
+		return vi < vj
+	}
+	return h[i].src < h[j].src
+}
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x any)   { *h = append(*h, x.(mergeCursor)) }
+func (h *mergeHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// MergeSortedUint64 returns an iterator over slices, each of which must be sorted in ascending order.
+// The slices must not be modified while iterating.
+func MergeSortedUint64(slices ...IterableSSZ[uint64]) *MergedUint64Iterator {
+	it := &MergedUint64Iterator{h: make(mergeHeap, 0, len(slices))}
+	for i, s := range slices {
+		if s == nil || s.Length() == 0 {
+			continue
+		}
+		it.h = append(it.h, mergeCursor{src: i, s: s})
+		it.length += s.Length()
+	}
+	heap.Init(&it.h)
+	return it
+}
+
+// HasNext returns whether there are values left.
+func (it *MergedUint64Iterator) HasNext() bool {
+	return len(it.h) > 0
+}
+
+// Next returns the smallest value left and the index of the slice it comes from.
+// It panics if there are no values left.
+func (it *MergedUint64Iterator) Next() (v uint64, src int) {
+	c := &it.h[0]
+	v, src = c.s.Get(c.pos), c.src
+	c.pos++
+	if c.pos == c.s.Length() {
+		heap.Pop(&it.h)
+	} else {
+		heap.Fix(&it.h, 0)
+	}
+	return v, src
+}
+
+// Range consumes the iterator, calling fn with the position of each value in the merged order
+// and the total amount of values, so that it can be used as a Ranger.
+func (it *MergedUint64Iterator) Range(fn func(idx int, v uint64, length int) bool) {
+	for i := 0; it.HasNext(); i++ {
+		v, _ := it.Next()
+		if !fn(i, v, it.length) {
+			return
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package solid_test
+
+import (
+	"testing"
+
+	"github.com/ledgerwatch/erigon/cl/cltypes/solid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeSortedUint64(t *testing.T) {
+	a := solid.NewUint64ListSSZFromSlice(8, []uint64{1, 4, 7})
+	b := solid.NewUint64ListSSZFromSlice(8, []uint64{2, 4, 9, 10})
+	c := solid.NewRawUint64List(8, []uint64{0, 3})
+	empty := solid.NewUint64ListSSZ(8)
+
+	it := solid.MergeSortedUint64(a, empty, b, c)
+	var values []uint64
+	var sources []int
+	for it.HasNext() {
+		v, src := it.Next()
+		values = append(values, v)
+		sources = append(sources, src)
+	}
+	require.Equal(t, []uint64{0, 1, 2, 3, 4, 4, 7, 9, 10}, values)
+	require.Equal(t, []int{3, 0, 2, 3, 0, 2, 0, 2, 2}, sources)
+
+	values = values[:0]
+	solid.MergeSortedUint64(a, b, c).Range(func(idx int, v uint64, length int) bool {
+		require.Equal(t, len(values), idx)
+		require.Equal(t, 9, length)
+		values = append(values, v)
+		return idx < 3
+	})
+	require.Equal(t, []uint64{0, 1, 2, 3}, values)
+
+	require.False(t, solid.MergeSortedUint64().HasNext())
+	require.False(t, solid.MergeSortedUint64(empty).HasNext())
+}
@@ -3,6 +3,7 @@ package solid
 import (
 	"encoding/binary"
 	"encoding/json"
+	"fmt"
 	"strconv"
 
 	libcommon "github.com/ledgerwatch/erigon-lib/common"
@@ -42,9 +43,25 @@ func (arr *RawUint64List) Get(index int) uint64 {
 }
 
 func (arr *RawUint64List) Set(index int, v uint64) {
+	arr.cachedHash = libcommon.Hash{}
 	arr.u[index] = v
 }
 
+func (arr *RawUint64List) GetErr(index int) (uint64, error) {
+	if index < 0 || index >= len(arr.u) {
+		return 0, fmt.Errorf("%w: index %d, length %d", ErrIndexOutOfRange, index, len(arr.u))
+	}
+	return arr.u[index], nil
+}
+
+func (arr *RawUint64List) SetErr(index int, v uint64) error {
+	if index < 0 || index >= len(arr.u) {
+		return fmt.Errorf("%w: index %d, length %d", ErrIndexOutOfRange, index, len(arr.u))
+	}
+	arr.Set(index, v)
+	return nil
+}
+
 func (arr *RawUint64List) CopyTo(target IterableSSZ[uint64]) {
 	if c, ok := target.(*RawUint64List); ok {
 		c.u = append(c.u[:0], arr.u...)
@@ -2,6 +2,7 @@ package solid
 
 import (
 	"encoding/json"
+	"io"
 
 	"github.com/ledgerwatch/erigon-lib/common/length"
 	"github.com/ledgerwatch/erigon-lib/types/clonable"
@@ -13,10 +14,9 @@ type uint64VectorSSZ struct {
 
 func NewUint64VectorSSZ(size int) Uint64VectorSSZ {
 	o := &byteBasedUint64Slice{
-		c:               size,
-		l:               size,
-		u:               make([]byte, size*8),
-		treeCacheBuffer: make([]byte, getTreeCacheSize((size+3)/4, treeCacheDepthUint64Slice)*length.Hash),
+		c: size,
+		l: size,
+		u: make([]byte, length.Hash*((size+3)/4)),
 	}
 	return &uint64VectorSSZ{
 		u: o,
@@ -31,6 +31,11 @@ func (h *uint64VectorSSZ) UnmarshalJSON(buf []byte) error {
 	return json.Unmarshal(buf, h.u)
 }
 
+// Release returns the backing buffer to the pool, see byteBasedUint64Slice.Release.
+func (arr *uint64VectorSSZ) Release() {
+	arr.u.Release()
+}
+
 func (arr *uint64VectorSSZ) Clear() {
 	arr.u.Clear()
 }
@@ -52,6 +57,14 @@ func (arr *uint64VectorSSZ) Range(fn func(index int, value uint64, length int) b
 	arr.u.Range(fn)
 }
 
+func (arr *uint64VectorSSZ) RangeFrom(start int, fn func(index int, value uint64, length int) bool) {
+	arr.u.RangeFrom(start, fn)
+}
+
+func (arr *uint64VectorSSZ) RangeReverse(fn func(index int, value uint64, length int) bool) {
+	arr.u.RangeReverse(fn)
+}
+
 func (arr *uint64VectorSSZ) Get(index int) uint64 {
 	return arr.u.Get(index)
 }
@@ -60,6 +73,38 @@ func (arr *uint64VectorSSZ) Set(index int, v uint64) {
 	arr.u.Set(index, v)
 }
 
+func (arr *uint64VectorSSZ) GetErr(index int) (uint64, error) {
+	return arr.u.GetErr(index)
+}
+
+func (arr *uint64VectorSSZ) SetErr(index int, v uint64) error {
+	return arr.u.SetErr(index, v)
+}
+
+func (arr *uint64VectorSSZ) SetRange(start int, vals []uint64) {
+	arr.u.SetRange(start, vals)
+}
+
+func (arr *uint64VectorSSZ) SetAligned(start int, vals []uint64) error {
+	return arr.u.SetAligned(start, vals)
+}
+
+func (arr *uint64VectorSSZ) AddAt(index int, delta int64) {
+	arr.u.AddAt(index, delta)
+}
+
+func (arr *uint64VectorSSZ) ApplyDelta(deltas []int64) {
+	arr.u.ApplyDelta(deltas)
+}
+
+func (arr *uint64VectorSSZ) IsSorted() bool {
+	return arr.u.IsSorted()
+}
+
+func (arr *uint64VectorSSZ) BinarySearch(v uint64) (int, bool) {
+	return arr.u.BinarySearch(v)
+}
+
 func (arr *uint64VectorSSZ) Length() int {
 	return arr.u.Length()
 }
@@ -69,13 +114,96 @@ func (arr *uint64VectorSSZ) Cap() int {
 }
 
 func (arr *uint64VectorSSZ) HashSSZ() ([32]byte, error) {
-	return arr.u.HashVectorSSZ()
+	root, err := arr.u.HashVectorSSZ()
+	return assertHashSSZ(arr, root, err)
+}
+
+// HashSSZDelta is HashSSZ when only the elements at changed were modified since prevRoot was computed, possibly
+// through Bytes: just their branches are recomputed.
+func (arr *uint64VectorSSZ) HashSSZDelta(prevRoot [32]byte, changed []int) ([32]byte, error) {
+	root, err := arr.u.hashDelta(prevRoot, changed, arr.u.HashVectorSSZ)
+	return assertHashSSZ(arr, root, err)
+}
+
+// MarkDirty records that the element at index was modified through Bytes, see HashSSZDelta.
+func (arr *uint64VectorSSZ) MarkDirty(index int) {
+	arr.u.MarkDirty(index)
+}
+
+// HashSSZWithScratch is HashSSZ, the vector keeps its own memoized merkle layers so s is not needed.
+func (arr *uint64VectorSSZ) HashSSZWithScratch(_ *Scratch) ([32]byte, error) {
+	return arr.HashSSZ()
+}
+
+// HashSSZWithBranches computes the root of the vector and, in the same merkleization pass, the merkle branch of
+// the chunk holding each element at indices.
+func (arr *uint64VectorSSZ) HashSSZWithBranches(indices ...int) ([32]byte, [][][32]byte, error) {
+	root, branches, err := arr.u.hashVectorSSZWithBranches(indices)
+	if err != nil {
+		return [32]byte{}, nil, err
+	}
+	root, err = assertHashSSZ(arr, root, err)
+	return root, branches, err
+}
+
+// ProveIndex returns the merkle branch of the element at index i against the root of the vector, see
+// HashSSZWithBranches.
+func (arr *uint64VectorSSZ) ProveIndex(i int) ([][32]byte, error) {
+	_, branches, err := arr.HashSSZWithBranches(i)
+	if err != nil {
+		return nil, err
+	}
+	return branches[0], nil
+}
+
+// ProveIndices returns the merkle branches of the elements at indices, all computed in one merkleization pass.
+func (arr *uint64VectorSSZ) ProveIndices(indices ...int) ([][][32]byte, error) {
+	_, branches, err := arr.HashSSZWithBranches(indices...)
+	return branches, err
+}
+
+// CloneShared returns a copy-on-write copy of the vector: it shares the backing buffer and the memoized merkle tree
+// with arr until either of them is mutated, so it costs no copy of the elements.
+func (arr *uint64VectorSSZ) CloneShared() IterableSSZ[uint64] {
+	return &uint64VectorSSZ{u: arr.u.cloneShared()}
 }
 
 func (arr *uint64VectorSSZ) Clone() clonable.Clonable {
 	return NewUint64VectorSSZ(arr.Length())
 }
 
+// Equal returns whether other has the same elements, skipping the equal subtrees of the memoized merkle trees.
+func (arr *uint64VectorSSZ) Equal(other IterableSSZ[uint64]) bool {
+	return arr.u.equal(other)
+}
+
+// Diff returns the elements of other differing from the vector, in ascending index order.
+func (arr *uint64VectorSSZ) Diff(other IterableSSZ[uint64]) []IndexedChange {
+	return arr.u.changes(other)
+}
+
+// Begin returns a batch staging mutations of the vector.
+func (arr *uint64VectorSSZ) Begin() *MutationBatch {
+	return newMutationBatch(arr)
+}
+
+func (arr *uint64VectorSSZ) commitBatch(base int, sets map[int]uint64, appends []uint64) error {
+	return arr.u.commitBatch(base, sets, appends)
+}
+
+// EncodeSnapshot writes the elements, with the memoized merkle tree, in the snapshot format.
+func (arr *uint64VectorSSZ) EncodeSnapshot(w io.Writer, compress bool) error {
+	if _, err := arr.HashSSZ(); err != nil {
+		return err
+	}
+	return arr.u.encodeSnapshot(w, compress)
+}
+
+// DecodeSnapshot restores the elements and the memoized merkle tree written by EncodeSnapshot.
+func (arr *uint64VectorSSZ) DecodeSnapshot(r io.Reader) error {
+	return arr.u.decodeSnapshot(r, true)
+}
+
 func (arr *uint64VectorSSZ) EncodeSSZ(buf []byte) (dst []byte, err error) {
 	return arr.u.EncodeSSZ(buf)
 }
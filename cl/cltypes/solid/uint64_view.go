@@ -0,0 +1,104 @@
+package solid
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/types/ssz"
+)
+
+// Uint64View is a read-only uint64 list or vector over its SSZ encoding, which is not copied: elements are decoded
+// on access and the root is only computed if asked for, then cached. The encoding must not be modified while in use.
+type Uint64View struct {
+	buf    []byte
+	c      int
+	vector bool
+
+	root *[32]byte
+}
+
+// NewUint64ListView returns a view over buf, the encoding of a list of at most limit elements.
+func NewUint64ListView(limit int, buf []byte) (*Uint64View, error) {
+	if len(buf)%8 > 0 {
+		return nil, ssz.ErrBadDynamicLength
+	}
+	if len(buf)/8 > limit {
+		return nil, ssz.ErrTooBigList
+	}
+	return &Uint64View{buf: buf, c: limit}, nil
+}
+
+// NewUint64VectorView returns a view over buf, the encoding of a vector of size elements.
+func NewUint64VectorView(size int, buf []byte) (*Uint64View, error) {
+	if len(buf) != size*8 {
+		return nil, ssz.ErrLowBufferSize
+	}
+	return &Uint64View{buf: buf, c: size, vector: true}, nil
+}
+
+func (v *Uint64View) Length() int {
+	return len(v.buf) / 8
+}
+
+func (v *Uint64View) Cap() int {
+	return v.c
+}
+
+// Bytes returns the encoding the view is over.
+func (v *Uint64View) Bytes() []byte {
+	return v.buf
+}
+
+func (v *Uint64View) Get(index int) uint64 {
+	val, err := v.GetErr(index)
+	if err != nil {
+		panic(err)
+	}
+	return val
+}
+
+// GetErr returns the element at the given index, or ErrIndexOutOfRange.
+func (v *Uint64View) GetErr(index int) (uint64, error) {
+	if index < 0 || index >= v.Length() {
+		return 0, fmt.Errorf("%w: index %d, length %d", ErrIndexOutOfRange, index, v.Length())
+	}
+	return binary.LittleEndian.Uint64(v.buf[index*8:]), nil
+}
+
+func (v *Uint64View) Range(fn func(index int, value uint64, length int) bool) {
+	for i := 0; i < v.Length(); i++ {
+		if !fn(i, binary.LittleEndian.Uint64(v.buf[i*8:]), v.Length()) {
+			break
+		}
+	}
+}
+
+func (v *Uint64View) EncodeSSZ(buf []byte) ([]byte, error) {
+	return append(buf, v.buf...), nil
+}
+
+func (v *Uint64View) EncodingSizeSSZ() int {
+	return len(v.buf)
+}
+
+// HashSSZ merkleizes a padded copy of the elements on the first call, which is released right after.
+func (v *Uint64View) HashSSZ() ([32]byte, error) {
+	if v.root != nil {
+		return *v.root, nil
+	}
+	arr := &byteBasedUint64Slice{c: v.c}
+	if err := arr.DecodeSSZ(v.buf, 0); err != nil {
+		return [32]byte{}, err
+	}
+	defer arr.Release()
+	hash := arr.HashListSSZ
+	if v.vector {
+		hash = arr.HashVectorSSZ
+	}
+	root, err := hash()
+	if err != nil {
+		return [32]byte{}, err
+	}
+	v.root = &root
+	return root, nil
+}
@@ -1,19 +1,25 @@
 package solid
 
 import (
-	"bytes"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"strconv"
 
-	"github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/common/length"
 	"github.com/ledgerwatch/erigon-lib/types/ssz"
 	"github.com/ledgerwatch/erigon/cl/merkle_tree"
 	"github.com/ledgerwatch/erigon/cl/utils"
 )
 
-const treeCacheDepthUint64Slice = 0
+// ErrIndexOutOfRange is returned by the checked accessors (GetErr/SetErr) of the uint64 containers, and wrapped in
+// the panic value of Get/Set. Indices are checked against the length, not the (larger) backing buffer.
+var ErrIndexOutOfRange = errors.New("solid: index out of range")
+
+// ErrUnalignedWrite is returned by SetAligned when the written range doesn't cover whole 32-byte chunks.
+var ErrUnalignedWrite = errors.New("solid: write is not chunk-aligned")
 
 func convertDepthToChunkSize(d int) int {
 	return (1 << d) // just power of 2
@@ -29,9 +35,10 @@ func getTreeCacheSize(listLen int, cacheDepth int) int {
 // The underlying storage for the slice is a byte array. This approach allows for efficient
 // memory usage, especially when dealing with large slices.
 type byteBasedUint64Slice struct {
-	// The bytes that back the slice
-	u               []byte
-	treeCacheBuffer []byte
+	// The bytes that back the slice, padded to whole 32-byte chunks. Each chunk is a leaf of the merkle tree.
+	u []byte
+	// memoized merkle tree over the chunks of u
+	layers merkleLayers
 
 	// Length of the slice
 	l int
@@ -39,7 +46,15 @@ type byteBasedUint64Slice struct {
 	// Capacity of the slice
 	c int
 
-	hashBuf
+	// shared is set when u is also referenced by a copy-on-write clone, it is copied before the first write
+	shared bool
+	// pooled is set once u was taken from the buffer pool, see Release
+	pooled bool
+	// checked is set by DecodeSSZ: Set then refuses indices past the length instead of writing into the spare buffer,
+	// as the indices applied to decoded containers may come from network data
+	checked bool
+	// root last computed over the memoized tree, as a list or as a vector
+	root [32]byte
 }
 
 // NewUint64Slice creates a new instance of byteBasedUint64Slice with a specified capacity limit.
@@ -53,11 +68,59 @@ func NewUint64Slice(limit int) *byteBasedUint64Slice {
 // Clear clears the slice by setting its length to 0 and zeroing out its backing array.
 func (arr *byteBasedUint64Slice) Clear() {
 	arr.l = 0
-	for i := range arr.u {
-		arr.u[i] = 0
+	if arr.shared {
+		arr.u, arr.shared = make([]byte, len(arr.u)), false
+	} else {
+		for i := range arr.u {
+			arr.u[i] = 0
+		}
+	}
+	arr.layers.reset()
+}
+
+// cloneShared returns a copy of the slice sharing its backing buffer and memoized tree until either of them is
+// written to.
+func (arr *byteBasedUint64Slice) cloneShared() *byteBasedUint64Slice {
+	arr.shared = true
+	return &byteBasedUint64Slice{
+		u:       arr.u[:len(arr.u):len(arr.u)],
+		layers:  arr.layers.share(),
+		l:       arr.l,
+		c:       arr.c,
+		shared:  true,
+		checked: arr.checked,
+		root:    arr.root,
 	}
-	for i := range arr.treeCacheBuffer {
-		arr.treeCacheBuffer[i] = 0
+}
+
+// allocate replaces the backing buffer with a zeroed one of n bytes, taken from the buffer pool if large enough.
+func (arr *byteBasedUint64Slice) allocate(n int) {
+	b, pooled := getBuffer(n)
+	arr.u, arr.shared = b, false
+	if pooled && !arr.pooled {
+		arr.pooled = true
+		trackBuffer(arr)
+	}
+}
+
+// Release empties the slice and returns its backing buffer to the pool, unless a copy-on-write clone still shares it.
+// Neither the slice nor the bytes returned by Bytes must be used afterwards.
+func (arr *byteBasedUint64Slice) Release() {
+	if !arr.shared {
+		putBuffer(arr.u)
+	}
+	arr.u, arr.shared, arr.l = nil, false, 0
+	arr.layers = merkleLayers{}
+	if arr.pooled {
+		arr.pooled = false
+		untrackBuffer(arr)
+	}
+}
+
+// own copies the backing buffer if it is shared, so that it can be written.
+func (arr *byteBasedUint64Slice) own() {
+	if arr.shared {
+		arr.u, arr.shared = append([]byte(nil), arr.u...), false
 	}
 }
 
@@ -67,16 +130,14 @@ func (arr *byteBasedUint64Slice) CopyTo(target *byteBasedUint64Slice) {
 
 	target.c = arr.c
 	target.l = arr.l
+	target.checked = arr.checked
 	if len(target.u) < len(arr.u) {
-		target.u = make([]byte, len(arr.u))
+		target.allocate(len(arr.u))
 	}
-	if len(target.treeCacheBuffer) < len(arr.treeCacheBuffer) {
-		target.treeCacheBuffer = make([]byte, len(arr.treeCacheBuffer))
-	}
-	target.treeCacheBuffer = target.treeCacheBuffer[:len(arr.treeCacheBuffer)]
 	target.u = target.u[:len(arr.u)]
 	copy(target.u, arr.u)
-	copy(target.treeCacheBuffer, arr.treeCacheBuffer)
+	arr.layers.copyTo(&target.layers)
+	target.root = arr.root
 }
 
 func (arr *byteBasedUint64Slice) MarshalJSON() ([]byte, error) {
@@ -111,54 +172,169 @@ func (arr *byteBasedUint64Slice) Range(fn func(index int, value uint64, length i
 	}
 }
 
+// RangeFrom is Range starting at index start, a negative start being the beginning of the slice.
+func (arr *byteBasedUint64Slice) RangeFrom(start int, fn func(index int, value uint64, length int) bool) {
+	for i := max(start, 0); i < arr.l; i++ {
+		if !fn(i, binary.LittleEndian.Uint64(arr.u[i*8:]), arr.l) {
+			break
+		}
+	}
+}
+
+// RangeReverse is Range from the last element to the first one.
+func (arr *byteBasedUint64Slice) RangeReverse(fn func(index int, value uint64, length int) bool) {
+	for i := arr.l - 1; i >= 0; i-- {
+		if !fn(i, binary.LittleEndian.Uint64(arr.u[i*8:]), arr.l) {
+			break
+		}
+	}
+}
+
 // Pop removes and returns the last element of the slice.
 func (arr *byteBasedUint64Slice) Pop() uint64 {
+	arr.own()
 	offset := (arr.l - 1) * 8
 	val := binary.LittleEndian.Uint64(arr.u[offset : offset+8])
 	binary.LittleEndian.PutUint64(arr.u[offset:offset+8], 0)
 	arr.l = arr.l - 1
-	arr.treeCacheBuffer = arr.treeCacheBuffer[:getTreeCacheSize((arr.l+3)/4, treeCacheDepthUint64Slice)*length.Hash]
+	arr.layers.markDirty(arr.l / 4)
 	return val
 }
 
 // Append adds a new element to the end of the slice.
 func (arr *byteBasedUint64Slice) Append(v uint64) {
+	arr.own()
 	if len(arr.u) <= arr.l*8 {
 		arr.u = append(arr.u, make([]byte, 32)...)
 	}
 
 	offset := arr.l * 8
 	binary.LittleEndian.PutUint64(arr.u[offset:offset+8], v)
+	arr.layers.markDirty(arr.l / 4)
 	arr.l = arr.l + 1
-	treeBufferExpectCache := getTreeCacheSize((arr.l+3)/4, treeCacheDepthUint64Slice) * length.Hash
-	if len(arr.treeCacheBuffer) < treeBufferExpectCache {
-		arr.treeCacheBuffer = append(arr.treeCacheBuffer, make([]byte, treeBufferExpectCache-len(arr.treeCacheBuffer))...)
+}
+
+// AppendMultiple adds vals to the end of the slice, growing the backing buffer at most once.
+func (arr *byteBasedUint64Slice) AppendMultiple(vals ...uint64) {
+	if len(vals) == 0 {
+		return
+	}
+	arr.own()
+	end := arr.l + len(vals)
+	if size := length.Hash * ((end + 3) / 4); len(arr.u) < size {
+		arr.u = append(arr.u, make([]byte, size-len(arr.u))...)
+	}
+	for chunk := arr.l / 4; chunk < (end+3)/4; chunk++ {
+		arr.layers.markDirty(chunk)
 	}
-	ihIdx := (((arr.l - 1) / 4) / convertDepthToChunkSize(treeCacheDepthUint64Slice)) * length.Hash
-	for i := ihIdx; i < ihIdx+length.Hash; i++ {
-		arr.treeCacheBuffer[i] = 0
+	for i, v := range vals {
+		binary.LittleEndian.PutUint64(arr.u[(arr.l+i)*8:], v)
 	}
+	arr.l = end
+}
+
+func (arr *byteBasedUint64Slice) checkIndex(index int) error {
+	if index < 0 || index >= arr.l {
+		return fmt.Errorf("%w: index %d, length %d", ErrIndexOutOfRange, index, arr.l)
+	}
+	return nil
 }
 
 // Get returns the element at the given index.
 func (arr *byteBasedUint64Slice) Get(index int) uint64 {
-	if index >= arr.l {
-		panic("index out of range")
+	v, err := arr.GetErr(index)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// GetErr returns the element at the given index, or ErrIndexOutOfRange.
+func (arr *byteBasedUint64Slice) GetErr(index int) (uint64, error) {
+	if err := arr.checkIndex(index); err != nil {
+		return 0, err
 	}
 	offset := index * 8
-	return binary.LittleEndian.Uint64(arr.u[offset : offset+8])
+	return binary.LittleEndian.Uint64(arr.u[offset : offset+8]), nil
 }
 
-// Set replaces the element at the given index with a new value.
+// Set replaces the element at the given index with a new value. Unless the slice was decoded, an index past the length
+// but within the backing buffer is written without extending the slice; any other out of range index panics.
 func (arr *byteBasedUint64Slice) Set(index int, v uint64) {
-	offset := index * 8
-	ihIdx := ((index / 4) / convertDepthToChunkSize(treeCacheDepthUint64Slice)) * length.Hash
-	for i := ihIdx; i < ihIdx+length.Hash; i++ {
-		arr.treeCacheBuffer[i] = 0
+	if arr.checked {
+		if err := arr.SetErr(index, v); err != nil {
+			panic(err)
+		}
+		return
+	}
+	if index < 0 || index >= len(arr.u)/8 {
+		panic(fmt.Errorf("%w: index %d, buffer of %d elements", ErrIndexOutOfRange, index, len(arr.u)/8))
 	}
+	arr.set(index, v)
+}
+
+// SetErr replaces the element at the given index with a new value, or returns ErrIndexOutOfRange.
+func (arr *byteBasedUint64Slice) SetErr(index int, v uint64) error {
+	if err := arr.checkIndex(index); err != nil {
+		return err
+	}
+	arr.set(index, v)
+	return nil
+}
+
+func (arr *byteBasedUint64Slice) set(index int, v uint64) {
+	arr.own()
+	offset := index * 8
+	arr.layers.markDirty(index / 4)
 	binary.LittleEndian.PutUint64(arr.u[offset:offset+8], v)
 }
 
+// SetRange replaces the elements from start on with vals, marking each touched chunk dirty once.
+// It panics with ErrIndexOutOfRange if vals doesn't fit in the slice.
+func (arr *byteBasedUint64Slice) SetRange(start int, vals []uint64) {
+	if err := arr.setRange(start, vals); err != nil {
+		panic(err)
+	}
+}
+
+// SetAligned replaces the elements from start on with vals, which must cover whole 32-byte chunks (4 elements each):
+// start must be chunk-aligned, and so must be the end of vals unless it is the end of the slice.
+func (arr *byteBasedUint64Slice) SetAligned(start int, vals []uint64) error {
+	end := start + len(vals)
+	if err := arr.checkRange(start, end); err != nil {
+		return err
+	}
+	if start%4 != 0 || (end%4 != 0 && end != arr.l) {
+		return fmt.Errorf("%w: range [%d, %d), length %d", ErrUnalignedWrite, start, end, arr.l)
+	}
+	return arr.setRange(start, vals)
+}
+
+func (arr *byteBasedUint64Slice) checkRange(start, end int) error {
+	if start < 0 || end > arr.l {
+		return fmt.Errorf("%w: range [%d, %d), length %d", ErrIndexOutOfRange, start, end, arr.l)
+	}
+	return nil
+}
+
+func (arr *byteBasedUint64Slice) setRange(start int, vals []uint64) error {
+	end := start + len(vals)
+	if err := arr.checkRange(start, end); err != nil {
+		return err
+	}
+	if len(vals) == 0 {
+		return nil
+	}
+	arr.own()
+	for chunk := start / 4; chunk < (end+3)/4; chunk++ {
+		arr.layers.markDirty(chunk)
+	}
+	for i, v := range vals {
+		binary.LittleEndian.PutUint64(arr.u[(start+i)*8:], v)
+	}
+	return nil
+}
+
 // Length returns the current length (number of elements) of the slice.
 func (arr *byteBasedUint64Slice) Length() int {
 	return arr.l
@@ -171,66 +347,217 @@ func (arr *byteBasedUint64Slice) Cap() int {
 
 // HashListSSZ computes the SSZ hash of the slice as a list. It returns the hash and any error encountered.
 func (arr *byteBasedUint64Slice) HashListSSZ() ([32]byte, error) {
+	root, _, err := arr.hashListSSZWithBranches(nil)
+	return root, err
+}
+
+// hashListSSZWithBranches is hashListSSZ which also collects the branches of the elements at indices,
+// each one ending with the length mix-in.
+func (arr *byteBasedUint64Slice) hashListSSZWithBranches(indices []int) ([32]byte, [][][32]byte, error) {
 	depth := GetDepth((uint64(arr.c)*8 + 31) / 32)
 	baseRoot := [32]byte{}
+	var branches [][][32]byte
 	var err error
 	if arr.l == 0 {
+		if len(indices) > 0 {
+			return [32]byte{}, nil, fmt.Errorf("index %d out of range, list is empty", indices[0])
+		}
 		copy(baseRoot[:], merkle_tree.ZeroHashes[depth][:])
 	} else {
-		baseRoot, err = arr.HashVectorSSZ()
+		baseRoot, branches, err = arr.hashVectorSSZWithBranches(indices)
 		if err != nil {
-			return [32]byte{}, err
+			return [32]byte{}, nil, err
 		}
 	}
 	lengthRoot := merkle_tree.Uint64Root(uint64(arr.l))
-	return utils.Sha256(baseRoot[:], lengthRoot[:]), nil
+	for i := range branches {
+		branches[i] = append(branches[i], lengthRoot)
+	}
+	arr.root = utils.Sha256(baseRoot[:], lengthRoot[:])
+	return arr.root, branches, nil
 }
 
 // HashVectorSSZ computes the SSZ hash of the slice as a vector. It returns the hash and any error encountered.
+// Only the branches of the chunks changed since the previous call are rehashed.
 func (arr *byteBasedUint64Slice) HashVectorSSZ() ([32]byte, error) {
-	chunkSize := convertDepthToChunkSize(treeCacheDepthUint64Slice) * length.Hash
+	root, _, err := arr.hashVectorSSZWithBranches(nil)
+	return root, err
+}
+
+// hashVectorSSZWithBranches computes the vector root and the branch of the 32-byte chunk holding each element at
+// indices (4 uint64s share a chunk). Branches are ordered from the leaf up.
+func (arr *byteBasedUint64Slice) hashVectorSSZWithBranches(indices []int) ([32]byte, [][][32]byte, error) {
+	for _, idx := range indices {
+		if idx < 0 || idx >= arr.l {
+			return [32]byte{}, nil, fmt.Errorf("index %d out of range, length %d", idx, arr.l)
+		}
+	}
 	depth := GetDepth((uint64(arr.c)*8 + length.Hash - 1) / length.Hash)
-	emptyHashBytes := make([]byte, length.Hash)
+	leaves := arr.u[:length.Hash*((arr.l+3)/4)]
+	root, err := arr.layers.update(leaves, depth)
+	if err != nil {
+		return [32]byte{}, nil, err
+	}
+	arr.root = root
+	var branches [][][32]byte
+	if len(indices) > 0 {
+		branches = make([][][32]byte, len(indices))
+		for i, idx := range indices {
+			branches[i] = make([][32]byte, 0, depth+1) // +1 - room for the length mix-in of lists
+			for h := 0; h < int(depth); h++ {
+				branches[i] = append(branches[i], arr.layers.node(leaves, h, ((idx/4)>>h)^1))
+			}
+		}
+	}
+	return root, branches, nil
+}
 
-	layerBuffer := make([]byte, chunkSize)
-	maxTo := length.Hash*((arr.l-1)/4) + length.Hash
+// MarkDirty records that the element at index was modified without going through the setters, e.g. through Bytes,
+// so that the next hash recomputes its branch. It panics with ErrIndexOutOfRange.
+// The bytes of a copy-on-write clone must not be written to, as they are shared.
+func (arr *byteBasedUint64Slice) MarkDirty(index int) {
+	if err := arr.checkIndex(index); err != nil {
+		panic(err)
+	}
+	arr.layers.markDirty(index / 4)
+}
 
-	offset := 0
-	for i := 0; i < maxTo; i += chunkSize {
-		offset = (i / chunkSize) * length.Hash
-		from := i
-		to := int(utils.Min64(uint64(from+chunkSize), uint64(maxTo)))
+// hashDelta marks the elements at changed dirty and rehashes with hash. If prevRoot isn't the root last computed
+// over the memoized tree, the tree doesn't match the caller's view of the slice and is recomputed from scratch.
+func (arr *byteBasedUint64Slice) hashDelta(prevRoot [32]byte, changed []int, hash func() ([32]byte, error)) ([32]byte, error) {
+	for _, idx := range changed {
+		if err := arr.checkIndex(idx); err != nil {
+			return [32]byte{}, err
+		}
+	}
+	if prevRoot != arr.root {
+		arr.layers.reset()
+	}
+	for _, idx := range changed {
+		arr.layers.markDirty(idx / 4)
+	}
+	return hash()
+}
 
-		if !bytes.Equal(arr.treeCacheBuffer[offset:offset+length.Hash], emptyHashBytes) {
-			continue
+// saturatingAdd returns v+delta, clamped to [0, math.MaxUint64].
+func saturatingAdd(v uint64, delta int64) uint64 {
+	if delta >= 0 {
+		if sum := v + uint64(delta); sum >= v {
+			return sum
 		}
-		layerBuffer = layerBuffer[:to-from]
-		copy(layerBuffer, arr.u[from:to])
-		if err := computeFlatRootsToBuffer(uint8(utils.Min64(treeCacheDepthUint64Slice, uint64(depth))), layerBuffer, arr.treeCacheBuffer[offset:]); err != nil {
-			return [32]byte{}, err
+		return math.MaxUint64
+	}
+	if sub := uint64(-(delta + 1)) + 1; sub <= v { // -(delta+1) doesn't overflow for math.MinInt64
+		return v - sub
+	}
+	return 0
+}
+
+// AddAt adds delta to the element at index, saturating at 0 and math.MaxUint64. It panics with ErrIndexOutOfRange.
+func (arr *byteBasedUint64Slice) AddAt(index int, delta int64) {
+	if err := arr.checkIndex(index); err != nil {
+		panic(err)
+	}
+	if delta == 0 {
+		return
+	}
+	arr.own()
+	arr.layers.markDirty(index / 4)
+	binary.LittleEndian.PutUint64(arr.u[index*8:], saturatingAdd(binary.LittleEndian.Uint64(arr.u[index*8:]), delta))
+}
+
+// ApplyDelta adds deltas[i] to the i-th element, saturating like AddAt, in one pass over the slice: only the chunks
+// with non-zero deltas are marked dirty. It panics with ErrIndexOutOfRange if there are more deltas than elements.
+func (arr *byteBasedUint64Slice) ApplyDelta(deltas []int64) {
+	if err := arr.checkRange(0, len(deltas)); err != nil {
+		panic(err)
+	}
+	arr.own()
+	for i, delta := range deltas {
+		if delta == 0 {
+			continue
 		}
+		arr.layers.markDirty(i / 4)
+		offset := i * 8
+		binary.LittleEndian.PutUint64(arr.u[offset:], saturatingAdd(binary.LittleEndian.Uint64(arr.u[offset:]), delta))
 	}
-	if treeCacheDepthUint64Slice >= depth {
-		return common.BytesToHash(arr.treeCacheBuffer[:32]), nil
+}
+
+// IsSorted returns whether the elements are in non-decreasing order.
+func (arr *byteBasedUint64Slice) IsSorted() bool {
+	for i := 1; i < arr.l; i++ {
+		if binary.LittleEndian.Uint64(arr.u[(i-1)*8:]) > binary.LittleEndian.Uint64(arr.u[i*8:]) {
+			return false
+		}
 	}
+	return true
+}
 
-	arr.makeBuf(offset + length.Hash)
-	copy(arr.buf, arr.treeCacheBuffer[:offset+length.Hash])
-	elements := arr.buf
-	for i := uint8(treeCacheDepthUint64Slice); i < depth; i++ {
-		layerLen := len(elements)
-		if layerLen%64 == 32 {
-			elements = append(elements, merkle_tree.ZeroHashes[i][:]...)
+// BinarySearch searches v in the sorted slice, returning the index of its first occurrence, or the index it would be
+// inserted at, and whether it was found.
+func (arr *byteBasedUint64Slice) BinarySearch(v uint64) (int, bool) {
+	lo, hi := 0, arr.l
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if binary.LittleEndian.Uint64(arr.u[mid*8:]) < v {
+			lo = mid + 1
+		} else {
+			hi = mid
 		}
-		outputLen := len(elements) / 2
-		arr.makeBuf(outputLen)
-		if err := merkle_tree.HashByteSlice(arr.buf, elements); err != nil {
-			return [32]byte{}, err
+	}
+	return lo, lo < arr.l && binary.LittleEndian.Uint64(arr.u[lo*8:]) == v
+}
+
+// InsertSorted inserts v into the sorted slice before its equal elements, shifting the following ones.
+func (arr *byteBasedUint64Slice) InsertSorted(v uint64) {
+	idx, _ := arr.BinarySearch(v)
+	arr.Insert(idx, v)
+}
+
+// Insert inserts v at index, shifting the following elements. It panics with ErrIndexOutOfRange unless
+// 0 <= index <= length.
+func (arr *byteBasedUint64Slice) Insert(index int, v uint64) {
+	if index != arr.l {
+		if err := arr.checkIndex(index); err != nil {
+			panic(err)
 		}
-		elements = arr.buf
 	}
+	arr.Append(0) // grows the buffer and marks the last chunk dirty
+	copy(arr.u[(index+1)*8:arr.l*8], arr.u[index*8:(arr.l-1)*8])
+	binary.LittleEndian.PutUint64(arr.u[index*8:], v)
+	for chunk := index / 4; chunk < (arr.l-1)/4; chunk++ {
+		arr.layers.markDirty(chunk)
+	}
+}
 
-	return common.BytesToHash(elements[:32]), nil
+// RemoveAt removes the element at index, shifting the following ones. It panics with ErrIndexOutOfRange.
+func (arr *byteBasedUint64Slice) RemoveAt(index int) {
+	if err := arr.checkIndex(index); err != nil {
+		panic(err)
+	}
+	arr.own()
+	copy(arr.u[index*8:], arr.u[(index+1)*8:arr.l*8])
+	arr.l--
+	binary.LittleEndian.PutUint64(arr.u[arr.l*8:], 0)
+	for chunk := index / 4; chunk <= arr.l/4; chunk++ {
+		arr.layers.markDirty(chunk)
+	}
+}
+
+// Truncate removes the elements from n on. It panics with ErrIndexOutOfRange unless 0 <= n <= length.
+func (arr *byteBasedUint64Slice) Truncate(n int) {
+	if n < 0 || n > arr.l {
+		panic(fmt.Errorf("%w: truncate to %d, length %d", ErrIndexOutOfRange, n, arr.l))
+	}
+	if n == arr.l {
+		return
+	}
+	arr.own()
+	clear(arr.u[n*8 : arr.l*8])
+	for chunk := n / 4; chunk <= (arr.l-1)/4; chunk++ {
+		arr.layers.markDirty(chunk)
+	}
+	arr.l = n
 }
 
 // EncodeSSZ encodes the slice in SSZ format. It appends the encoded data to the provided buffer and returns the result.
@@ -245,9 +572,10 @@ func (arr *byteBasedUint64Slice) DecodeSSZ(buf []byte, _ int) error {
 	}
 	arr.l = len(buf) / 8
 	bufferLength := length.Hash*((arr.l-1)/4) + length.Hash
-	arr.u = make([]byte, bufferLength)
+	arr.allocate(bufferLength)
 	copy(arr.u, buf)
-	arr.treeCacheBuffer = make([]byte, getTreeCacheSize((arr.l+3)/4, treeCacheDepthUint64Slice)*length.Hash)
+	arr.layers.reset()
+	arr.checked = true
 	return nil
 }
 
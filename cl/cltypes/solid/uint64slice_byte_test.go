@@ -1,10 +1,18 @@
 package solid_test
 
 import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"slices"
+	"sync"
 	"testing"
 
 	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/metrics"
 	"github.com/ledgerwatch/erigon/cl/cltypes/solid"
+	"github.com/ledgerwatch/erigon/cl/utils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -43,3 +51,920 @@ func TestUint64SliceCopyTo(t *testing.T) {
 
 	require.Equal(t, firstHash, secondHash)
 }
+
+func TestUint64SliceHashWithBranches(t *testing.T) {
+	type withBranches interface {
+		HashSSZ() ([32]byte, error)
+		HashSSZWithBranches(indices ...int) ([32]byte, [][][32]byte, error)
+	}
+	list := solid.NewUint64ListSSZ(1 << 10)
+	vector := solid.NewUint64VectorSSZ(64)
+	for i := 0; i < 45; i++ {
+		list.Append(uint64(i * 7))
+		vector.Set(i, uint64(i*i))
+	}
+	indices := []int{0, 3, 4, 21, 44}
+	for _, tc := range []struct {
+		obj    withBranches
+		depth  uint64
+		values solid.IterableSSZ[uint64]
+	}{
+		{list.(withBranches), uint64(solid.GetDepth((1<<10)*8/32)) + 1, list},
+		{vector.(withBranches), uint64(solid.GetDepth(64 * 8 / 32)), vector},
+	} {
+		root, branches, err := tc.obj.HashSSZWithBranches(indices...)
+		require.NoError(t, err)
+		expected, err := tc.obj.HashSSZ()
+		require.NoError(t, err)
+		require.Equal(t, expected, root)
+		require.Len(t, branches, len(indices))
+		for i, idx := range indices {
+			var leaf common.Hash
+			for j := 0; j < 4 && (idx/4)*4+j < tc.values.Length(); j++ {
+				binary.LittleEndian.PutUint64(leaf[j*8:], tc.values.Get((idx/4)*4+j))
+			}
+			branch := make([]common.Hash, len(branches[i]))
+			for j := range branch {
+				branch[j] = branches[i][j]
+			}
+			require.Len(t, branch, int(tc.depth))
+			require.True(t, utils.IsValidMerkleBranch(leaf, branch, tc.depth, uint64(idx/4), root), "index %d", idx)
+		}
+	}
+
+	_, _, err := list.(withBranches).HashSSZWithBranches(45)
+	require.Error(t, err)
+}
+
+func TestUint64SliceProveIndex(t *testing.T) {
+	list := solid.NewUint64ListSSZ(1 << 10)
+	for i := 0; i < 45; i++ {
+		list.Append(uint64(i * 7))
+	}
+	root, err := list.HashSSZ()
+	require.NoError(t, err)
+	depth := uint64(solid.GetDepth((1<<10)*8/32)) + 1
+
+	proofs, err := list.ProveIndices(2, 30, 44)
+	require.NoError(t, err)
+	for i, idx := range []int{2, 30, 44} {
+		proof, err := list.ProveIndex(idx)
+		require.NoError(t, err)
+		require.Equal(t, proofs[i], proof)
+
+		var leaf common.Hash
+		for j := 0; j < 4 && (idx/4)*4+j < list.Length(); j++ {
+			binary.LittleEndian.PutUint64(leaf[j*8:], list.Get((idx/4)*4+j))
+		}
+		branch := make([]common.Hash, len(proof))
+		for j := range proof {
+			branch[j] = proof[j]
+		}
+		require.True(t, utils.IsValidMerkleBranch(leaf, branch, depth, uint64(idx/4), root), "index %d", idx)
+	}
+
+	_, err = list.ProveIndex(45)
+	require.Error(t, err)
+}
+
+func TestUint64SliceCheckedAccessors(t *testing.T) {
+	list := solid.NewUint64ListSSZ(16)
+	require.NoError(t, list.DecodeSSZ([]byte{1, 0, 0, 0, 0, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0}, 0))
+
+	v, err := list.GetErr(1)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, v)
+	require.NoError(t, list.SetErr(1, 3))
+	require.EqualValues(t, 3, list.Get(1))
+
+	// the backing buffer has room for 4 elements, but only 2 are in the list
+	_, err = list.GetErr(2)
+	require.ErrorIs(t, err, solid.ErrIndexOutOfRange)
+	require.ErrorIs(t, list.SetErr(2, 1), solid.ErrIndexOutOfRange)
+	require.ErrorIs(t, list.SetErr(-1, 1), solid.ErrIndexOutOfRange)
+	require.Panics(t, func() { list.Set(3, 1) })
+
+	raw := solid.NewRawUint64List(16, []uint64{1})
+	_, err = raw.GetErr(1)
+	require.ErrorIs(t, err, solid.ErrIndexOutOfRange)
+	root, err := raw.HashSSZ()
+	require.NoError(t, err)
+	require.NoError(t, raw.SetErr(0, 2))
+	newRoot, err := raw.HashSSZ()
+	require.NoError(t, err)
+	require.NotEqual(t, root, newRoot)
+}
+
+func TestUint64SliceCheckedMode(t *testing.T) {
+	// built in memory: Set may write the spare backing buffer, as it always did
+	list := solid.NewUint64ListSSZ(16)
+	list.Append(1)
+	list.Append(2)
+	require.NotPanics(t, func() { list.Set(3, 7) })
+	require.Equal(t, 2, list.Length())
+	require.ErrorIs(t, list.SetErr(3, 7), solid.ErrIndexOutOfRange)
+	require.Panics(t, func() { list.Set(4, 7) }) // past the buffer of 4 elements
+	require.Panics(t, func() { list.Set(-1, 7) })
+
+	// decoded, and the copies of decoded ones: Set is checked against the length
+	decoded := solid.NewUint64ListSSZ(16)
+	require.NoError(t, decoded.DecodeSSZ([]byte{1, 0, 0, 0, 0, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0}, 0))
+	copied := solid.NewUint64ListSSZ(16)
+	decoded.CopyTo(copied)
+	for _, l := range []solid.Uint64ListSSZ{decoded, decoded.CloneShared().(solid.Uint64ListSSZ), copied} {
+		require.NotPanics(t, func() { l.Set(1, 3) })
+		require.Panics(t, func() { l.Set(2, 7) })
+	}
+}
+
+func TestUint64SliceRangeFrom(t *testing.T) {
+	list := solid.NewUint64ListSSZ(16)
+	for i := 0; i < 10; i++ {
+		list.Append(uint64(i * 10))
+	}
+	collect := func(rangeFn func(fn func(int, uint64, int) bool), limit int) (indices []int) {
+		rangeFn(func(idx int, v uint64, length int) bool {
+			require.Equal(t, uint64(idx*10), v)
+			require.Equal(t, 10, length)
+			indices = append(indices, idx)
+			return len(indices) < limit
+		})
+		return indices
+	}
+	from := func(start int) func(fn func(int, uint64, int) bool) {
+		return func(fn func(int, uint64, int) bool) { list.RangeFrom(start, fn) }
+	}
+	assert.Equal(t, []int{7, 8, 9}, collect(from(7), 10))
+	assert.Equal(t, []int{2, 3}, collect(from(2), 2))
+	assert.Equal(t, []int{0, 1}, collect(from(-1), 2))
+	assert.Empty(t, collect(from(10), 10))
+	assert.Equal(t, []int{9, 8, 7}, collect(list.RangeReverse, 3))
+	assert.Len(t, collect(list.RangeReverse, 100), 10)
+}
+
+func TestUint64SliceSorted(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	list := solid.NewUint64ListSSZ(1 << 10)
+	require.True(t, list.IsSorted())
+	_, found := list.BinarySearch(1)
+	require.False(t, found)
+
+	var expected []uint64
+	for i := 0; i < 300; i++ {
+		v := uint64(rnd.Intn(200))
+		list.InsertSorted(v)
+		idx, _ := slices.BinarySearch(expected, v)
+		expected = slices.Insert(expected, idx, v)
+		if i%7 == 0 {
+			root, err := list.HashSSZ()
+			require.NoError(t, err)
+			reference, err := solid.ReferenceHashSSZ(list)
+			require.NoError(t, err)
+			require.Equal(t, reference, root)
+		}
+	}
+	require.True(t, list.IsSorted())
+	require.Equal(t, len(expected), list.Length())
+	for i, v := range expected {
+		require.Equal(t, v, list.Get(i))
+	}
+	for v := uint64(0); v <= 200; v++ {
+		idx, found := list.BinarySearch(v)
+		expectedIdx, expectedFound := slices.BinarySearch(expected, v)
+		require.Equal(t, expectedIdx, idx)
+		require.Equal(t, expectedFound, found)
+	}
+
+	list.Set(0, 1000)
+	require.False(t, list.IsSorted())
+}
+
+func TestUint64SliceSetAligned(t *testing.T) {
+	aligned, perElement := solid.NewUint64ListSSZ(16), solid.NewUint64ListSSZ(16)
+	for i := 0; i < 10; i++ {
+		aligned.Append(uint64(100 + i))
+		perElement.Append(uint64(100 + i))
+	}
+	// hash once, so that the tree cache is populated and must be invalidated
+	_, err := aligned.HashSSZ()
+	require.NoError(t, err)
+
+	vals := []uint64{1, 2, 3, 4, 5, 6}
+	require.NoError(t, aligned.SetAligned(4, vals))
+	for i, v := range vals {
+		perElement.Set(4+i, v)
+	}
+	// the last chunk may be partial
+	require.NoError(t, aligned.SetAligned(8, []uint64{7, 8}))
+	perElement.Set(8, 7)
+	perElement.Set(9, 8)
+
+	for i := 0; i < 10; i++ {
+		require.Equal(t, perElement.Get(i), aligned.Get(i))
+	}
+	expected, err := perElement.HashSSZ()
+	require.NoError(t, err)
+	root, err := aligned.HashSSZ()
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+
+	require.ErrorIs(t, aligned.SetAligned(1, []uint64{1, 2, 3, 4}), solid.ErrUnalignedWrite)
+	require.ErrorIs(t, aligned.SetAligned(0, []uint64{1, 2}), solid.ErrUnalignedWrite)
+	require.ErrorIs(t, aligned.SetAligned(8, []uint64{1, 2, 3, 4}), solid.ErrIndexOutOfRange)
+	require.NoError(t, aligned.SetAligned(4, nil))
+}
+
+func TestUint64SliceBulkMutations(t *testing.T) {
+	bulk, perElement := solid.NewUint64ListSSZ(64), solid.NewUint64ListSSZ(64)
+	bulk.Append(1)
+	perElement.Append(1)
+	_, err := bulk.HashSSZ()
+	require.NoError(t, err)
+
+	vals := []uint64{2, 3, 4, 5, 6, 7, 8, 9, 10}
+	bulk.AppendMultiple(vals...)
+	for _, v := range vals {
+		perElement.Append(v)
+	}
+	bulk.SetRange(3, []uint64{30, 40, 50})
+	perElement.Set(3, 30)
+	perElement.Set(4, 40)
+	perElement.Set(5, 50)
+	bulk.AppendMultiple()
+
+	require.Equal(t, perElement.Length(), bulk.Length())
+	for i := 0; i < bulk.Length(); i++ {
+		require.Equal(t, perElement.Get(i), bulk.Get(i))
+	}
+	expected, err := perElement.HashSSZ()
+	require.NoError(t, err)
+	root, err := bulk.HashSSZ()
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+
+	require.Panics(t, func() { bulk.SetRange(8, []uint64{1, 2, 3}) })
+}
+
+func TestUint64SliceMemoizedHash(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	list, copied := solid.NewUint64ListSSZ(256), solid.NewUint64ListSSZ(256)
+	vector := solid.NewUint64VectorSSZ(64)
+	check := func(obj solid.IterableSSZ[uint64]) {
+		root, err := obj.HashSSZ()
+		require.NoError(t, err)
+		expected, err := solid.ReferenceHashSSZ(obj)
+		require.NoError(t, err)
+		require.Equal(t, expected, root)
+	}
+	for i := 0; i < 2_000; i++ {
+		switch op := rnd.Intn(10); {
+		case op < 4 && list.Length() < list.Cap():
+			list.Append(rnd.Uint64())
+		case op < 6 && list.Length() > 0:
+			list.Pop()
+		case op < 8 && list.Length() > 0:
+			list.Set(rnd.Intn(list.Length()), rnd.Uint64())
+		case op == 8 && list.Length() >= 4:
+			start := rnd.Intn(list.Length()/4) * 4
+			require.NoError(t, list.SetAligned(start, []uint64{rnd.Uint64(), rnd.Uint64(), rnd.Uint64(), rnd.Uint64()}))
+		case op == 9 && rnd.Intn(20) == 0:
+			list.Clear()
+		}
+		vector.Set(rnd.Intn(vector.Length()), rnd.Uint64())
+		if rnd.Intn(3) == 0 {
+			check(list)
+			check(vector)
+		}
+		if rnd.Intn(50) == 0 {
+			list.CopyTo(copied)
+			check(copied)
+			copied.Append(1)
+			check(copied)
+		}
+	}
+	check(list)
+}
+
+func TestUint64SliceHashTelemetry(t *testing.T) {
+	calls := func(path string) uint64 {
+		return metrics.GetOrCreateCounter(`caplin_solid_hash_calls{path="` + path + `"}`).GetValueUint64()
+	}
+	leaves := func(path string) uint64 {
+		return metrics.GetOrCreateCounter(`caplin_solid_hash_leaves{path="` + path + `"}`).GetValueUint64()
+	}
+	list := solid.NewUint64ListSSZ(1 << 10)
+	for i := 0; i < 400; i++ {
+		list.Append(uint64(i))
+	}
+	rebuilds, leavesRebuilt := calls("rebuild"), leaves("rebuild")
+	_, err := list.HashSSZ()
+	require.NoError(t, err)
+	require.Equal(t, rebuilds+1, calls("rebuild"))
+	require.Equal(t, leavesRebuilt+100, leaves("rebuild"))
+
+	cached := calls("cached")
+	_, err = list.HashSSZ()
+	require.NoError(t, err)
+	require.Equal(t, cached+1, calls("cached"))
+
+	incrementals, leavesRehashed := calls("incremental"), leaves("incremental")
+	list.Set(0, 1)
+	list.Set(5, 1)
+	_, err = list.HashSSZ()
+	require.NoError(t, err)
+	require.Equal(t, incrementals+1, calls("incremental"))
+	require.Equal(t, leavesRehashed+2, leaves("incremental"))
+}
+
+func TestUint64SliceParallelHash(t *testing.T) {
+	defer solid.SetHashWorkers(solid.HashWorkers())
+	rnd := rand.New(rand.NewSource(1))
+	list := solid.NewUint64ListSSZ(1 << 20)
+	for i := 0; i < 300_000; i++ {
+		list.Append(rnd.Uint64())
+	}
+	expected, err := solid.ReferenceHashSSZ(list)
+	require.NoError(t, err)
+	for _, workers := range []int{1, 3, 8} {
+		solid.SetHashWorkers(workers)
+		copied := solid.NewUint64ListSSZ(1 << 20)
+		list.CopyTo(copied) // not hashed yet - the whole tree is built
+		root, err := copied.HashSSZ()
+		require.NoError(t, err)
+		require.Equal(t, expected, root, "workers %d", workers)
+	}
+}
+
+func TestUint64SliceSparseHashing(t *testing.T) {
+	defer solid.SetSparseHashing(solid.SparseHashing())
+	skipped := metrics.GetOrCreateCounter(`caplin_solid_zero_nodes_skipped`)
+	slashings := solid.NewUint64VectorSSZ(8192)
+	slashings.Set(3000, 7)
+	list := solid.NewUint64ListSSZ(1 << 40)
+	for i := 0; i < 50_000; i++ {
+		v := uint64(0)
+		if i%10_000 == 1 || i >= 49_990 {
+			v = uint64(i)
+		}
+		list.Append(v)
+	}
+	for _, obj := range []solid.IterableSSZ[uint64]{slashings, list} {
+		expected, err := solid.ReferenceHashSSZ(obj)
+		require.NoError(t, err)
+		for _, sparse := range []bool{false, true} {
+			solid.SetSparseHashing(sparse)
+			before := skipped.GetValueUint64()
+			encoded, err := obj.EncodeSSZ(nil)
+			require.NoError(t, err)
+			decoded := obj.Clone().(solid.IterableSSZ[uint64])
+			require.NoError(t, decoded.DecodeSSZ(encoded, 0)) // not hashed yet - the whole tree is built
+			root, err := decoded.HashSSZ()
+			require.NoError(t, err)
+			require.Equal(t, expected, root, "sparse %v", sparse)
+			require.Equal(t, sparse, skipped.GetValueUint64() > before)
+		}
+	}
+
+	// zeroing a run of leaves is rehashed incrementally
+	solid.SetSparseHashing(true)
+	_, err := list.HashSSZ()
+	require.NoError(t, err)
+	for i := 49_990; i < 50_000; i++ {
+		list.Set(i, 0)
+	}
+	root, err := list.HashSSZ()
+	require.NoError(t, err)
+	expected, err := solid.ReferenceHashSSZ(list)
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+}
+
+func TestUint64SliceCloneShared(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	list := solid.NewUint64ListSSZ(1 << 10)
+	for i := 0; i < 100; i++ {
+		list.Append(uint64(i))
+	}
+	check := func(obj solid.IterableSSZ[uint64]) {
+		root, err := obj.HashSSZ()
+		require.NoError(t, err)
+		expected, err := solid.ReferenceHashSSZ(obj)
+		require.NoError(t, err)
+		require.Equal(t, expected, root)
+	}
+	check(list)
+	list.Set(7, 1000) // dirty when cloned
+
+	clones := []solid.IterableSSZ[uint64]{list, list.CloneShared(), list.CloneShared()}
+	clones = append(clones, clones[1].(solid.Uint64ListSSZ).CloneShared())
+	var wg sync.WaitGroup
+	for i, c := range clones {
+		i, c, seed := i, c, rnd.Int63()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(seed))
+			for j := 0; j < 20; j++ {
+				switch rnd.Intn(3) {
+				case 0:
+					c.Set(rnd.Intn(c.Length()), uint64(i))
+				case 1:
+					c.Append(uint64(i))
+				case 2:
+					c.Pop()
+				}
+				c.HashSSZ()
+			}
+		}()
+	}
+	wg.Wait()
+	for i, c := range clones {
+		check(c)
+		c.Range(func(idx int, v uint64, _ int) bool {
+			require.True(t, v == uint64(idx) || v == uint64(i) || (idx == 7 && v == 1000), "clone %d index %d value %d", i, idx, v)
+			return true
+		})
+	}
+
+	vector := solid.NewUint64VectorSSZ(64)
+	check(vector)
+	copied := vector.CloneShared()
+	copied.Set(3, 3)
+	require.Equal(t, uint64(0), vector.Get(3))
+	check(vector)
+	check(copied)
+	vector.Clear()
+	require.Equal(t, uint64(3), copied.Get(3))
+}
+
+func TestUint64SliceRelease(t *testing.T) {
+	encoded := make([]byte, 8*1024)
+	for i := 0; i < len(encoded)/8; i++ {
+		binary.LittleEndian.PutUint64(encoded[i*8:], uint64(i))
+	}
+	list := solid.NewUint64ListSSZ(1 << 20)
+	require.NoError(t, list.DecodeSSZ(encoded, 0))
+	copied := list.CloneShared()
+	list.Release()
+	require.Equal(t, 0, list.Length())
+
+	// the shared buffer is not pooled, so decoding again doesn't overwrite the clone
+	for i := 0; i < 10; i++ {
+		other := solid.NewUint64ListSSZ(1 << 20)
+		require.NoError(t, other.DecodeSSZ(make([]byte, 8*1024), 0))
+		require.Equal(t, uint64(0), other.Get(1023))
+		other.Release()
+	}
+	require.Equal(t, 1024, copied.Length())
+	require.Equal(t, uint64(1023), copied.Get(1023))
+
+	require.NoError(t, list.DecodeSSZ(encoded, 0))
+	root, err := list.HashSSZ()
+	require.NoError(t, err)
+	expected, err := copied.HashSSZ()
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+}
+
+func TestUint64SliceHashSSZDelta(t *testing.T) {
+	list := solid.NewUint64ListSSZ(1 << 12)
+	for i := 0; i < 1000; i++ {
+		list.Append(uint64(i))
+	}
+	prevRoot, err := list.HashSSZ()
+	require.NoError(t, err)
+
+	// writes through Bytes are invisible to the memoized tree until marked
+	binary.LittleEndian.PutUint64(list.Bytes()[5*8:], 55)
+	binary.LittleEndian.PutUint64(list.Bytes()[600*8:], 66)
+	root, err := list.HashSSZDelta(prevRoot, []int{5, 600})
+	require.NoError(t, err)
+	expected, err := solid.ReferenceHashSSZ(list)
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+
+	// a root other than the last one computed falls back to the full merkleization
+	binary.LittleEndian.PutUint64(list.Bytes()[7*8:], 77)
+	binary.LittleEndian.PutUint64(list.Bytes()[900*8:], 99)
+	root, err = list.HashSSZDelta(prevRoot, []int{7})
+	require.NoError(t, err)
+	expected, err = solid.ReferenceHashSSZ(list)
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+
+	binary.LittleEndian.PutUint64(list.Bytes()[8*8:], 88)
+	list.MarkDirty(8)
+	root, err = list.HashSSZ()
+	require.NoError(t, err)
+	expected, err = solid.ReferenceHashSSZ(list)
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+
+	_, err = list.HashSSZDelta(root, []int{1000})
+	require.ErrorIs(t, err, solid.ErrIndexOutOfRange)
+}
+
+func TestUint64SliceSnapshot(t *testing.T) {
+	list := solid.NewUint64ListSSZ(1 << 12)
+	for i := 0; i < 1000; i++ {
+		list.Append(uint64(i * i))
+	}
+	vector := solid.NewUint64VectorSSZ(64)
+	vector.Set(3, 3)
+	for _, compress := range []bool{false, true} {
+		for _, tc := range []struct {
+			obj, restored solid.Uint64SSZ
+		}{
+			{list, solid.NewUint64ListSSZ(1 << 12)},
+			{vector, solid.NewUint64VectorSSZ(64)},
+			{solid.NewUint64ListSSZ(16), solid.NewUint64ListSSZ(16)},
+		} {
+			var buf bytes.Buffer
+			require.NoError(t, tc.obj.EncodeSnapshot(&buf, compress))
+			require.NoError(t, tc.restored.DecodeSnapshot(&buf))
+			require.True(t, bytes.Equal(tc.obj.Bytes(), tc.restored.Bytes()))
+			expected, err := tc.obj.HashSSZ()
+			require.NoError(t, err)
+			root, err := tc.restored.HashSSZ()
+			require.NoError(t, err)
+			require.Equal(t, expected, root)
+			// the restored tree is updated incrementally
+			if tc.restored.Length() > 0 {
+				tc.restored.Set(tc.restored.Length()-1, 42)
+				root, err = tc.restored.HashSSZ()
+				require.NoError(t, err)
+				expected, err = solid.ReferenceHashSSZ(tc.restored)
+				require.NoError(t, err)
+				require.Equal(t, expected, root)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, list.EncodeSnapshot(&buf, true))
+	encoded := buf.Bytes()
+	require.ErrorIs(t, solid.NewUint64ListSSZ(1<<10).DecodeSnapshot(bytes.NewReader(encoded)), solid.ErrBadSnapshot)
+	require.ErrorIs(t, solid.NewUint64VectorSSZ(1<<12).DecodeSnapshot(bytes.NewReader(encoded)), solid.ErrBadSnapshot)
+	require.ErrorIs(t, solid.NewUint64ListSSZ(1<<12).DecodeSnapshot(bytes.NewReader(encoded[:len(encoded)-1])), solid.ErrBadSnapshot)
+	encoded[0]++
+	require.ErrorIs(t, solid.NewUint64ListSSZ(1<<12).DecodeSnapshot(bytes.NewReader(encoded)), solid.ErrSnapshotVersion)
+
+	// the body length can't exceed the one of a full container, nor allocate more than the snapshot holds
+	for _, compress := range []bool{false, true} {
+		full := solid.NewUint64VectorSSZ(1 << 12)
+		buf.Reset()
+		require.NoError(t, full.EncodeSnapshot(&buf, compress))
+		require.NoError(t, solid.NewUint64VectorSSZ(1<<12).DecodeSnapshot(bytes.NewReader(buf.Bytes())))
+		require.NoError(t, solid.NewUint64ListSSZ(1<<12).DecodeSnapshot(bytes.NewReader(buf.Bytes())))
+		forged := append([]byte(nil), buf.Bytes()...)
+		binary.BigEndian.PutUint64(forged[2:], math.MaxUint64)
+		require.ErrorIs(t, solid.NewUint64ListSSZ(1<<12).DecodeSnapshot(bytes.NewReader(forged)), solid.ErrBadSnapshot)
+		binary.BigEndian.PutUint64(forged[2:], 1<<40)
+		require.ErrorIs(t, solid.NewUint64ListSSZ(1<<40).DecodeSnapshot(bytes.NewReader(forged)), solid.ErrBadSnapshot)
+
+		// a bit flip in the stored merkle layers is caught by the checksum, rather than giving a wrong root
+		flipped := append([]byte(nil), buf.Bytes()...)
+		flipped[len(flipped)-1] ^= 1
+		require.ErrorIs(t, solid.NewUint64VectorSSZ(1<<12).DecodeSnapshot(bytes.NewReader(flipped)), solid.ErrBadSnapshot)
+	}
+}
+
+func TestConcurrentUint64SSZ(t *testing.T) {
+	list := solid.NewUint64ListSSZ(1 << 12)
+	for i := 0; i < 1000; i++ {
+		list.Append(uint64(i))
+	}
+	c := solid.NewConcurrentUint64SSZ(list)
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				length := c.Length()
+				require.GreaterOrEqual(t, length, 1000)
+				c.Get(rand.Intn(length))
+				c.Range(func(idx int, v uint64, _ int) bool { return idx < 10 })
+				_, err := c.HashSSZ()
+				require.NoError(t, err)
+			}
+		}()
+	}
+	for i := 0; i < 200; i++ {
+		c.Set(i, uint64(2*i))
+		c.Append(uint64(i))
+	}
+	wg.Wait()
+
+	copied := solid.NewConcurrentUint64SSZ(solid.NewUint64ListSSZ(1 << 12))
+	c.CopyTo(copied)
+	root, err := copied.HashSSZ()
+	require.NoError(t, err)
+	expected, err := solid.ReferenceHashSSZ(list)
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+}
+
+func TestConcurrentUint64SSZCopyTo(t *testing.T) {
+	a, b := solid.NewConcurrentUint64SSZ(solid.NewUint64ListSSZ(1<<10)), solid.NewConcurrentUint64SSZ(solid.NewUint64ListSSZ(1<<10))
+	for i := 0; i < 100; i++ {
+		a.Append(uint64(i))
+		b.Append(uint64(2 * i))
+	}
+	a.CopyTo(a) // no self-deadlock
+	require.True(t, a.Equal(a))
+	require.Empty(t, a.Diff(a))
+	require.Equal(t, uint64(99), a.Get(99))
+
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				a.CopyTo(b)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				b.CopyTo(a)
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, 100, a.Length())
+	require.True(t, a.Equal(b))
+
+	// the snapshot shares a's buffer until a is written to
+	a.CopyTo(b)
+	a.Set(0, 42)
+	require.Equal(t, uint64(42), a.Get(0))
+	require.Equal(t, uint64(0), b.Get(0))
+}
+
+func TestUint64SliceDiff(t *testing.T) {
+	a, b := solid.NewUint64ListSSZ(1<<12), solid.NewUint64ListSSZ(1<<12)
+	for i := 0; i < 1000; i++ {
+		a.Append(uint64(i))
+		b.Append(uint64(i))
+	}
+	require.True(t, a.Equal(b))
+	b.Set(10, 1)
+	b.Set(999, 2)
+	b.Append(3)
+	expected := []solid.IndexedChange{{Index: 10, Old: 10, New: 1}, {Index: 999, Old: 999, New: 2}, {Index: 1000, Old: 0, New: 3}}
+
+	require.Equal(t, expected, a.Diff(b)) // dirty trees - elements are compared one by one
+	_, err := a.HashSSZ()
+	require.NoError(t, err)
+	_, err = b.HashSSZ()
+	require.NoError(t, err)
+	require.Equal(t, expected, a.Diff(b)) // equal subtrees are skipped
+	require.False(t, a.Equal(b))
+	require.Equal(t, expected, a.Diff(solid.NewConcurrentUint64SSZ(b)))
+
+	b.Pop()
+	b.Set(10, 10)
+	b.Set(999, 999)
+	_, err = b.HashSSZ()
+	require.NoError(t, err)
+	require.True(t, a.Equal(b))
+	require.Empty(t, a.Diff(b))
+}
+
+func TestUint64View(t *testing.T) {
+	list := solid.NewUint64ListSSZ(1 << 10)
+	for i := 0; i < 100; i++ {
+		list.Append(uint64(i * 3))
+	}
+	encoded, err := list.EncodeSSZ(nil)
+	require.NoError(t, err)
+	view, err := solid.NewUint64ListView(1<<10, encoded)
+	require.NoError(t, err)
+	require.Equal(t, list.Length(), view.Length())
+	require.Equal(t, uint64(297), view.Get(99))
+	_, err = view.GetErr(100)
+	require.ErrorIs(t, err, solid.ErrIndexOutOfRange)
+	expected, err := list.HashSSZ()
+	require.NoError(t, err)
+	root, err := view.HashSSZ()
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+	require.True(t, &encoded[0] == &view.Bytes()[0], "the encoding is not copied")
+
+	vector := solid.NewUint64VectorSSZ(64)
+	vector.Set(5, 5)
+	encoded, err = vector.EncodeSSZ(nil)
+	require.NoError(t, err)
+	vectorView, err := solid.NewUint64VectorView(64, encoded)
+	require.NoError(t, err)
+	expected, err = vector.HashSSZ()
+	require.NoError(t, err)
+	root, err = vectorView.HashSSZ()
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+
+	_, err = solid.NewUint64ListView(10, make([]byte, 88))
+	require.Error(t, err)
+	_, err = solid.NewUint64VectorView(10, make([]byte, 72))
+	require.Error(t, err)
+}
+
+func TestUint64SliceApplyDelta(t *testing.T) {
+	list := solid.NewUint64ListSSZ(64)
+	for i := 0; i < 10; i++ {
+		list.Append(uint64(100 * i))
+	}
+	list.Set(9, math.MaxUint64-1)
+	_, err := list.HashSSZ()
+	require.NoError(t, err)
+
+	list.ApplyDelta([]int64{-1, 50, 0, -1000, 0, 0, 0, 0, 0, 10})
+	var values []uint64
+	list.Range(func(_ int, v uint64, _ int) bool {
+		values = append(values, v)
+		return true
+	})
+	require.Equal(t, []uint64{0, 150, 200, 0, 400, 500, 600, 700, 800, math.MaxUint64}, values)
+	list.AddAt(4, math.MinInt64)
+	list.AddAt(5, 5)
+	require.Equal(t, uint64(0), list.Get(4))
+	require.Equal(t, uint64(505), list.Get(5))
+	list.ApplyDelta([]int64{7})
+	require.Equal(t, uint64(7), list.Get(0))
+
+	root, err := list.HashSSZ()
+	require.NoError(t, err)
+	expected, err := solid.ReferenceHashSSZ(list)
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+
+	require.Panics(t, func() { list.ApplyDelta(make([]int64, 11)) })
+	require.Panics(t, func() { list.AddAt(10, 1) })
+}
+
+func TestUint64SliceSplice(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	list := solid.NewUint64ListSSZ(1 << 10)
+	var expected []uint64
+	for i := 0; i < 1000; i++ {
+		switch op := rnd.Intn(10); {
+		case op < 5 || len(expected) == 0:
+			idx, v := rnd.Intn(len(expected)+1), rnd.Uint64()
+			list.Insert(idx, v)
+			expected = slices.Insert(expected, idx, v)
+		case op < 9:
+			idx := rnd.Intn(len(expected))
+			list.RemoveAt(idx)
+			expected = slices.Delete(expected, idx, idx+1)
+		default:
+			n := rnd.Intn(len(expected) + 1)
+			list.Truncate(n)
+			expected = expected[:n]
+		}
+		if i%3 == 0 {
+			root, err := list.HashSSZ()
+			require.NoError(t, err)
+			reference, err := solid.ReferenceHashSSZ(list)
+			require.NoError(t, err)
+			require.Equal(t, reference, root)
+		}
+	}
+	require.Equal(t, len(expected), list.Length())
+	for i, v := range expected {
+		require.Equal(t, v, list.Get(i))
+	}
+	require.Panics(t, func() { list.Insert(list.Length()+1, 0) })
+	require.Panics(t, func() { list.RemoveAt(list.Length()) })
+	require.Panics(t, func() { list.Truncate(list.Length() + 1) })
+}
+
+func TestUint64SliceMutationBatch(t *testing.T) {
+	for _, list := range []solid.Uint64ListSSZ{
+		solid.NewUint64ListSSZ(1 << 10),
+		solid.NewConcurrentUint64SSZ(solid.NewUint64ListSSZ(1 << 10)),
+	} {
+		for i := 0; i < 100; i++ {
+			list.Append(uint64(i))
+		}
+		root, err := list.HashSSZ()
+		require.NoError(t, err)
+
+		// a discarded batch leaves the list untouched
+		batch := list.Begin()
+		batch.Set(3, 1000)
+		batch.Append(7)
+		require.Equal(t, uint64(1000), batch.Get(3))
+		require.Equal(t, uint64(7), batch.Get(100))
+		require.Equal(t, uint64(3), list.Get(3))
+		require.Equal(t, 101, batch.Length())
+		batch.Discard()
+		require.Zero(t, batch.Staged())
+		discarded, err := list.HashSSZ()
+		require.NoError(t, err)
+		require.Equal(t, root, discarded)
+
+		batch.Set(3, 1000)
+		batch.Set(50, 5000)
+		batch.Append(7)
+		batch.Append(8)
+		batch.Set(101, 9)
+		require.NoError(t, batch.Commit())
+		require.Equal(t, 102, list.Length())
+		require.Equal(t, uint64(1000), list.Get(3))
+		require.Equal(t, uint64(5000), list.Get(50))
+		require.Equal(t, uint64(9), list.Get(101))
+		committed, err := list.HashSSZ()
+		require.NoError(t, err)
+		rebuilt := solid.NewUint64ListSSZ(1 << 10)
+		require.NoError(t, rebuilt.DecodeSSZ(list.Bytes(), 0))
+		reference, err := solid.ReferenceHashSSZ(rebuilt)
+		require.NoError(t, err)
+		require.Equal(t, reference, committed)
+
+		// the batch is reusable once committed, but not across a resize of the list
+		batch.Set(0, 1)
+		list.Append(0)
+		require.ErrorIs(t, batch.Commit(), solid.ErrBatchConflict)
+		require.Equal(t, uint64(0), list.Get(0))
+	}
+
+	vector := solid.NewUint64VectorSSZ(8)
+	batch := vector.Begin()
+	batch.Set(7, 1)
+	require.NoError(t, batch.Commit())
+	require.Equal(t, uint64(1), vector.Get(7))
+	require.Panics(t, func() { batch.Append(1) })
+}
+
+type mapSubtreeCache struct {
+	sync.Mutex
+	m          map[[32]byte][]byte
+	hits, puts int
+}
+
+func (c *mapSubtreeCache) Get(key [32]byte) ([]byte, bool) {
+	c.Lock()
+	defer c.Unlock()
+	nodes, ok := c.m[key]
+	if ok {
+		c.hits++
+	}
+	return nodes, ok
+}
+
+func (c *mapSubtreeCache) Put(key [32]byte, nodes []byte) {
+	c.Lock()
+	defer c.Unlock()
+	c.m[key] = append([]byte(nil), nodes...)
+	c.puts++
+}
+
+func TestUint64SliceSubtreeCache(t *testing.T) {
+	cache := &mapSubtreeCache{m: map[[32]byte][]byte{}}
+	solid.SetSubtreeCache(cache)
+	defer solid.SetSubtreeCache(nil)
+
+	rnd := rand.New(rand.NewSource(1))
+	list := solid.NewUint64ListSSZ(1 << 20)
+	for i := 0; i < 10_000; i++ { // 2 full subtrees of 4096 elements, and a partial one
+		list.Append(rnd.Uint64())
+	}
+	check := func(obj solid.IterableSSZ[uint64]) {
+		root, err := obj.HashSSZ()
+		require.NoError(t, err)
+		expected, err := solid.ReferenceHashSSZ(obj)
+		require.NoError(t, err)
+		require.Equal(t, expected, root)
+	}
+	check(list)
+	require.Equal(t, 2, cache.puts)
+	require.Zero(t, cache.hits)
+
+	// a decoded copy finds its subtrees in the cache, and can still be updated incrementally
+	decoded := solid.NewUint64ListSSZ(1 << 20)
+	require.NoError(t, decoded.DecodeSSZ(list.Bytes(), 0))
+	check(decoded)
+	require.Equal(t, 2, cache.hits)
+	decoded.Set(10, 1)
+	decoded.Append(2)
+	check(decoded)
+
+	// entries of the wrong length are ignored, and the subtrees rehashed
+	for key := range cache.m {
+		cache.m[key] = cache.m[key][:32]
+	}
+	require.NoError(t, decoded.DecodeSSZ(list.Bytes(), 0))
+	check(decoded)
+
+	// zero subtrees are not cached in sparse mode
+	puts := cache.puts
+	zeros := solid.NewUint64ListSSZ(1 << 20)
+	for i := 0; i < 3*4096; i++ {
+		zeros.Append(0)
+	}
+	zeros.Set(100, 1)
+	check(zeros)
+	require.Equal(t, puts+1, cache.puts)
+}
@@ -129,8 +129,8 @@ func (v Validator) CopyHashBufferTo(o []byte) error {
 		o[i] = 0
 	}
 	copy(o[:64], v[:48])
-	err := merkle_tree.InPlaceRoot(o[:64])
-	if err != nil {
+	// hash the pubkey chunks directly rather than through the shared (locked) merkle_tree hasher.
+	if err := merkleizeFlatInPlace(o[:64]); err != nil {
 		return err
 	}
 	copy(o[32:64], v[48:80])
@@ -172,49 +172,72 @@ func (v *ValidatorSet) Get(idx int) Validator {
 }
 
 func (v *ValidatorSet) HashSSZ() ([32]byte, error) {
+	s := AcquireScratch()
+	defer ReleaseScratch(s)
+	// the layers buffer is sized for this set, so it is kept around for the next call rather than pooled
+	pooled := s.hashBuf
+	s.hashBuf = v.hashBuf
+	root, err := v.hashSSZ(s)
+	v.hashBuf, s.hashBuf = s.hashBuf, pooled
+	return assertHashSSZ(v, root, err)
+}
+
+// HashSSZWithScratch is HashSSZ using the buffers of s rather than allocating its own.
+func (v *ValidatorSet) HashSSZWithScratch(s *Scratch) ([32]byte, error) {
+	root, err := v.hashSSZ(s)
+	return assertHashSSZ(v, root, err)
+}
+
+func (v *ValidatorSet) hashSSZ(s *Scratch) ([32]byte, error) {
 	// generate root list
 	validatorsLeafChunkSize := convertDepthToChunkSize(validatorTreeCacheGroupLayer)
-	hashBuffer := make([]byte, 8*32)
+	hashBuffer := s.leavesBuf(8 * 32)
 	depth := GetDepth(uint64(v.c))
-	lengthRoot := merkle_tree.Uint64Root(uint64(v.l))
+	// sets smaller than a group are a single group, hashed up to the root
+	groupDepth := min(uint8(validatorTreeCacheGroupLayer), depth)
 
 	if v.l == 0 {
+		lengthRoot := merkle_tree.Uint64Root(0)
 		return utils.Sha256(merkle_tree.ZeroHashes[depth][:], lengthRoot[:]), nil
 	}
 
-	emptyHashBytes := make([]byte, length.Hash)
-
-	layerBuffer := make([]byte, validatorsLeafChunkSize*length.Hash)
+	layerBuffer := s.layerBuf(validatorsLeafChunkSize * length.Hash)
 	for i := 0; i < v.l; i += validatorsLeafChunkSize {
 		from := uint64(i)
 		to := utils.Min64(from+uint64(validatorsLeafChunkSize), uint64(v.l))
 		offset := (i / validatorsLeafChunkSize) * length.Hash
 
-		if !bytes.Equal(v.treeCacheBuffer[offset:offset+length.Hash], emptyHashBytes) {
+		if !bytes.Equal(v.treeCacheBuffer[offset:offset+length.Hash], merkle_tree.ZeroHashes[0][:]) {
+			validatorGroupsCached.Inc()
 			continue
 		}
+		validatorGroupsRehashed.Inc()
+		validatorsHashed.AddUint64(to - from)
 		for i := from; i < to; i++ {
 			validator := v.Get(int(i))
+			// CopyHashBufferTo does not zero the leaves padding, which the in-place merkleization below overwrites.
+			clear(hashBuffer)
 			if err := validator.CopyHashBufferTo(hashBuffer); err != nil {
 				return [32]byte{}, err
 			}
-			hashBuffer = hashBuffer[:(8 * 32)]
-			if err := merkle_tree.MerkleRootFromFlatLeaves(hashBuffer, layerBuffer[(i-from)*length.Hash:]); err != nil {
+			if err := merkleizeFlatInPlace(hashBuffer); err != nil {
 				return [32]byte{}, err
 			}
+			copy(layerBuffer[(i-from)*length.Hash:], hashBuffer[:length.Hash])
 		}
 		endOffset := (to - from) * length.Hash
-		if err := computeFlatRootsToBuffer(validatorTreeCacheGroupLayer, layerBuffer[:endOffset], v.treeCacheBuffer[offset:]); err != nil {
+		if err := computeFlatRootsToBuffer(groupDepth, layerBuffer[:endOffset], v.treeCacheBuffer[offset:]); err != nil {
 			return [32]byte{}, err
 		}
 
 	}
 
 	offset := length.Hash * ((v.l + validatorsLeafChunkSize - 1) / validatorsLeafChunkSize)
-	v.makeBuf(offset)
-	copy(v.buf, v.treeCacheBuffer[:offset])
-	elements := v.buf
-	for i := uint8(validatorTreeCacheGroupLayer); i < depth; i++ {
+	// one more node of room, for the zero hash padding an odd layer
+	s.makeBuf(offset + length.Hash)
+	elements := s.buf[:offset]
+	copy(elements, v.treeCacheBuffer[:offset])
+	for i := groupDepth; i < depth; i++ {
 		// Sequential
 		if len(elements)%64 != 0 {
 			elements = append(elements, merkle_tree.ZeroHashes[i][:]...)
@@ -226,7 +249,14 @@ func (v *ValidatorSet) HashSSZ() ([32]byte, error) {
 		elements = elements[:outputLen]
 	}
 
-	return utils.Sha256(elements[:length.Hash], lengthRoot[:]), nil
+	// mix in the length in the scratch leaves, utils.Sha256 would make its arguments escape
+	copy(hashBuffer, elements[:length.Hash])
+	lengthRoot := merkle_tree.Uint64Root(uint64(v.l))
+	copy(hashBuffer[length.Hash:], lengthRoot[:])
+	if err := merkle_tree.HashByteSlice(hashBuffer, hashBuffer[:2*length.Hash]); err != nil {
+		return [32]byte{}, err
+	}
+	return [32]byte(hashBuffer[:length.Hash]), nil
 }
 
 func computeFlatRootsToBuffer(depth uint8, layerBuffer, output []byte) error {
@@ -249,6 +279,7 @@ func (v *ValidatorSet) Set(idx int, val Validator) {
 	if idx >= v.l {
 		panic("ValidatorSet -- Set: out of bounds")
 	}
+	v.zeroTreeHash(idx)
 	copy(v.buffer[idx*validatorSize:(idx*validatorSize)+validatorSize], val)
 }
 
@@ -1,6 +1,7 @@
 package solid
 
 import (
+	"bytes"
 	"encoding/binary"
 	"testing"
 
@@ -87,3 +88,71 @@ func TestMarshalUnmarshalJson(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, validator, decoded)
 }
+
+func TestValidatorSetSnapshot(t *testing.T) {
+	vset := NewValidatorSet(1000000)
+	for i := 0; i < 100; i++ {
+		var pk [48]byte
+		binary.BigEndian.PutUint32(pk[:], uint32(i))
+		vset.Append(NewValidatorFromParameters(pk, [32]byte{}, uint64(i), false, 1, 2, 3, 4))
+	}
+	vset.setAttesterBit(7, IsCurrentMatchingHeadAttesterBit, true)
+	att := NewPendingAttestionFromParameters([]byte{0xff, 0x01}, NewAttestionDataFromParameters(1, 2, common.Hash{3}, NewCheckpoint(), NewCheckpoint()), 4, 5)
+	vset.SetMinCurrentInclusionDelayAttestation(3, att)
+	vset.SetMinPreviousInclusionDelayAttestation(3, att)
+	vset.SetMinPreviousInclusionDelayAttestation(99, att)
+	var buf bytes.Buffer
+	require.NoError(t, vset.EncodeSnapshot(&buf, true))
+
+	restored := NewValidatorSet(1000000)
+	require.NoError(t, restored.DecodeSnapshot(&buf))
+	require.Equal(t, vset.Bytes(), restored.Bytes())
+	require.Equal(t, vset.treeCacheBuffer, restored.treeCacheBuffer)
+	require.True(t, restored.IsCurrentMatchingHeadAttester(7))
+	for i := 0; i < vset.Length(); i++ {
+		for _, get := range []func(*ValidatorSet, int) *PendingAttestation{(*ValidatorSet).MinCurrentInclusionDelayAttestation, (*ValidatorSet).MinPreviousInclusionDelayAttestation} {
+			if expected := get(vset, i); expected == nil {
+				require.Nil(t, get(restored, i), i)
+			} else {
+				require.Equal(t, expected, get(restored, i), i)
+			}
+		}
+	}
+	expected, err := vset.HashSSZ()
+	require.NoError(t, err)
+	root, err := restored.HashSSZ()
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+
+	require.NoError(t, vset.EncodeSnapshot(&buf, false))
+	forged := append([]byte(nil), buf.Bytes()...)
+	require.ErrorIs(t, NewValidatorSet(10).DecodeSnapshot(&buf), ErrBadSnapshot)
+	binary.BigEndian.PutUint64(forged[2:], 1<<40)
+	require.ErrorIs(t, NewValidatorSet(1000000).DecodeSnapshot(bytes.NewReader(forged)), ErrBadSnapshot)
+
+	buf.Reset()
+	require.NoError(t, vset.EncodeSnapshot(&buf, false))
+	flipped := buf.Bytes()
+	flipped[14+3*8+vset.Length()*validatorSize+8] ^= 1 // in the tree cache
+	require.ErrorIs(t, NewValidatorSet(1000000).DecodeSnapshot(bytes.NewReader(flipped)), ErrBadSnapshot)
+}
+
+func TestValidatorSetSetRehashes(t *testing.T) {
+	vset, expected := NewValidatorSet(1000000), NewValidatorSet(1000000)
+	for i := 0; i < 100; i++ {
+		vset.Append(NewValidatorFromParameters([48]byte{byte(i)}, common.Hash{}, 32_000_000_000, false, 0, 0, 1, 1))
+	}
+	_, err := vset.HashSSZ()
+	require.NoError(t, err)
+	// Set replaces a whole record, the memoized root of its group must not be reused
+	replaced := NewValidatorFromParameters([48]byte{42}, common.Hash{1}, 31_000_000_000, true, 2, 3, 4, 5)
+	vset.Set(42, replaced)
+	for i := 0; i < 100; i++ {
+		expected.Append(vset.Get(i))
+	}
+	root, err := vset.HashSSZ()
+	require.NoError(t, err)
+	expectedRoot, err := expected.HashSSZ()
+	require.NoError(t, err)
+	require.Equal(t, expectedRoot, root)
+}
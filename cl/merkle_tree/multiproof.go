@@ -0,0 +1,254 @@
+package merkle_tree
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+
+	"github.com/ledgerwatch/erigon/cl/utils"
+)
+
+// Generalized indices follow https://github.com/ethereum/consensus-specs/blob/dev/ssz/merkle-proofs.md:
+// the root is 1 and the children of node i are 2i and 2i+1.
+
+// ConcatGeneralizedIndices returns the generalized index of the descendant reached by following each index in turn,
+// e.g. the index of a field of a container nested in another one.
+func ConcatGeneralizedIndices(indices ...uint64) uint64 {
+	o := uint64(1)
+	for _, i := range indices {
+		p := floorPowerOfTwo(i)
+		o = o*p + (i - p)
+	}
+	return o
+}
+
+func floorPowerOfTwo(i uint64) uint64 {
+	return 1 << (bits.Len64(i) - 1)
+}
+
+func generalizedIndexLength(i uint64) int {
+	return bits.Len64(i) - 1
+}
+
+// branchIndices returns the siblings of the nodes on the path from i to the root.
+func branchIndices(i uint64) []uint64 {
+	var o []uint64
+	for ; i > 1; i /= 2 {
+		o = append(o, i^1)
+	}
+	return o
+}
+
+// pathIndices returns the nodes on the path from i to the root, root excluded.
+func pathIndices(i uint64) []uint64 {
+	var o []uint64
+	for ; i > 1; i /= 2 {
+		o = append(o, i)
+	}
+	return o
+}
+
+// GetHelperIndices returns the generalized indices of the nodes a multiproof of indices is made of, in decreasing order.
+func GetHelperIndices(indices []uint64) []uint64 {
+	helpers, paths := map[uint64]struct{}{}, map[uint64]struct{}{}
+	for _, index := range indices {
+		for _, b := range branchIndices(index) {
+			helpers[b] = struct{}{}
+		}
+		for _, p := range pathIndices(index) {
+			paths[p] = struct{}{}
+		}
+	}
+	o := make([]uint64, 0, len(helpers))
+	for h := range helpers {
+		if _, ok := paths[h]; !ok {
+			o = append(o, h)
+		}
+	}
+	sort.Slice(o, func(i, j int) bool { return o[i] > o[j] })
+	return o
+}
+
+// CalculateMultiMerkleRoot computes the root committed to by a multiproof of leaves at indices.
+func CalculateMultiMerkleRoot(leaves, proof [][32]byte, indices []uint64) ([32]byte, error) {
+	if len(leaves) != len(indices) {
+		return [32]byte{}, fmt.Errorf("multiproof has %d leaves but %d indices", len(leaves), len(indices))
+	}
+	helpers := GetHelperIndices(indices)
+	if len(proof) != len(helpers) {
+		return [32]byte{}, fmt.Errorf("multiproof has %d nodes, want %d", len(proof), len(helpers))
+	}
+	objects := make(map[uint64][32]byte, len(leaves)+len(proof))
+	for i, index := range indices {
+		objects[index] = leaves[i]
+	}
+	for i, index := range helpers {
+		objects[index] = proof[i]
+	}
+	keys := make([]uint64, 0, len(objects))
+	for k := range objects {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] > keys[j] })
+	for pos := 0; pos < len(keys); pos++ {
+		k := keys[pos]
+		_, hasSibling := objects[k^1]
+		_, hasParent := objects[k/2]
+		if hasSibling && !hasParent {
+			left, right := objects[(k|1)^1], objects[k|1]
+			objects[k/2] = utils.Sha256(left[:], right[:])
+			keys = append(keys, k/2)
+		}
+	}
+	root, ok := objects[1]
+	if !ok {
+		return [32]byte{}, fmt.Errorf("multiproof does not reach the root")
+	}
+	return root, nil
+}
+
+// VerifyMerkleMultiproof checks that leaves at indices are committed to by root.
+func VerifyMerkleMultiproof(leaves, proof [][32]byte, indices []uint64, root [32]byte) bool {
+	computed, err := CalculateMultiMerkleRoot(leaves, proof, indices)
+	return err == nil && computed == root
+}
+
+// ProofTree is the merkle tree of an SSZ object whose leaves can be expanded into the trees of the nested objects,
+// so that proofs can span composite containers (e.g. state -> validators[i] -> pubkey).
+// Internal nodes are only computed when a proof needs them.
+type ProofTree struct {
+	depth     int
+	chunks    [][32]byte
+	hasLength bool
+	length    uint64
+	children  map[uint64]*ProofTree
+	nodes     map[uint64][32]byte // memoized internal nodes of the chunks tree
+}
+
+// NewProofTree creates the tree of a container or vector out of its chunks, limit being the maximum amount of chunks.
+func NewProofTree(chunks [][32]byte, limit uint64) *ProofTree {
+	if limit < uint64(len(chunks)) {
+		limit = uint64(len(chunks))
+	}
+	return &ProofTree{
+		depth:    generalizedIndexLength(NextPowerOfTwo(limit)),
+		chunks:   chunks,
+		children: map[uint64]*ProofTree{},
+		nodes:    map[uint64][32]byte{},
+	}
+}
+
+// NewListProofTree creates the tree of a list, which mixes length in its root.
+func NewListProofTree(chunks [][32]byte, limit uint64, length uint64) *ProofTree {
+	t := NewProofTree(chunks, limit)
+	t.hasLength, t.length = true, length
+	return t
+}
+
+// Attach expands the chunk at index into sub, whose root must be the chunk itself.
+func (t *ProofTree) Attach(index uint64, sub *ProofTree) error {
+	if index >= uint64(len(t.chunks)) {
+		return fmt.Errorf("chunk %d out of range, have %d chunks", index, len(t.chunks))
+	}
+	root, err := sub.Root()
+	if err != nil {
+		return err
+	}
+	if root != t.chunks[index] {
+		return fmt.Errorf("subtree root %x does not match chunk %d (%x)", root, index, t.chunks[index])
+	}
+	t.children[index] = sub
+	return nil
+}
+
+// Root returns the hash tree root of the object.
+func (t *ProofTree) Root() ([32]byte, error) {
+	return t.Node(1)
+}
+
+// ChunkIndex returns the generalized index of the chunk at index, relative to this tree.
+func (t *ProofTree) ChunkIndex(index uint64) uint64 {
+	g := uint64(1)<<t.depth + index
+	if t.hasLength {
+		return ConcatGeneralizedIndices(2, g)
+	}
+	return g
+}
+
+// Node returns the node at generalized index g, descending into the attached subtrees if needed.
+func (t *ProofTree) Node(g uint64) ([32]byte, error) {
+	if g == 0 {
+		return [32]byte{}, fmt.Errorf("invalid generalized index 0")
+	}
+	if !t.hasLength {
+		return t.chunksNode(g)
+	}
+	if g == 1 {
+		dataRoot, err := t.chunksNode(1)
+		if err != nil {
+			return [32]byte{}, err
+		}
+		lengthRoot := Uint64Root(t.length)
+		return utils.Sha256(dataRoot[:], lengthRoot[:]), nil
+	}
+	if g == 3 {
+		return Uint64Root(t.length), nil
+	}
+	l := generalizedIndexLength(g)
+	if (g>>(l-1))&1 == 1 {
+		return [32]byte{}, fmt.Errorf("generalized index %d is below the length mix-in", g)
+	}
+	return t.chunksNode(g&(1<<(l-1)-1) | 1<<(l-1))
+}
+
+func (t *ProofTree) chunksNode(g uint64) ([32]byte, error) {
+	l := generalizedIndexLength(g)
+	if l > t.depth {
+		index := g>>(l-t.depth) - 1<<t.depth
+		child, ok := t.children[index]
+		if !ok {
+			return [32]byte{}, fmt.Errorf("generalized index %d is inside chunk %d, which is not expanded", g, index)
+		}
+		rel := l - t.depth
+		return child.Node(g&(1<<rel-1) | 1<<rel)
+	}
+	first := (g - 1<<l) << (t.depth - l)
+	if first >= uint64(len(t.chunks)) {
+		return ZeroHashes[t.depth-l], nil
+	}
+	if l == t.depth {
+		return t.chunks[first], nil
+	}
+	if node, ok := t.nodes[g]; ok {
+		return node, nil
+	}
+	left, err := t.chunksNode(2 * g)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	right, err := t.chunksNode(2*g + 1)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	node := utils.Sha256(left[:], right[:])
+	t.nodes[g] = node
+	return node, nil
+}
+
+// MultiProof returns the leaves at indices and the helper nodes proving them, ordered as GetHelperIndices.
+func (t *ProofTree) MultiProof(indices ...uint64) (leaves, proof [][32]byte, err error) {
+	leaves = make([][32]byte, len(indices))
+	for i, index := range indices {
+		if leaves[i], err = t.Node(index); err != nil {
+			return nil, nil, err
+		}
+	}
+	helpers := GetHelperIndices(indices)
+	proof = make([][32]byte, len(helpers))
+	for i, index := range helpers {
+		if proof[i], err = t.Node(index); err != nil {
+			return nil, nil, err
+		}
+	}
+	return leaves, proof, nil
+}
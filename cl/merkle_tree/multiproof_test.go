@@ -0,0 +1,61 @@
+package merkle_tree_test
+
+import (
+	"testing"
+
+	"github.com/ledgerwatch/erigon/cl/merkle_tree"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneralizedIndices(t *testing.T) {
+	// field 3 of a 4-fields container, nested in field 1 of an 8-fields container
+	require.Equal(t, uint64(0b1001_11), merkle_tree.ConcatGeneralizedIndices(9, 7))
+	require.Equal(t, uint64(9), merkle_tree.ConcatGeneralizedIndices(1, 9))
+	// siblings of leaves 8 and 10 are 9 and 11; 4 and 5 are computed from them, leaving only 3 to prove
+	require.Equal(t, []uint64{11, 9, 3}, merkle_tree.GetHelperIndices([]uint64{8, 10}))
+}
+
+func TestProofTreeMultiProof(t *testing.T) {
+	chunks := make([][32]byte, 11)
+	for i := range chunks {
+		chunks[i][0] = byte(i + 1)
+	}
+	lengthRoot := merkle_tree.Uint64Root(uint64(len(chunks)))
+
+	inner := merkle_tree.NewProofTree([][32]byte{{0xaa}, {0xbb}, {0xcc}}, 4)
+	innerRoot, err := inner.Root()
+	require.NoError(t, err)
+	chunks[5] = innerRoot
+	listRoot, err := merkle_tree.MerkleizeVector(chunks, 64)
+	require.NoError(t, err)
+	expected, err := merkle_tree.HashTreeRoot(listRoot[:], lengthRoot[:])
+	require.NoError(t, err)
+
+	tree := merkle_tree.NewListProofTree(chunks, 64, uint64(len(chunks)))
+	root, err := tree.Root()
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+
+	require.Error(t, tree.Attach(4, inner))
+	require.NoError(t, tree.Attach(5, inner))
+	_, err = tree.Node(merkle_tree.ConcatGeneralizedIndices(tree.ChunkIndex(4), 5))
+	require.ErrorContains(t, err, "not expanded")
+
+	indices := []uint64{
+		tree.ChunkIndex(0),
+		tree.ChunkIndex(10),
+		merkle_tree.ConcatGeneralizedIndices(tree.ChunkIndex(5), 6), // third chunk of the nested tree
+		3, // length
+	}
+	leaves, proof, err := tree.MultiProof(indices...)
+	require.NoError(t, err)
+	require.Equal(t, chunks[0], leaves[0])
+	require.Equal(t, chunks[10], leaves[1])
+	require.Equal(t, [32]byte{0xcc}, leaves[2])
+	require.Equal(t, [32]byte(lengthRoot), leaves[3])
+	require.True(t, merkle_tree.VerifyMerkleMultiproof(leaves, proof, indices, root))
+
+	leaves[2][0]++
+	require.False(t, merkle_tree.VerifyMerkleMultiproof(leaves, proof, indices, root))
+	require.False(t, merkle_tree.VerifyMerkleMultiproof(leaves[:3], proof, indices, root))
+}
@@ -1,5 +1,7 @@
 package merkle_tree
 
+import "math/bits"
+
 func NextPowerOfTwo(n uint64) uint64 {
 	if n == 0 {
 		return 1
@@ -17,25 +19,11 @@ func NextPowerOfTwo(n uint64) uint64 {
 	return n
 }
 
-// GetDepth returns the depth of a merkle tree with a given number of nodes.
-// The depth is defined as the number of levels in the tree, with the root
-// node at level 0 and each child node at a level one greater than its parent.
-// If the number of nodes is less than or equal to 1, the depth is 0.
+// GetDepth returns the depth of a merkle tree with v leaves, which are padded with zero hashes to the next power of
+// two: the root is at depth 0, so 0 or 1 leaves give a depth of 0, 3 and 4 leaves a depth of 2.
 func GetDepth(v uint64) uint8 {
-	// If there are 0 or 1 nodes, the depth is 0.
 	if v <= 1 {
 		return 0
 	}
-
-	// Initialize the depth to 0.
-	depth := uint8(0)
-
-	// Divide the number of nodes by 2 until it is less than or equal to 1.
-	// The number of iterations is the depth of the tree.
-	for v > 1 {
-		v >>= 1
-		depth++
-	}
-
-	return depth
+	return uint8(bits.Len64(v - 1))
 }
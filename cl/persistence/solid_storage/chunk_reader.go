@@ -0,0 +1,101 @@
+package solid_storage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/cl/cltypes/solid"
+)
+
+// ChunkReader reads a stored container chunk by chunk, each chunk being fetched on first access and kept for the
+// lifetime of the reader. It is only valid as long as its transaction and must not be shared across goroutines.
+type ChunkReader struct {
+	tx     kv.Tx
+	id     uint64
+	m      metadata
+	chunks map[int][]byte
+}
+
+// Size returns the length of the encoding of the container.
+func (r *ChunkReader) Size() int {
+	return r.m.size
+}
+
+// FetchedChunks returns the number of chunks read from the database so far.
+func (r *ChunkReader) FetchedChunks() int {
+	return len(r.chunks)
+}
+
+func (r *ChunkReader) chunk(i int) ([]byte, error) {
+	if c, ok := r.chunks[i]; ok {
+		return c, nil
+	}
+	v, err := r.tx.GetOne(kv.SolidContainerChunks, chunkKey(r.id, i))
+	if err != nil {
+		return nil, err
+	}
+	if want := min(r.m.chunkSize, r.m.size-i*r.m.chunkSize); len(v) != want {
+		return nil, fmt.Errorf("solid_storage: container %d: chunk %d is %d bytes, want %d", r.id, i, len(v), want)
+	}
+	// the value is only valid within the transaction and may be reused by the remote client
+	c := append([]byte(nil), v...)
+	r.chunks[i] = c
+	return c, nil
+}
+
+// ReadAt implements io.ReaderAt over the encoding of the container, fetching the chunks covering the range.
+func (r *ChunkReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("solid_storage: negative offset %d", off)
+	}
+	n := 0
+	for n < len(p) && int(off)+n < r.m.size {
+		pos := int(off) + n
+		c, err := r.chunk(pos / r.m.chunkSize)
+		if err != nil {
+			return n, err
+		}
+		n += copy(p[n:], c[pos%r.m.chunkSize:])
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Bytes reads the whole encoding.
+func (r *ChunkReader) Bytes() ([]byte, error) {
+	buf := make([]byte, r.m.size)
+	if _, err := r.ReadAt(buf, 0); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// Uint64 reads the element at index of a uint64 list or vector.
+func (r *ChunkReader) Uint64(index int) (uint64, error) {
+	var buf [8]byte
+	if err := r.readElement(buf[:], index); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(buf[:]), nil
+}
+
+// Validator reads the validator at index of a validator set.
+func (r *ChunkReader) Validator(index int) (solid.Validator, error) {
+	v := solid.NewValidator()
+	if err := r.readElement(v, index); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (r *ChunkReader) readElement(dst []byte, index int) error {
+	if index < 0 || (index+1)*len(dst) > r.m.size {
+		return fmt.Errorf("%w: index %d, length %d", solid.ErrIndexOutOfRange, index, r.m.size/len(dst))
+	}
+	_, err := r.ReadAt(dst, int64(index*len(dst)))
+	return err
+}
@@ -0,0 +1,184 @@
+package solid_storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/cl/cltypes/solid"
+)
+
+// DefaultChunkSize is the size in bytes of the chunks containers are split into, 512 uint64s.
+const DefaultChunkSize = 4096
+
+// ChunkStore persists the SSZ encodings of solid containers into kv.SolidContainerChunks, split in fixed-size chunks
+// keyed by (container id, chunk index). Writing a container again only rewrites the chunks which changed, so that
+// e.g. the balances can be persisted every epoch at the cost of the validators whose balance moved.
+//
+// Reading goes through kv.Tx only, so containers can be read lazily both from the local database and through the
+// remote KV client.
+type ChunkStore struct {
+	chunkSize int
+}
+
+// NewChunkStore returns a store writing chunks of chunkSize bytes, which should be a multiple of the element size.
+func NewChunkStore(chunkSize int) *ChunkStore {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	return &ChunkStore{chunkSize: chunkSize}
+}
+
+// the metadata of a container is stored under its id alone, which sorts before its chunks
+func metadataKey(id uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, id)
+}
+
+func chunkKey(id uint64, chunk int) []byte {
+	return binary.BigEndian.AppendUint64(metadataKey(id), uint64(chunk))
+}
+
+// metadata is the length of the encoding and the size of the chunks it was written with.
+type metadata struct {
+	size, chunkSize int
+}
+
+func (m metadata) chunks() int {
+	if m.chunkSize == 0 {
+		return 0
+	}
+	return (m.size + m.chunkSize - 1) / m.chunkSize
+}
+
+func readMetadata(tx kv.Tx, id uint64) (metadata, bool, error) {
+	v, err := tx.GetOne(kv.SolidContainerChunks, metadataKey(id))
+	if err != nil {
+		return metadata{}, false, err
+	}
+	if len(v) == 0 {
+		return metadata{}, false, nil
+	}
+	if len(v) != 16 {
+		return metadata{}, false, fmt.Errorf("solid_storage: container %d: bad metadata length %d", id, len(v))
+	}
+	size, chunkSize := binary.BigEndian.Uint64(v), binary.BigEndian.Uint64(v[8:])
+	if chunkSize == 0 || chunkSize > math.MaxInt32 || size > math.MaxInt-chunkSize {
+		return metadata{}, false, fmt.Errorf("solid_storage: container %d: bad metadata, size %d, chunk size %d", id, size, chunkSize)
+	}
+	m := metadata{size: int(size), chunkSize: int(chunkSize)}
+	// the size must account for exactly the stored chunks: the last one is there, the next one isn't
+	n := m.chunks()
+	if n > 0 {
+		last, err := tx.Has(kv.SolidContainerChunks, chunkKey(id, n-1))
+		if err != nil {
+			return metadata{}, false, err
+		}
+		if !last {
+			return metadata{}, false, fmt.Errorf("solid_storage: container %d: size %d needs %d chunks, chunk %d is missing", id, m.size, n, n-1)
+		}
+	}
+	more, err := tx.Has(kv.SolidContainerChunks, chunkKey(id, n))
+	if err != nil {
+		return metadata{}, false, err
+	}
+	if more {
+		return metadata{}, false, fmt.Errorf("solid_storage: container %d: size %d needs %d chunks, more are stored", id, m.size, n)
+	}
+	return m, true, nil
+}
+
+// Put writes data as the encoding of container id and returns the number of chunks written, the ones equal to what
+// is already stored being skipped.
+func (s *ChunkStore) Put(tx kv.RwTx, id uint64, data []byte) (int, error) {
+	prev, _, err := readMetadata(tx, id)
+	if err != nil {
+		return 0, err
+	}
+	next := metadata{size: len(data), chunkSize: s.chunkSize}
+	written := 0
+	for i := 0; i < next.chunks(); i++ {
+		chunk := data[i*s.chunkSize : min((i+1)*s.chunkSize, len(data))]
+		if prev.chunkSize == s.chunkSize && i < prev.chunks() {
+			stored, err := tx.GetOne(kv.SolidContainerChunks, chunkKey(id, i))
+			if err != nil {
+				return 0, err
+			}
+			if bytes.Equal(stored, chunk) {
+				continue
+			}
+		}
+		if err := tx.Put(kv.SolidContainerChunks, chunkKey(id, i), chunk); err != nil {
+			return 0, err
+		}
+		written++
+	}
+	for i := next.chunks(); i < prev.chunks(); i++ {
+		if err := tx.Delete(kv.SolidContainerChunks, chunkKey(id, i)); err != nil {
+			return 0, err
+		}
+	}
+	v := binary.BigEndian.AppendUint64(nil, uint64(next.size))
+	v = binary.BigEndian.AppendUint64(v, uint64(next.chunkSize))
+	return written, tx.Put(kv.SolidContainerChunks, metadataKey(id), v)
+}
+
+// PutUint64s writes a uint64 list or vector.
+func (s *ChunkStore) PutUint64s(tx kv.RwTx, id uint64, u solid.IterableSSZ[uint64]) (int, error) {
+	return s.Put(tx, id, u.Bytes())
+}
+
+// PutValidatorSet writes the validators, without their cached hashes and attester bits.
+func (s *ChunkStore) PutValidatorSet(tx kv.RwTx, id uint64, v *solid.ValidatorSet) (int, error) {
+	return s.Put(tx, id, v.Bytes())
+}
+
+// Delete removes container id.
+func (s *ChunkStore) Delete(tx kv.RwTx, id uint64) error {
+	m, ok, err := readMetadata(tx, id)
+	if err != nil || !ok {
+		return err
+	}
+	for i := 0; i < m.chunks(); i++ {
+		if err := tx.Delete(kv.SolidContainerChunks, chunkKey(id, i)); err != nil {
+			return err
+		}
+	}
+	return tx.Delete(kv.SolidContainerChunks, metadataKey(id))
+}
+
+// Open returns a reader of container id, nil if it is not stored. Chunks are only read when accessed.
+func (s *ChunkStore) Open(tx kv.Tx, id uint64) (*ChunkReader, error) {
+	m, ok, err := readMetadata(tx, id)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &ChunkReader{tx: tx, id: id, m: m, chunks: make(map[int][]byte)}, nil
+}
+
+// LoadUint64s decodes container id into u, returning false if it is not stored.
+func (s *ChunkStore) LoadUint64s(tx kv.Tx, id uint64, u solid.IterableSSZ[uint64]) (bool, error) {
+	r, err := s.Open(tx, id)
+	if err != nil || r == nil {
+		return false, err
+	}
+	buf, err := r.Bytes()
+	if err != nil {
+		return false, err
+	}
+	return true, u.DecodeSSZ(buf, 0)
+}
+
+// LoadValidatorSet decodes container id into v, returning false if it is not stored.
+func (s *ChunkStore) LoadValidatorSet(tx kv.Tx, id uint64, v *solid.ValidatorSet) (bool, error) {
+	r, err := s.Open(tx, id)
+	if err != nil || r == nil {
+		return false, err
+	}
+	buf, err := r.Bytes()
+	if err != nil {
+		return false, err
+	}
+	return true, v.DecodeSSZ(buf, 0)
+}
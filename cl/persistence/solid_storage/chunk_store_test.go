@@ -0,0 +1,143 @@
+package solid_storage
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/cl/cltypes/solid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkStoreUint64s(t *testing.T) {
+	db := memdb.NewTestDB(t)
+	tx, err := db.BeginRw(context.Background())
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	store := NewChunkStore(DefaultChunkSize)
+	balances := solid.NewUint64ListSSZ(1 << 20)
+	for i := 0; i < 10_000; i++ {
+		balances.Append(uint64(i) * 32)
+	}
+	written, err := store.PutUint64s(tx, 1, balances)
+	require.NoError(t, err)
+	require.Equal(t, 20, written)
+
+	// only the chunk holding the changed balance is rewritten
+	balances.Set(5000, 1)
+	written, err = store.PutUint64s(tx, 1, balances)
+	require.NoError(t, err)
+	require.Equal(t, 1, written)
+
+	r, err := store.Open(tx, 1)
+	require.NoError(t, err)
+	v, err := r.Uint64(5000)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), v)
+	v, err = r.Uint64(9999)
+	require.NoError(t, err)
+	require.Equal(t, uint64(9999*32), v)
+	require.Equal(t, 2, r.FetchedChunks())
+	_, err = r.Uint64(10_000)
+	require.ErrorIs(t, err, solid.ErrIndexOutOfRange)
+
+	loaded := solid.NewUint64ListSSZ(1 << 20)
+	ok, err := store.LoadUint64s(tx, 1, loaded)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.True(t, balances.Equal(loaded))
+
+	// shrinking drops the trailing chunks
+	balances.Truncate(100)
+	_, err = store.PutUint64s(tx, 1, balances)
+	require.NoError(t, err)
+	count := 0
+	require.NoError(t, tx.ForPrefix(kv.SolidContainerChunks, metadataKey(1), func(k, v []byte) error {
+		count++
+		return nil
+	}))
+	require.Equal(t, 2, count) // metadata and chunk 0
+
+	require.NoError(t, store.Delete(tx, 1))
+	r, err = store.Open(tx, 1)
+	require.NoError(t, err)
+	require.Nil(t, r)
+}
+
+func TestChunkStoreValidatorSet(t *testing.T) {
+	db := memdb.NewTestDB(t)
+	tx, err := db.BeginRw(context.Background())
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	store := NewChunkStore(1000) // validators straddle the chunks
+	validators := solid.NewValidatorSet(1 << 20)
+	for i := 0; i < 100; i++ {
+		v := solid.NewValidator()
+		v.SetEffectiveBalance(uint64(i))
+		v.SetExitEpoch(uint64(i) + 1)
+		validators.Append(v)
+	}
+	_, err = store.PutValidatorSet(tx, 2, validators)
+	require.NoError(t, err)
+
+	r, err := store.Open(tx, 2)
+	require.NoError(t, err)
+	v, err := r.Validator(8)
+	require.NoError(t, err)
+	require.Equal(t, validators.Get(8), v)
+
+	loaded := solid.NewValidatorSet(1 << 20)
+	ok, err := store.LoadValidatorSet(tx, 2, loaded)
+	require.NoError(t, err)
+	require.True(t, ok)
+	expected, err := validators.HashSSZ()
+	require.NoError(t, err)
+	root, err := loaded.HashSSZ()
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+}
+
+func TestChunkStoreBadMetadata(t *testing.T) {
+	db := memdb.NewTestDB(t)
+	tx, err := db.BeginRw(context.Background())
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	store := NewChunkStore(64)
+	balances := solid.NewUint64ListSSZ(1 << 10)
+	for i := 0; i < 20; i++ { // 160 bytes, in 3 chunks
+		balances.Append(uint64(i))
+	}
+	_, err = store.PutUint64s(tx, 1, balances)
+	require.NoError(t, err)
+
+	for _, m := range []struct{ size, chunkSize uint64 }{
+		{160, 0},
+		{160, 32},             // 5 chunks
+		{64, 64},              // 1 chunk
+		{200, 64},             // 4 chunks
+		{0, 64},               // no chunk
+		{math.MaxUint64, 64},  // would wrap to a negative size
+		{160, math.MaxUint64}, // and chunk size
+	} {
+		v := binary.BigEndian.AppendUint64(nil, m.size)
+		v = binary.BigEndian.AppendUint64(v, m.chunkSize)
+		require.NoError(t, tx.Put(kv.SolidContainerChunks, metadataKey(1), v))
+		r, err := store.Open(tx, 1)
+		require.Error(t, err, "size %d, chunk size %d", m.size, m.chunkSize)
+		require.Nil(t, r)
+	}
+
+	v := binary.BigEndian.AppendUint64(nil, 150)
+	v = binary.BigEndian.AppendUint64(v, 64)
+	require.NoError(t, tx.Put(kv.SolidContainerChunks, metadataKey(1), v))
+	r, err := store.Open(tx, 1)
+	require.NoError(t, err) // the chunk count matches, the length of the last chunk is checked when it is read
+	_, err = r.Bytes()
+	require.Error(t, err)
+}
@@ -0,0 +1,120 @@
+package solid_storage
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/cl/cltypes/solid"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// MaxPendingSubtrees is the amount of subtrees kept in memory until Flush, 32MiB of nodes: further ones are dropped.
+const MaxPendingSubtrees = 1024
+
+// SubtreeCache is a solid.SubtreeCache persisted in kv.SolidSubtreeCache, so that the merkleization of the subtrees
+// survives restarts. Entries are tagged with the generation (e.g. the epoch) they were last used in, so that the ones
+// not used for a while can be pruned. New and refreshed entries are kept in memory until Flush.
+type SubtreeCache struct {
+	db         kv.RwDB
+	generation atomic.Uint64
+
+	mu      sync.Mutex
+	pending map[[32]byte][]byte // generation + nodes
+}
+
+var _ solid.SubtreeCache = (*SubtreeCache)(nil)
+
+func NewSubtreeCache(db kv.RwDB) *SubtreeCache {
+	return &SubtreeCache{db: db, pending: make(map[[32]byte][]byte)}
+}
+
+// SetGeneration sets the generation entries used from then on are tagged with.
+func (c *SubtreeCache) SetGeneration(generation uint64) {
+	c.generation.Store(generation)
+}
+
+func (c *SubtreeCache) Get(key [32]byte) ([]byte, bool) {
+	c.mu.Lock()
+	v, ok := c.pending[key]
+	c.mu.Unlock()
+	if ok {
+		return v[8:], true
+	}
+	if err := c.db.View(context.Background(), func(tx kv.Tx) error {
+		stored, err := tx.GetOne(kv.SolidSubtreeCache, key[:])
+		if len(stored) > 8 {
+			v = append([]byte(nil), stored...)
+		}
+		return err
+	}); err != nil {
+		log.Debug("[SubtreeCache] lookup failed", "err", err)
+		return nil, false
+	}
+	if v == nil {
+		return nil, false
+	}
+	if generation := c.generation.Load(); binary.BigEndian.Uint64(v) < generation {
+		binary.BigEndian.PutUint64(v, generation)
+		c.stage(key, v)
+	}
+	return v[8:], true
+}
+
+func (c *SubtreeCache) Put(key [32]byte, nodes []byte) {
+	v := binary.BigEndian.AppendUint64(make([]byte, 0, 8+len(nodes)), c.generation.Load())
+	c.stage(key, append(v, nodes...))
+}
+
+func (c *SubtreeCache) stage(key [32]byte, v []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.pending) < MaxPendingSubtrees {
+		c.pending[key] = v
+	}
+}
+
+// Flush writes the new and refreshed entries.
+func (c *SubtreeCache) Flush(ctx context.Context) error {
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = make(map[[32]byte][]byte)
+	c.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+	return c.db.Update(ctx, func(tx kv.RwTx) error {
+		for key, v := range pending {
+			if err := tx.Put(kv.SolidSubtreeCache, key[:], v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Prune deletes the stored entries last used before generation, and returns how many were deleted.
+func (c *SubtreeCache) Prune(ctx context.Context, generation uint64) (int, error) {
+	var pruned int
+	err := c.db.Update(ctx, func(tx kv.RwTx) error {
+		var stale [][]byte
+		if err := tx.ForEach(kv.SolidSubtreeCache, nil, func(k, v []byte) error {
+			if len(v) < 8 || binary.BigEndian.Uint64(v) < generation {
+				stale = append(stale, append([]byte(nil), k...))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, k := range stale {
+			if err := tx.Delete(kv.SolidSubtreeCache, k); err != nil {
+				return err
+			}
+		}
+		pruned = len(stale)
+		return nil
+	})
+	return pruned, err
+}
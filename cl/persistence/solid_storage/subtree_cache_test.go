@@ -0,0 +1,48 @@
+package solid_storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/cl/cltypes/solid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubtreeCache(t *testing.T) {
+	db := memdb.NewTestDB(t)
+	ctx := context.Background()
+	balances := solid.NewUint64ListSSZ(1 << 20)
+	for i := 0; i < 10_000; i++ {
+		balances.Append(uint64(i) * 32)
+	}
+	expected, err := solid.ReferenceHashSSZ(balances)
+	require.NoError(t, err)
+
+	cache := NewSubtreeCache(db)
+	solid.SetSubtreeCache(cache)
+	defer solid.SetSubtreeCache(nil)
+	root, err := balances.HashSSZ()
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+	require.NoError(t, cache.Flush(ctx))
+
+	// as after a restart: the subtrees are read from the database, and refreshed to the current generation
+	cache = NewSubtreeCache(db)
+	cache.SetGeneration(5)
+	solid.SetSubtreeCache(cache)
+	decoded := solid.NewUint64ListSSZ(1 << 20)
+	require.NoError(t, decoded.DecodeSSZ(balances.Bytes(), 0))
+	root, err = decoded.HashSSZ()
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+	require.Len(t, cache.pending, 2)
+	require.NoError(t, cache.Flush(ctx))
+
+	pruned, err := cache.Prune(ctx, 5)
+	require.NoError(t, err)
+	require.Zero(t, pruned)
+	pruned, err = cache.Prune(ctx, 6)
+	require.NoError(t, err)
+	require.Equal(t, 2, pruned)
+}
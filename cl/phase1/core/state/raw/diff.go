@@ -0,0 +1,585 @@
+package raw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/types/ssz"
+	"github.com/ledgerwatch/erigon/cl/clparams"
+	"github.com/ledgerwatch/erigon/cl/cltypes/solid"
+)
+
+// State diffs encode a beacon state as its changes from a previous state of the same version, field by field, so
+// that archive nodes can store a full state every N slots and a diff for the other ones. An unchanged field takes a
+// byte, the validators, balances, inactivity scores, slashings, participation and roots take their changed elements,
+// and the other fields, which are small, are stored whole or as the bytes appended to their SSZ encoding.
+
+// stateDiffFormat is the first byte of the diffs.
+const stateDiffFormat = 1
+
+// encodings of a field in a diff
+const (
+	fieldUnchanged byte = iota
+	fieldFull           // length-prefixed SSZ encoding
+	fieldAppended       // length-prefixed bytes appended to the previous SSZ encoding
+	fieldPatched        // new length and changed elements, see the append*Patch functions
+)
+
+// validatorFieldOffsets delimit the fields of a validator record, a patch storing the changed ones only.
+var validatorFieldOffsets = [...]int{0, 48, 80, 88, 89, 97, 105, 113, 121}
+
+// EncodeDiff appends to buf the diff turning prev, a state of the same version, into b. See ApplyDiff.
+func (b *BeaconState) EncodeDiff(buf []byte, prev *BeaconState) ([]byte, error) {
+	if b.version != prev.version {
+		return nil, fmt.Errorf("%w: from version %d to %d", ErrDiffVersionMismatch, prev.version, b.version)
+	}
+	buf = append(buf, stateDiffFormat, byte(b.version))
+	buf = binary.AppendUvarint(buf, prev.slot)
+	schema, prevSchema := b.getSchema(), prev.getSchema()
+	var err error
+	for i, field := range schema {
+		if buf, err = appendFieldDiff(buf, i, field, prevSchema); err != nil {
+			return nil, fmt.Errorf("field %d: %w", i, err)
+		}
+	}
+	return buf, nil
+}
+
+// ApplyDiff turns b into the state the diff was encoded for, b being the state it was computed from: the diff
+// records its slot and version. The whole diff is decoded and checked before b is updated, so that b is left unchanged
+// by an error. Only the touched fields are rehashed, and the events are not emitted.
+func (b *BeaconState) ApplyDiff(diff []byte) error {
+	r := diffReader{buf: diff}
+	if format := r.byte(); r.err == nil && format != stateDiffFormat {
+		return fmt.Errorf("%w: unknown format %d", ErrMalformedDiff, format)
+	}
+	version, slot := clparams.StateVersion(r.byte()), r.uvarint()
+	if r.err != nil {
+		return r.err
+	}
+	if version != b.version || slot != b.slot {
+		return fmt.Errorf("%w: diff from version %d slot %d, state at version %d slot %d", ErrDiffBaseMismatch,
+			version, slot, b.version, b.slot)
+	}
+	schema := b.getSchema()
+	// participation may be patched from the previous value of the other participation field
+	participation := make(map[int][]byte)
+	for i, field := range schema {
+		if bits, ok := field.(*solid.BitList); ok {
+			participation[i] = common.Copy(bits.Bytes())
+		}
+	}
+	updates := make([]fieldUpdate, len(schema))
+	for i, field := range schema {
+		var err error
+		if updates[i], err = readFieldDiff(&r, i, field, participation); err != nil {
+			return fmt.Errorf("field %d: %w", i, err)
+		}
+	}
+	if len(r.buf) > 0 {
+		return fmt.Errorf("%w: %d trailing bytes", ErrMalformedDiff, len(r.buf))
+	}
+	// the new encodings are the only updates which may fail, they are decoded first and undone on an error
+	var decoded []int
+	for i, u := range updates {
+		if !u.changed || u.patch != nil {
+			continue
+		}
+		if err := decodeField(schema[i], u.enc, int(version)); err != nil {
+			for _, j := range append(decoded, i) {
+				if restoreErr := decodeField(schema[j], updates[j].prevEnc, int(version)); restoreErr != nil {
+					return fmt.Errorf("field %d: %w: %w, restoring field %d: %v", i, ErrMalformedDiff, err, j, restoreErr)
+				}
+			}
+			return fmt.Errorf("field %d: %w: %w", i, ErrMalformedDiff, err)
+		}
+		decoded = append(decoded, i)
+	}
+	for i, u := range updates {
+		if u.patch != nil {
+			u.patch()
+		}
+		if u.changed {
+			b.markLeaf(StateLeafIndex(i))
+		}
+	}
+	return nil
+}
+
+// fieldUpdate is the decoded diff of a changed field, either its new SSZ encoding or a patch checked against the
+// field, which cannot fail.
+type fieldUpdate struct {
+	changed      bool
+	enc, prevEnc []byte
+	patch        func()
+}
+
+func appendFieldDiff(buf []byte, i int, field any, prevSchema []any) ([]byte, error) {
+	switch field := field.(type) {
+	case *solid.ValidatorSet:
+		if prev := prevSchema[i].(*solid.ValidatorSet); field.Length() >= prev.Length() {
+			return appendValidatorsPatch(buf, field, prev), nil
+		}
+	case solid.Uint64SSZ:
+		return appendUint64Patch(buf, field, prevSchema[i].(solid.Uint64SSZ)), nil
+	case *solid.BitList:
+		return appendParticipationPatch(buf, i, field, prevSchema), nil
+	case solid.IterableSSZ[common.Hash]:
+		if prev := prevSchema[i].(solid.IterableSSZ[common.Hash]); field.Length() >= prev.Length() {
+			return appendHashesPatch(buf, field, prev), nil
+		}
+	}
+	enc, err := encodeField(nil, field)
+	if err != nil {
+		return nil, err
+	}
+	prevEnc, err := encodeField(nil, prevSchema[i])
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case bytes.Equal(enc, prevEnc):
+		return append(buf, fieldUnchanged), nil
+	case len(prevEnc) > 0 && bytes.HasPrefix(enc, prevEnc):
+		buf = binary.AppendUvarint(append(buf, fieldAppended), uint64(len(enc)-len(prevEnc)))
+		return append(buf, enc[len(prevEnc):]...), nil
+	default:
+		buf = binary.AppendUvarint(append(buf, fieldFull), uint64(len(enc)))
+		return append(buf, enc...), nil
+	}
+}
+
+func encodeField(buf []byte, field any) ([]byte, error) {
+	switch field := field.(type) {
+	case *uint64:
+		return binary.LittleEndian.AppendUint64(buf, *field), nil
+	case []byte:
+		return append(buf, field...), nil
+	case ssz.Marshaler:
+		return field.EncodeSSZ(buf)
+	}
+	return nil, fmt.Errorf("unsupported field type %T", field)
+}
+
+func decodeField(field any, enc []byte, version int) error {
+	switch field := field.(type) {
+	case *uint64:
+		if len(enc) != 8 {
+			return fmt.Errorf("uint64 of %d bytes", len(enc))
+		}
+		*field = binary.LittleEndian.Uint64(enc)
+		return nil
+	case []byte:
+		if len(enc) != len(field) {
+			return fmt.Errorf("%d bytes instead of %d", len(enc), len(field))
+		}
+		copy(field, enc)
+		return nil
+	case ssz.Unmarshaler:
+		return field.DecodeSSZ(enc, version)
+	}
+	return fmt.Errorf("unsupported field type %T", field)
+}
+
+// appendPatchHeader appends the header of a patch to a container of n elements, or marks the field unchanged.
+func appendPatchHeader(buf []byte, n, prevLength, changes int) ([]byte, bool) {
+	if n == prevLength && changes == 0 {
+		return append(buf, fieldUnchanged), false
+	}
+	buf = binary.AppendUvarint(append(buf, fieldPatched), uint64(n))
+	return binary.AppendUvarint(buf, uint64(changes)), true
+}
+
+// appendUint64Patch stores the changed elements as signed deltas, e.g. the balance rewards and penalties.
+func appendUint64Patch(buf []byte, field, prev solid.Uint64SSZ) []byte {
+	var changes []solid.IndexedChange
+	for _, c := range prev.Diff(field) {
+		if c.Index < min(prev.Length(), field.Length()) { // removed ones are cut by the length
+			changes = append(changes, c)
+		}
+	}
+	// the appended elements are all stored, zeros too, see readPatchHeader
+	for i := prev.Length(); i < field.Length(); i++ {
+		changes = append(changes, solid.IndexedChange{Index: i, New: field.Get(i)})
+	}
+	buf, patched := appendPatchHeader(buf, field.Length(), prev.Length(), len(changes))
+	if !patched {
+		return buf
+	}
+	next := 0
+	for _, c := range changes {
+		buf = binary.AppendUvarint(buf, uint64(c.Index-next))
+		buf = binary.AppendVarint(buf, int64(c.New-c.Old))
+		next = c.Index + 1
+	}
+	return buf
+}
+
+// appendValidatorsPatch stores the changed fields of the changed records, as a bitmask and their new values.
+func appendValidatorsPatch(buf []byte, field, prev *solid.ValidatorSet) []byte {
+	var changed []int
+	for i := 0; i < field.Length(); i++ {
+		if i >= prev.Length() || !bytes.Equal(field.Get(i), prev.Get(i)) {
+			changed = append(changed, i)
+		}
+	}
+	buf, patched := appendPatchHeader(buf, field.Length(), prev.Length(), len(changed))
+	if !patched {
+		return buf
+	}
+	zero := solid.NewValidator()
+	next := 0
+	for _, i := range changed {
+		record, prevRecord := field.Get(i), zero
+		if i < prev.Length() {
+			prevRecord = prev.Get(i)
+		}
+		buf = binary.AppendUvarint(buf, uint64(i-next))
+		maskAt := len(buf)
+		buf = append(buf, 0)
+		for f := 0; f < len(validatorFieldOffsets)-1; f++ {
+			from, to := validatorFieldOffsets[f], validatorFieldOffsets[f+1]
+			if !bytes.Equal(record[from:to], prevRecord[from:to]) {
+				buf[maskAt] |= 1 << f
+				buf = append(buf, record[from:to]...)
+			}
+		}
+		next = i + 1
+	}
+	return buf
+}
+
+// appendParticipationPatch stores the changed flags, relative to the previous flags of any participation field or to
+// zeros, whichever differs the least: at the epoch transition the current participation becomes the previous one,
+// and the current one is reset. The flags appended to the field are all stored.
+func appendParticipationPatch(buf []byte, i int, field *solid.BitList, prevSchema []any) []byte {
+	flags, prevLength := field.Bytes(), prevSchema[i].(*solid.BitList).Length()
+	stored := func(source []byte, j int) bool {
+		return flags[j] != getOrZero(source, j) || j >= prevLength
+	}
+	countChanges := func(source []byte) int {
+		changes := 0
+		for j := range flags {
+			if stored(source, j) {
+				changes++
+			}
+		}
+		return changes
+	}
+	// source 0 is zeros, source j+1 is the previous value of field j
+	source, sourceFlags, changes := 0, []byte(nil), countChanges(nil)
+	for j, prev := range prevSchema {
+		bits, ok := prev.(*solid.BitList)
+		if !ok {
+			continue
+		}
+		if c := countChanges(bits.Bytes()); c < changes || (c == changes && j == i) {
+			source, sourceFlags, changes = j+1, bits.Bytes(), c
+		}
+	}
+	if source == i+1 && len(sourceFlags) == len(flags) && changes == 0 {
+		return append(buf, fieldUnchanged)
+	}
+	buf = binary.AppendUvarint(append(buf, fieldPatched), uint64(source))
+	buf = binary.AppendUvarint(buf, uint64(len(flags)))
+	buf = binary.AppendUvarint(buf, uint64(changes))
+	next := 0
+	for j, flag := range flags {
+		if stored(sourceFlags, j) {
+			buf = append(binary.AppendUvarint(buf, uint64(j-next)), flag)
+			next = j + 1
+		}
+	}
+	return buf
+}
+
+// appendHashesPatch stores the changed roots, e.g. the block and state roots of the new slot.
+func appendHashesPatch(buf []byte, field, prev solid.IterableSSZ[common.Hash]) []byte {
+	var changed []int
+	for i := 0; i < field.Length(); i++ {
+		if i >= prev.Length() || field.Get(i) != prev.Get(i) {
+			changed = append(changed, i)
+		}
+	}
+	buf, patched := appendPatchHeader(buf, field.Length(), prev.Length(), len(changed))
+	if !patched {
+		return buf
+	}
+	next := 0
+	for _, i := range changed {
+		root := field.Get(i)
+		buf = append(binary.AppendUvarint(buf, uint64(i-next)), root[:]...)
+		next = i + 1
+	}
+	return buf
+}
+
+func getOrZero(b []byte, i int) byte {
+	if i >= len(b) {
+		return 0
+	}
+	return b[i]
+}
+
+// readFieldDiff reads the diff of the i-th field, without updating it.
+func readFieldDiff(r *diffReader, i int, field any, participation map[int][]byte) (fieldUpdate, error) {
+	encoding := r.byte()
+	if r.err != nil {
+		return fieldUpdate{}, r.err
+	}
+	var suffix []byte
+	switch encoding {
+	case fieldUnchanged:
+		return fieldUpdate{}, nil
+	case fieldFull, fieldAppended:
+		suffix = r.bytes(r.uvarint())
+		if r.err != nil {
+			return fieldUpdate{}, r.err
+		}
+	case fieldPatched:
+		var (
+			patch func()
+			err   error
+		)
+		switch field := field.(type) {
+		case *solid.ValidatorSet:
+			patch, err = readValidatorsPatch(r, field)
+		case solid.Uint64SSZ:
+			patch, err = readUint64Patch(r, field)
+		case *solid.BitList:
+			var flags []byte
+			if flags, err = readParticipationPatch(r, i, field, participation); err == nil {
+				return fieldUpdate{changed: true, enc: flags, prevEnc: participation[i]}, nil
+			}
+		case solid.IterableSSZ[common.Hash]:
+			patch, err = readHashesPatch(r, field)
+		default:
+			err = fmt.Errorf("%w: patch of a %T", ErrMalformedDiff, field)
+		}
+		return fieldUpdate{changed: true, patch: patch}, err
+	default:
+		return fieldUpdate{}, fmt.Errorf("%w: unknown field encoding %d", ErrMalformedDiff, encoding)
+	}
+	prevEnc, err := encodeField(nil, field)
+	if err != nil {
+		return fieldUpdate{}, err
+	}
+	enc := suffix
+	if encoding == fieldAppended {
+		enc = append(common.Copy(prevEnc), suffix...)
+	}
+	return fieldUpdate{changed: true, enc: enc, prevEnc: prevEnc}, nil
+}
+
+// readPatchHeader reads the length and the amount of changed elements of a patch to a container of the given length.
+// The elements past that length are all stored, so that the length is bounded by the bytes left in the diff.
+func readPatchHeader(r *diffReader, length, capacity int) (n, changes int, err error) {
+	n64, changes64 := r.uvarint(), r.uvarint()
+	if r.err != nil {
+		return 0, 0, r.err
+	}
+	if n64 > uint64(capacity) || changes64 > n64 || changes64 > uint64(len(r.buf)) || n64 > uint64(length)+changes64 {
+		return 0, 0, fmt.Errorf("%w: %d changes of %d elements, length %d, capacity %d, %d bytes left",
+			ErrMalformedDiff, changes64, n64, length, capacity, len(r.buf))
+	}
+	return int(n64), int(changes64), nil
+}
+
+// readIndex reads the index of the next changed element, next being the lowest valid one.
+func readIndex(r *diffReader, next, n int) (int, error) {
+	index := uint64(next) + r.uvarint()
+	if r.err != nil {
+		return 0, r.err
+	}
+	if index >= uint64(n) {
+		return 0, fmt.Errorf("%w: index %d out of %d elements", ErrMalformedDiff, index, n)
+	}
+	return int(index), nil
+}
+
+func readUint64Patch(r *diffReader, field solid.Uint64SSZ) (func(), error) {
+	n, changes, err := readPatchHeader(r, field.Length(), field.Cap())
+	if err != nil {
+		return nil, err
+	}
+	list, isList := field.(solid.Uint64ListSSZ)
+	if !isList && n != field.Length() {
+		return nil, fmt.Errorf("%w: vector of %d elements patched to %d", ErrMalformedDiff, field.Length(), n)
+	}
+	indices, deltas := make([]int, changes), make([]int64, changes)
+	for c, next := 0, 0; c < changes; c++ {
+		if indices[c], err = readIndex(r, next, n); err != nil {
+			return nil, err
+		}
+		if deltas[c] = r.varint(); r.err != nil {
+			return nil, r.err
+		}
+		next = indices[c] + 1
+	}
+	return func() {
+		if isList && n < list.Length() {
+			list.Truncate(n)
+		}
+		for field.Length() < n {
+			field.Append(0)
+		}
+		for c, index := range indices {
+			field.Set(index, field.Get(index)+uint64(deltas[c]))
+		}
+	}, nil
+}
+
+func readValidatorsPatch(r *diffReader, field *solid.ValidatorSet) (func(), error) {
+	n, changes, err := readPatchHeader(r, field.Length(), field.Cap())
+	if err != nil {
+		return nil, err
+	}
+	if n < field.Length() {
+		return nil, fmt.Errorf("%w: %d validators patched to %d", ErrMalformedDiff, field.Length(), n)
+	}
+	indices, records := make([]int, changes), make([]solid.Validator, changes)
+	for c, next := 0, 0; c < changes; c++ {
+		if indices[c], err = readIndex(r, next, n); err != nil {
+			return nil, err
+		}
+		records[c] = solid.NewValidator()
+		if indices[c] < field.Length() {
+			field.Get(indices[c]).CopyTo(records[c])
+		}
+		mask := r.byte()
+		for f := 0; f < len(validatorFieldOffsets)-1; f++ {
+			if mask&(1<<f) != 0 {
+				from, to := validatorFieldOffsets[f], validatorFieldOffsets[f+1]
+				copy(records[c][from:to], r.bytes(uint64(to-from)))
+			}
+		}
+		if r.err != nil {
+			return nil, r.err
+		}
+		next = indices[c] + 1
+	}
+	return func() {
+		for field.Length() < n {
+			field.Append(solid.NewValidator())
+		}
+		for c, index := range indices {
+			field.Set(index, records[c])
+		}
+	}, nil
+}
+
+// readParticipationPatch returns the new flags of the i-th field.
+func readParticipationPatch(r *diffReader, i int, field *solid.BitList, participation map[int][]byte) ([]byte, error) {
+	source := r.uvarint()
+	n, changes, err := readPatchHeader(r, len(participation[i]), field.Cap())
+	if err != nil {
+		return nil, err
+	}
+	var sourceFlags []byte
+	if source > 0 {
+		var ok bool
+		if sourceFlags, ok = participation[int(source)-1]; !ok {
+			return nil, fmt.Errorf("%w: participation patched from field %d", ErrMalformedDiff, source-1)
+		}
+	}
+	flags := make([]byte, n)
+	copy(flags, sourceFlags)
+	for c, next := 0, 0; c < changes; c++ {
+		index, err := readIndex(r, next, n)
+		if err != nil {
+			return nil, err
+		}
+		flags[index] = r.byte()
+		next = index + 1
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return flags, nil
+}
+
+func readHashesPatch(r *diffReader, field solid.IterableSSZ[common.Hash]) (func(), error) {
+	n, changes, err := readPatchHeader(r, field.Length(), field.Cap())
+	if err != nil {
+		return nil, err
+	}
+	if n < field.Length() || (field.Static() && n != field.Length()) {
+		return nil, fmt.Errorf("%w: %d roots patched to %d", ErrMalformedDiff, field.Length(), n)
+	}
+	indices, roots := make([]int, changes), make([]common.Hash, changes)
+	for c, next := 0, 0; c < changes; c++ {
+		if indices[c], err = readIndex(r, next, n); err != nil {
+			return nil, err
+		}
+		root := r.bytes(length.Hash)
+		if r.err != nil {
+			return nil, r.err
+		}
+		roots[c] = common.BytesToHash(root)
+		next = indices[c] + 1
+	}
+	return func() {
+		for field.Length() < n {
+			field.Append(common.Hash{})
+		}
+		for c, index := range indices {
+			field.Set(index, roots[c])
+		}
+	}, nil
+}
+
+// diffReader reads the primitives of a diff, the first error making the following reads no-ops.
+type diffReader struct {
+	buf []byte
+	err error
+}
+
+func (r *diffReader) fail(what string) {
+	if r.err == nil {
+		r.err = fmt.Errorf("%w: truncated %s", ErrMalformedDiff, what)
+	}
+	r.buf = nil
+}
+
+func (r *diffReader) byte() byte {
+	if len(r.buf) == 0 {
+		r.fail("byte")
+		return 0
+	}
+	b := r.buf[0]
+	r.buf = r.buf[1:]
+	return b
+}
+
+func (r *diffReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.fail("uvarint")
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *diffReader) varint() int64 {
+	v, n := binary.Varint(r.buf)
+	if n <= 0 {
+		r.fail("varint")
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *diffReader) bytes(n uint64) []byte {
+	if uint64(len(r.buf)) < n {
+		r.fail("bytes")
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
@@ -0,0 +1,125 @@
+package raw
+
+import (
+	"encoding/binary"
+	"testing"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon/cl/clparams"
+	"github.com/ledgerwatch/erigon/cl/cltypes"
+	"github.com/ledgerwatch/erigon/cl/cltypes/solid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateDiff(t *testing.T) {
+	prev := GetTestState()
+	_, err := prev.HashSSZ()
+	require.NoError(t, err)
+	state, err := prev.Copy()
+	require.NoError(t, err)
+
+	// a slot with an epoch transition
+	state.SetSlot(prev.Slot() + 1)
+	state.SetBlockRootAt(int(prev.Slot()%BlockRootsLength), libcommon.HexToHash("0x01"))
+	state.SetStateRootAt(int(prev.Slot()%StateRootsLength), libcommon.HexToHash("0x02"))
+	state.SetRandaoMixAt(3, libcommon.HexToHash("0x03"))
+	for i := 0; i < state.ValidatorLength(); i += 10 {
+		balance, err := state.ValidatorBalance(i)
+		require.NoError(t, err)
+		require.NoError(t, state.SetValidatorBalance(i, balance-1000))
+	}
+	state.SetExitEpochForValidatorAtIndex(5, 1000)
+	state.SetEffectiveBalanceForValidatorAtIndex(6, 31_000_000_000)
+	state.SetSlashingSegmentAt(7, 10)
+	state.ResetEpochParticipation()
+	state.SetEpochParticipationForValidatorIndex(true, 2, cltypes.ParticipationFlags(7))
+	state.AddValidator(solid.NewValidatorFromParameters([48]byte{1}, libcommon.Hash{2}, 32_000_000_000, false, 1, 2, 3, 4), 32_000_000_000)
+	state.AddCurrentEpochParticipationFlags(0)
+	state.AddPreviousEpochParticipationFlags(0)
+	state.AddInactivityScore(1)
+	state.AddHistoricalSummary(&cltypes.HistoricalSummary{BlockSummaryRoot: libcommon.Hash{4}})
+	state.SetFinalizedCheckpoint(solid.NewCheckpointFromParameters(libcommon.Hash{5}, 100))
+
+	diff, err := state.EncodeDiff(nil, prev)
+	require.NoError(t, err)
+	encoded, err := state.EncodeSSZ(nil)
+	require.NoError(t, err)
+	require.Less(t, len(diff), len(encoded)/20)
+
+	applied, err := prev.Copy()
+	require.NoError(t, err)
+	require.NoError(t, applied.ApplyDiff(diff))
+	appliedEncoded, err := applied.EncodeSSZ(nil)
+	require.NoError(t, err)
+	require.Equal(t, encoded, appliedEncoded)
+	expected, err := state.HashSSZ()
+	require.NoError(t, err)
+	root, err := applied.HashSSZ() // only the touched fields are rehashed
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+
+	// the diff applies to its base state only
+	require.ErrorIs(t, applied.ApplyDiff(diff), ErrDiffBaseMismatch)
+	truncated, err := prev.Copy()
+	require.NoError(t, err)
+	require.ErrorIs(t, truncated.ApplyDiff(diff[:len(diff)/2]), ErrMalformedDiff)
+	truncatedEncoded, err := truncated.EncodeSSZ(nil)
+	require.NoError(t, err)
+	prevEncoded, err := prev.EncodeSSZ(nil)
+	require.NoError(t, err)
+	require.Equal(t, prevEncoded, truncatedEncoded)
+}
+
+// encodeTestDiff encodes a diff to state changing the given fields only, by their encoded diffs.
+func encodeTestDiff(state *BeaconState, fields map[int][]byte) []byte {
+	diff := append([]byte{stateDiffFormat, byte(state.Version())}, binary.AppendUvarint(nil, state.Slot())...)
+	for i := range state.getSchema() {
+		if field, ok := fields[i]; ok {
+			diff = append(diff, field...)
+		} else {
+			diff = append(diff, fieldUnchanged)
+		}
+	}
+	return diff
+}
+
+func TestStateDiffMalformed(t *testing.T) {
+	state := GetTestState()
+	encoded, err := state.EncodeSSZ(nil)
+	require.NoError(t, err)
+	patch := func(n, changes uint64, tail ...byte) []byte {
+		return append(binary.AppendUvarint(binary.AppendUvarint([]byte{fieldPatched}, n), changes), tail...)
+	}
+	const fork, header, balances, slashings, previousParticipation = 3, 4, 12, 14, 15
+	for name, fields := range map[string]map[int][]byte{
+		"vector resized":          {slashings: patch(SlashingsLength-1, 0)},
+		"length past the diff":    {balances: patch(uint64(state.Balances().Length())+1_000_000, 0)},
+		"changes past the diff":   {balances: patch(uint64(state.Balances().Length()), 1_000_000)},
+		"participation past diff": {previousParticipation: append([]byte{fieldPatched, 0}, patch(1<<39, 1, 0, 7)[1:]...)},
+		"index out of the patch":  {balances: patch(uint64(state.Balances().Length()), 1, append(binary.AppendUvarint(nil, uint64(state.Balances().Length())), 2)...)},
+		"undecodable whole field": {fork: append([]byte{fieldFull, 16}, make([]byte, 16)...), header: {fieldFull, 1, 0}},
+		"patch of an unpatchable": {fork: patch(1, 0)},
+		"unknown field encoding":  {fork: {fieldPatched + 1}},
+	} {
+		require.ErrorIs(t, state.ApplyDiff(encodeTestDiff(state, fields)), ErrMalformedDiff, name)
+		// the state is left unchanged
+		afterEncoded, err := state.EncodeSSZ(nil)
+		require.NoError(t, err)
+		require.Equal(t, encoded, afterEncoded, name)
+	}
+}
+
+func TestStateDiffUnchanged(t *testing.T) {
+	state := GetTestState()
+	copied, err := state.Copy()
+	require.NoError(t, err)
+	diff, err := state.EncodeDiff(nil, copied)
+	require.NoError(t, err)
+	// format, version, slot and a byte per field
+	require.Len(t, diff, 2+len(binary.AppendUvarint(nil, state.Slot()))+len(state.getSchema()))
+	require.NoError(t, copied.ApplyDiff(diff))
+
+	copied.SetVersion(clparams.CapellaVersion)
+	_, err = state.EncodeDiff(nil, copied)
+	require.ErrorIs(t, err, ErrDiffVersionMismatch)
+}
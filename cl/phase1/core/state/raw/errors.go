@@ -5,4 +5,8 @@ import "errors"
 var (
 	// Error for missing validator
 	ErrInvalidValidatorIndex = errors.New("invalid validator index")
+	// Errors of the state diffs
+	ErrDiffVersionMismatch = errors.New("state diff between different versions")
+	ErrDiffBaseMismatch    = errors.New("state diff applied to another state")
+	ErrMalformedDiff       = errors.New("malformed state diff")
 )
@@ -6,6 +6,7 @@ import (
 	"github.com/ledgerwatch/erigon-lib/common"
 	libcommon "github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon/cl/clparams"
+	"github.com/ledgerwatch/erigon/cl/cltypes/solid"
 	"github.com/ledgerwatch/erigon/cl/merkle_tree"
 	"github.com/ledgerwatch/log/v3"
 )
@@ -64,6 +65,29 @@ func (b *BeaconState) FinalityRootBranch() ([][32]byte, error) {
 	return proof, nil
 }
 
+// ProofTree returns the merkle tree of the state, with the validator set expanded down to the validators at
+// validatorIndices, so that multiproofs such as state -> validators[i] -> pubkey can be generated from it.
+func (b *BeaconState) ProofTree(validatorIndices ...int) (*merkle_tree.ProofTree, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.computeDirtyLeaves(); err != nil {
+		return nil, err
+	}
+	chunks := make([][32]byte, len(b.leaves)/32)
+	for i := range chunks {
+		copy(chunks[i][:], b.leaves[i*32:])
+	}
+	t := merkle_tree.NewProofTree(chunks, uint64(len(chunks)))
+	validators, err := b.validators.ProofTree(validatorIndices...)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Attach(uint64(ValidatorsLeafIndex), validators); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
 func preparateRootsForHashing(roots []common.Hash) [][32]byte {
 	ret := make([][32]byte, len(roots))
 	for i := range roots {
@@ -135,6 +159,7 @@ func (b *BeaconState) computeDirtyLeaves() error {
 			return err
 		}
 		b.updateLeaf(HistoricalRootsLeafIndex, root)
+		solid.ObserveFieldHash("historical_roots", begin)
 	}
 	log.Trace("HistoricalRoots hashing", "elapsed", time.Since(begin))
 
@@ -170,6 +195,7 @@ func (b *BeaconState) computeDirtyLeaves() error {
 			return err
 		}
 		b.updateLeaf(ValidatorsLeafIndex, root)
+		solid.ObserveFieldHash("validators", begin)
 
 	}
 	log.Trace("ValidatorSet hashing", "elapsed", time.Since(begin))
@@ -182,6 +208,7 @@ func (b *BeaconState) computeDirtyLeaves() error {
 			return err
 		}
 		b.updateLeaf(BalancesLeafIndex, root)
+		solid.ObserveFieldHash("balances", begin)
 	}
 	log.Trace("Balances hashing", "elapsed", time.Since(begin))
 
@@ -193,6 +220,7 @@ func (b *BeaconState) computeDirtyLeaves() error {
 			return err
 		}
 		b.updateLeaf(RandaoMixesLeafIndex, root)
+		solid.ObserveFieldHash("randao_mixes", begin)
 	}
 	log.Trace("RandaoMixes hashing", "elapsed", time.Since(begin))
 
@@ -204,6 +232,7 @@ func (b *BeaconState) computeDirtyLeaves() error {
 			return err
 		}
 		b.updateLeaf(SlashingsLeafIndex, root)
+		solid.ObserveFieldHash("slashings", begin)
 	}
 	log.Trace("Slashings hashing", "elapsed", time.Since(begin))
 	// Field(15) and Field(16) are special due to the fact that they have different format in Phase0.
@@ -223,6 +252,7 @@ func (b *BeaconState) computeDirtyLeaves() error {
 		}
 
 		b.updateLeaf(PreviousEpochParticipationLeafIndex, root)
+		solid.ObserveFieldHash("previous_epoch_participation", begin)
 	}
 	log.Trace("PreviousEpochParticipation hashing", "elapsed", time.Since(begin))
 
@@ -241,6 +271,7 @@ func (b *BeaconState) computeDirtyLeaves() error {
 			return err
 		}
 		b.updateLeaf(CurrentEpochParticipationLeafIndex, root)
+		solid.ObserveFieldHash("current_epoch_participation", begin)
 	}
 	log.Trace("CurrentEpochParticipation hashing", "elapsed", time.Since(begin))
 
@@ -287,6 +318,7 @@ func (b *BeaconState) computeDirtyLeaves() error {
 			return err
 		}
 		b.updateLeaf(InactivityScoresLeafIndex, root)
+		solid.ObserveFieldHash("inactivity_scores", begin)
 	}
 	log.Trace("InactivityScores hashing", "elapsed", time.Since(begin))
 
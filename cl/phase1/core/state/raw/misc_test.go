@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon/cl/cltypes/solid"
+	"github.com/ledgerwatch/erigon/cl/merkle_tree"
 	"github.com/stretchr/testify/require"
 )
 
@@ -29,3 +31,39 @@ func TestGetters(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, common.Hash(root), common.HexToHash("0x9f1620db18ee06b9cbdf1b7fa9658701063d2bd05d54b09780f6c0a074b4ce5f"))
 }
+
+func TestStateProofTree(t *testing.T) {
+	state := GetTestState()
+	root, err := state.HashSSZ()
+	require.NoError(t, err)
+
+	tree, err := state.ProofTree(7)
+	require.NoError(t, err)
+	treeRoot, err := tree.Root()
+	require.NoError(t, err)
+	require.Equal(t, root, treeRoot)
+
+	validatorIndex := merkle_tree.ConcatGeneralizedIndices(tree.ChunkIndex(uint64(ValidatorsLeafIndex)), state.validators.ValidatorGeneralizedIndex(7))
+	indices := []uint64{
+		merkle_tree.ConcatGeneralizedIndices(validatorIndex, solid.ValidatorExitEpochGeneralizedIndex),
+		tree.ChunkIndex(uint64(SlotLeafIndex)),
+	}
+	leaves, proof, err := tree.MultiProof(indices...)
+	require.NoError(t, err)
+	require.Equal(t, merkle_tree.Uint64Root(state.validators.Get(7).ExitEpoch()), common.Hash(leaves[0]))
+	require.Equal(t, merkle_tree.Uint64Root(state.Slot()), common.Hash(leaves[1]))
+	require.True(t, merkle_tree.VerifyMerkleMultiproof(leaves, proof, indices, root))
+}
+
+func TestStateMemoryUsage(t *testing.T) {
+	state := GetTestState()
+	usage := state.MemoryUsage()
+	// at least the validators and balances buffers
+	require.Greater(t, usage, state.ValidatorLength()*(121+8))
+
+	unregister := solid.RegisterMemoryReporter("test_state", state)
+	require.Equal(t, usage, solid.MemoryUsageByName()["test_state"])
+	require.GreaterOrEqual(t, solid.MemoryUsage(), usage)
+	unregister()
+	require.NotContains(t, solid.MemoryUsageByName(), "test_state")
+}
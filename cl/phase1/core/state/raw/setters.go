@@ -371,7 +371,7 @@ func (b *BeaconState) SetInactivityScores(scores []uint64) {
 	b.markLeaf(InactivityScoresLeafIndex)
 }
 
-func (b *BeaconState) SetInactivityScoresRaw(scores solid.Uint64VectorSSZ) {
+func (b *BeaconState) SetInactivityScoresRaw(scores solid.Uint64ListSSZ) {
 	b.inactivityScores = scores
 	b.markLeaf(InactivityScoresLeafIndex)
 }
@@ -488,7 +488,7 @@ func (b *BeaconState) SetStateRoots(roots solid.HashVectorSSZ) {
 }
 
 // SetBlockRoots sets the block roots of the BeaconState.
-func (b *BeaconState) SetBalances(balances solid.Uint64VectorSSZ) {
+func (b *BeaconState) SetBalances(balances solid.Uint64ListSSZ) {
 	b.markLeaf(BalancesLeafIndex)
 	b.balances = balances
 }
@@ -157,6 +157,12 @@ func (b *BeaconState) Validators() *solid.ValidatorSet {
 	return b.validators
 }
 
+// MemoryUsage returns the bytes taken by the buffers of the state, it implements solid.MemoryReporter.
+func (b *BeaconState) MemoryUsage() int {
+	return cap(b.leaves) + solid.MemoryUsageOf(b.blockRoots, b.stateRoots, b.historicalRoots, b.validators, b.balances,
+		b.randaoMixes, b.slashings, b.previousEpochParticipation, b.currentEpochParticipation, b.inactivityScores)
+}
+
 func (b *BeaconState) SetEvents(events Events) {
 	b.events = events
 }
@@ -28,6 +28,14 @@ func (b *CachingBeaconState) DecodeSSZ(buf []byte, version int) error {
 	return b.InitBeaconState()
 }
 
+// ApplyDiff applies a diff encoded by raw.BeaconState.EncodeDiff, and recomputes the caches.
+func (b *CachingBeaconState) ApplyDiff(diff []byte) error {
+	if err := b.BeaconState.ApplyDiff(diff); err != nil {
+		return err
+	}
+	return b.InitBeaconState()
+}
+
 // SSZ size of the Beacon State
 func (b *CachingBeaconState) EncodingSizeSSZ() (size int) {
 	sz := b.BeaconState.EncodingSizeSSZ()
@@ -0,0 +1,114 @@
+// solidgen generates flat, byte-backed SSZ containers in the style of cl/cltypes/solid.
+//
+// A container is described by a JSON spec:
+//
+//	{
+//	  "name": "Checkpoint",
+//	  "package": "solid",
+//	  "fields": [
+//	    {"name": "Epoch", "type": "uint64"},
+//	    {"name": "BlockRoot", "type": "bytes", "size": 32, "json": "root"}
+//	  ]
+//	}
+//
+// and is usually wired through go:generate:
+//
+//	//go:generate go run github.com/ledgerwatch/erigon/cmd/solidgen -spec checkpoint.json -out gen_checkpoint.go -test gen_checkpoint_test.go
+//
+// Only static containers (uint64, bool and fixed-size bytes fields) are supported. A spec with a "limit" also gets a
+// list of at most limit containers, backed by a solid.Slice. With -test, round-trip tests of the generated code are
+// written as well.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"strings"
+	"text/template"
+)
+
+var (
+	specFlag = flag.String("spec", "", "path to the JSON container spec")
+	outFlag  = flag.String("out", "", "output file (default: stdout)")
+	testFlag = flag.String("test", "", "output file of the round-trip tests (default: none)")
+	pkgFlag  = flag.String("pkg", "", "override the package name of the spec")
+)
+
+func main() {
+	flag.Parse()
+	if *specFlag == "" {
+		fmt.Fprintln(os.Stderr, "Usage:", os.Args[0], "-spec <container.json> [-out <file.go>] [-test <file_test.go>] [-pkg <package>]")
+		os.Exit(2)
+	}
+	if err := run(*specFlag, *outFlag, *testFlag, *pkgFlag); err != nil {
+		fmt.Fprintln(os.Stderr, "solidgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(specPath, outPath, testPath, pkg string) error {
+	data, err := os.ReadFile(specPath)
+	if err != nil {
+		return err
+	}
+	spec, err := parseSpec(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", specPath, err)
+	}
+	if pkg != "" {
+		spec.Package = pkg
+	}
+	code, err := generate(spec)
+	if err != nil {
+		return err
+	}
+	if testPath != "" {
+		tests, err := generateTest(spec)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(testPath, tests, 0644); err != nil {
+			return err
+		}
+	}
+	if outPath == "" {
+		_, err = os.Stdout.Write(code)
+		return err
+	}
+	return os.WriteFile(outPath, code, 0644)
+}
+
+var funcs = template.FuncMap{
+	"lower": func(s string) string { return strings.ToLower(s[:1]) + s[1:] },
+	"mul":   func(a, b int) int { return a * b },
+}
+
+var (
+	tmpl     = template.Must(template.New("container").Funcs(funcs).Parse(containerTemplate))
+	testTmpl = template.Must(template.New("test").Funcs(funcs).Parse(testTemplate))
+)
+
+// generate renders the spec into gofmt-ed Go source.
+func generate(spec *Spec) ([]byte, error) {
+	return render(tmpl, spec)
+}
+
+// generateTest renders the round-trip tests of the code generated for the spec.
+func generateTest(spec *Spec) ([]byte, error) {
+	return render(testTmpl, spec)
+}
+
+func render(t *template.Template, spec *Spec) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, spec); err != nil {
+		return nil, err
+	}
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated code for %s is not valid Go: %w", spec.Name, err)
+	}
+	return code, nil
+}
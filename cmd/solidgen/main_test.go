@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const checkpointSpec = `{
+	"name": "Checkpoint",
+	"fields": [
+		{"name": "Epoch", "type": "uint64"},
+		{"name": "BlockRoot", "type": "bytes", "size": 32, "json": "root"}
+	]
+}`
+
+func TestParseSpecLayout(t *testing.T) {
+	spec, err := parseSpec([]byte(checkpointSpec))
+	require.NoError(t, err)
+	require.Equal(t, "solid", spec.Package)
+	require.Equal(t, 40, spec.Size())
+	require.Equal(t, 64, spec.HashBufferSize())
+	require.Equal(t, 8, spec.Fields[1].Offset)
+	require.Equal(t, "epoch", spec.Fields[0].JSON)
+	require.Equal(t, "root", spec.Fields[1].JSON)
+}
+
+func TestParseSpecErrors(t *testing.T) {
+	for _, tc := range []string{
+		`{"name": "lower", "fields": [{"name": "A", "type": "uint64"}]}`,
+		`{"name": "Empty"}`,
+		`{"name": "Dup", "fields": [{"name": "A", "type": "uint64"}, {"name": "A", "type": "bool"}]}`,
+		`{"name": "NoSize", "fields": [{"name": "A", "type": "bytes"}]}`,
+		`{"name": "Sized", "fields": [{"name": "A", "type": "uint64", "size": 8}]}`,
+		`{"name": "Unknown", "fields": [{"name": "A", "type": "uint32"}]}`,
+		`{"name": "Clash", "fields": [{"name": "C", "type": "uint64"}]}`,
+	} {
+		_, err := parseSpec([]byte(tc))
+		require.Error(t, err, tc)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	spec, err := parseSpec([]byte(`{
+		"name": "Validator",
+		"package": "solid",
+		"fields": [
+			{"name": "PublicKey", "type": "bytes", "size": 48},
+			{"name": "WithdrawalCredentials", "type": "bytes", "size": 32},
+			{"name": "EffectiveBalance", "type": "uint64"},
+			{"name": "Slashed", "type": "bool"},
+			{"name": "Graffiti", "type": "bytes", "size": 20}
+		]
+	}`))
+	require.NoError(t, err)
+	require.Equal(t, "va", spec.Receiver())
+	code, err := generate(spec)
+	require.NoError(t, err)
+	require.Contains(t, string(code), "const ValidatorSize = 109")
+	require.Contains(t, string(code), "func (va Validator) SetSlashed(v bool)")
+	require.Contains(t, string(code), `"github.com/ledgerwatch/erigon-lib/common/hexutility"`)
+}
+
+func TestGenerateList(t *testing.T) {
+	spec, err := parseSpec([]byte(`{
+		"name": "Checkpoint",
+		"package": "cltypes",
+		"limit": 1024,
+		"fields": [
+			{"name": "Epoch", "type": "uint64"},
+			{"name": "BlockRoot", "type": "bytes", "size": 32}
+		]
+	}`))
+	require.NoError(t, err)
+	code, err := generate(spec)
+	require.NoError(t, err)
+	require.Contains(t, string(code), "func NewCheckpointList() *solid.Slice[Checkpoint]")
+	require.Contains(t, string(code), `"github.com/ledgerwatch/erigon/cl/cltypes/solid"`)
+
+	tests, err := generateTest(spec)
+	require.NoError(t, err)
+	require.Contains(t, string(tests), "func TestCheckpointRoundTrip(t *testing.T)")
+	require.Contains(t, string(tests), "solid.ReferenceHashSSZ")
+}
+
+// TestGeneratedRoundTrip generates a container with a list into a temporary package of this module, then builds it
+// and runs its generated round-trip test.
+func TestGeneratedRoundTrip(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the generated code")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+	specPath := filepath.Join(t.TempDir(), "pending_deposit.json")
+	require.NoError(t, os.WriteFile(specPath, []byte(`{
+		"name": "PendingDeposit",
+		"limit": 64,
+		"fields": [
+			{"name": "PublicKey", "type": "bytes", "size": 48},
+			{"name": "Amount", "type": "uint64"},
+			{"name": "Slashed", "type": "bool"},
+			{"name": "Slot", "type": "uint64"}
+		]
+	}`), 0644))
+	dir, err := os.MkdirTemp(".", "_solidgen") // "_" keeps it out of ./... if the test is interrupted
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, run(specPath, filepath.Join(dir, "gen_pending_deposit.go"), filepath.Join(dir, "gen_pending_deposit_test.go"), "solidgentest"))
+
+	cmd := exec.Command(goBin, "test", "-count=1", "-v", "-run", "TestPendingDepositRoundTrip", "./"+filepath.Base(dir))
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+	require.Contains(t, string(out), "--- PASS: TestPendingDepositRoundTrip")
+}
+
+func TestSnakeCase(t *testing.T) {
+	require.Equal(t, "block_root", snakeCase("BlockRoot"))
+	require.Equal(t, "bls_key", snakeCase("BLSKey"))
+	require.Equal(t, "epoch", snakeCase("Epoch"))
+}
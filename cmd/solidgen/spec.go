@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"strings"
+	"unicode"
+
+	"github.com/ledgerwatch/erigon/cl/merkle_tree"
+)
+
+const (
+	kindUint64 = "uint64"
+	kindBool   = "bool"
+	kindBytes  = "bytes"
+)
+
+// Spec describes a fixed-size SSZ container which is going to be generated as a flat solid type.
+type Spec struct {
+	// Name is the name of the generated Go type.
+	Name string `json:"name"`
+	// Package is the package the generated file belongs to.
+	Package string `json:"package"`
+	// Fields are the container fields, in SSZ order.
+	Fields []*Field `json:"fields"`
+	// Limit is the limit of the list generated alongside the container, as a solid.Slice: none if 0.
+	Limit int `json:"limit,omitempty"`
+}
+
+// Field is a single static field of the container.
+type Field struct {
+	Name string `json:"name"`
+	// Type is one of uint64, bool or bytes.
+	Type string `json:"type"`
+	// Size is the fixed length of a bytes field.
+	Size int `json:"size,omitempty"`
+	// JSON overrides the json key of the field (snake_case of Name by default).
+	JSON string `json:"json,omitempty"`
+
+	// computed by validate
+	Offset int `json:"-"`
+	Leaf   int `json:"-"`
+}
+
+func parseSpec(data []byte) (*Spec, error) {
+	s := &Spec{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Spec) validate() error {
+	if !token.IsIdentifier(s.Name) || !token.IsExported(s.Name) {
+		return fmt.Errorf("container name %q must be an exported identifier", s.Name)
+	}
+	if s.Package == "" {
+		s.Package = "solid"
+	}
+	if !token.IsIdentifier(s.Package) {
+		return fmt.Errorf("package name %q is not a valid identifier", s.Package)
+	}
+	if len(s.Fields) == 0 {
+		return fmt.Errorf("container %s has no fields", s.Name)
+	}
+	if s.Limit < 0 {
+		return fmt.Errorf("container %s: negative list limit %d", s.Name, s.Limit)
+	}
+	seen := map[string]struct{}{}
+	offset := 0
+	for i, f := range s.Fields {
+		if !token.IsIdentifier(f.Name) || !token.IsExported(f.Name) {
+			return fmt.Errorf("field name %q must be an exported identifier", f.Name)
+		}
+		if _, ok := seen[f.Name]; ok {
+			return fmt.Errorf("duplicate field %s", f.Name)
+		}
+		seen[f.Name] = struct{}{}
+		switch f.Type {
+		case kindUint64, kindBool:
+			if f.Size != 0 {
+				return fmt.Errorf("field %s: size is only allowed for bytes fields", f.Name)
+			}
+		case kindBytes:
+			if f.Size <= 0 {
+				return fmt.Errorf("field %s: bytes fields need a positive size", f.Name)
+			}
+		default:
+			return fmt.Errorf("field %s: unsupported type %q", f.Name, f.Type)
+		}
+		if f.Param() == s.Receiver() {
+			return fmt.Errorf("field %s clashes with the method receiver name %q", f.Name, s.Receiver())
+		}
+		if f.JSON == "" {
+			f.JSON = snakeCase(f.Name)
+		}
+		f.Offset = offset
+		f.Leaf = i
+		offset += f.EncodingSize()
+	}
+	return nil
+}
+
+// Size is the SSZ encoding size of the container.
+func (s *Spec) Size() int {
+	last := s.Fields[len(s.Fields)-1]
+	return last.Offset + last.EncodingSize()
+}
+
+// HashBufferSize is the size of the flat leaves buffer used for merkleization.
+func (s *Spec) HashBufferSize() int {
+	return int(merkle_tree.NextPowerOfTwo(uint64(len(s.Fields)))) * 32
+}
+
+// reservedNames are the identifiers the generated methods use for parameters and locals.
+var reservedNames = map[string]struct{}{"o": {}, "v": {}, "i": {}, "dst": {}, "buf": {}, "err": {}, "tmp": {}, "other": {}, "leaves": {}}
+
+// Receiver is the receiver name used for the generated methods.
+func (s *Spec) Receiver() string {
+	r := strings.ToLower(s.Name[:1])
+	if _, ok := reservedNames[r]; ok && len(s.Name) > 1 {
+		r = strings.ToLower(s.Name[:2])
+	}
+	return r
+}
+
+func (s *Spec) has(pred func(f *Field) bool) bool {
+	for _, f := range s.Fields {
+		if pred(f) {
+			return true
+		}
+	}
+	return false
+}
+
+// Solid is the qualifier of the identifiers of the solid package, empty if the container is generated in it.
+func (s *Spec) Solid() string {
+	if s.Package == "solid" {
+		return ""
+	}
+	return "solid."
+}
+
+func (s *Spec) NeedsBinary() bool {
+	return s.has(func(f *Field) bool { return f.Type == kindUint64 })
+}
+
+func (s *Spec) NeedsLibcommon() bool {
+	return s.has(func(f *Field) bool { return f.Type == kindBytes && knownBytesType(f.Size) != "" })
+}
+
+func (s *Spec) NeedsHexutility() bool {
+	return s.has(func(f *Field) bool { return f.Type == kindBytes && knownBytesType(f.Size) == "" })
+}
+
+func (f *Field) EncodingSize() int {
+	switch f.Type {
+	case kindUint64:
+		return 8
+	case kindBool:
+		return 1
+	}
+	return f.Size
+}
+
+func (f *Field) End() int {
+	return f.Offset + f.EncodingSize()
+}
+
+// GoType is the type used by the accessors of the field.
+func (f *Field) GoType() string {
+	switch f.Type {
+	case kindUint64:
+		return "uint64"
+	case kindBool:
+		return "bool"
+	}
+	if t := knownBytesType(f.Size); t != "" {
+		return t
+	}
+	return fmt.Sprintf("[%d]byte", f.Size)
+}
+
+// JSONType is the type used for the field in the json representation.
+func (f *Field) JSONType() string {
+	if f.Type == kindBytes && knownBytesType(f.Size) == "" {
+		return "hexutility.Bytes"
+	}
+	return f.GoType()
+}
+
+func (f *Field) JSONTag() string {
+	if f.Type == kindUint64 {
+		return f.JSON + ",string"
+	}
+	return f.JSON
+}
+
+func (f *Field) IsUint64() bool { return f.Type == kindUint64 }
+func (f *Field) IsBool() bool   { return f.Type == kindBool }
+func (f *Field) IsBytes() bool  { return f.Type == kindBytes }
+
+// IsKnownBytes reports whether the field maps to one of the fixed byte types of erigon-lib/common.
+func (f *Field) IsKnownBytes() bool { return f.IsBytes() && knownBytesType(f.Size) != "" }
+
+// FitsLeaf reports whether the field is packed into a single 32-byte leaf, or needs its own merkleization.
+func (f *Field) FitsLeaf() bool { return f.EncodingSize() <= 32 }
+
+// Sample is a Go expression of a value of the field, distinct for every field, used by the generated tests.
+func (f *Field) Sample() string {
+	switch f.Type {
+	case kindUint64:
+		return fmt.Sprintf("uint64(%d)", (f.Leaf+1)*1_000_003)
+	case kindBool:
+		return "true"
+	}
+	return fmt.Sprintf("%s{%d, 0xff, %d}", f.GoType(), f.Leaf+1, f.Size)
+}
+
+// ReferenceLeaf is the argument of merkle_tree.HashTreeRoot for the sample of the field, held by v.
+func (f *Field) ReferenceLeaf(v string) string {
+	switch f.Type {
+	case kindUint64:
+		return v
+	case kindBool:
+		return "uint64(1)"
+	}
+	return v + "[:]"
+}
+
+// Param is the lowerCamelCase name used for the field in function parameters.
+func (f *Field) Param() string {
+	p := strings.ToLower(f.Name[:1]) + f.Name[1:]
+	if token.Lookup(p).IsKeyword() {
+		p += "_"
+	}
+	return p
+}
+
+func knownBytesType(size int) string {
+	switch size {
+	case 4:
+		return "libcommon.Bytes4"
+	case 32:
+		return "libcommon.Hash"
+	case 48:
+		return "libcommon.Bytes48"
+	case 64:
+		return "libcommon.Bytes64"
+	case 96:
+		return "libcommon.Bytes96"
+	}
+	return ""
+}
+
+func snakeCase(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// start a new word on lower->Upper and on the last capital of an acronym (e.g. "BLSKey" -> "bls_key")
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
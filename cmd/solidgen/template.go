@@ -0,0 +1,283 @@
+package main
+
+const containerTemplate = `// Code generated by solidgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"bytes"
+{{- if .NeedsBinary}}
+	"encoding/binary"
+{{- end}}
+	"encoding/json"
+{{- if .NeedsHexutility}}
+	"fmt"
+{{- end}}
+{{if .NeedsLibcommon}}
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+{{- end}}
+{{- if .NeedsHexutility}}
+	"github.com/ledgerwatch/erigon-lib/common/hexutility"
+{{- end}}
+	"github.com/ledgerwatch/erigon-lib/types/clonable"
+	"github.com/ledgerwatch/erigon-lib/types/ssz"
+{{- if and .Limit .Solid}}
+	"github.com/ledgerwatch/erigon/cl/cltypes/solid"
+{{- end}}
+	"github.com/ledgerwatch/erigon/cl/merkle_tree"
+)
+{{$r := .Receiver}}{{$t := .Name}}
+// {{$t}}Size is the size of the {{$t}} flat buffer, which is also its SSZ encoding size.
+const {{$t}}Size = {{.Size}}
+
+// {{$t}}HashBufferSize is the size of the leaves buffer needed by CopyHashBufferTo.
+const {{$t}}HashBufferSize = {{.HashBufferSize}}
+
+// {{$t}} is stored as a flat buffer, which is also its SSZ encoding.
+type {{$t}} []byte
+
+// New{{$t}} returns a new {{$t}} with the underlying byte slice initialized to zeros.
+func New{{$t}}() {{$t}} {
+	return make([]byte, {{$t}}Size)
+}
+
+// New{{$t}}FromParameters returns a new {{$t}} constructed from the given fields.
+func New{{$t}}FromParameters(
+{{- range .Fields}}
+	{{.Param}} {{.GoType}},
+{{- end}}
+) {{$t}} {
+	{{$r}} := New{{$t}}()
+{{- range .Fields}}
+	{{$r}}.Set{{.Name}}({{.Param}})
+{{- end}}
+	return {{$r}}
+}
+{{range .Fields}}
+{{- if .IsUint64}}
+func ({{$r}} {{$t}}) {{.Name}}() uint64 {
+	return binary.LittleEndian.Uint64({{$r}}[{{.Offset}}:{{.End}}])
+}
+
+func ({{$r}} {{$t}}) Set{{.Name}}(v uint64) {
+	binary.LittleEndian.PutUint64({{$r}}[{{.Offset}}:{{.End}}], v)
+}
+{{- else if .IsBool}}
+func ({{$r}} {{$t}}) {{.Name}}() bool {
+	return {{$r}}[{{.Offset}}] != 0
+}
+
+func ({{$r}} {{$t}}) Set{{.Name}}(v bool) {
+	if v {
+		{{$r}}[{{.Offset}}] = 1
+		return
+	}
+	{{$r}}[{{.Offset}}] = 0
+}
+{{- else}}
+func ({{$r}} {{$t}}) {{.Name}}() (o {{.GoType}}) {
+	copy(o[:], {{$r}}[{{.Offset}}:{{.End}}])
+	return
+}
+
+// Raw{{.Name}} returns the {{.Name}} bytes without copying them.
+func ({{$r}} {{$t}}) Raw{{.Name}}() []byte {
+	return {{$r}}[{{.Offset}}:{{.End}}]
+}
+
+func ({{$r}} {{$t}}) Set{{.Name}}(v {{.GoType}}) {
+	copy({{$r}}[{{.Offset}}:{{.End}}], v[:])
+}
+{{- end}}
+{{end}}
+// EncodingSizeSSZ returns the size of the {{$t}} object when encoded as SSZ.
+func ({{$t}}) EncodingSizeSSZ() int {
+	return {{$t}}Size
+}
+
+// DecodeSSZ decodes the {{$t}} object from SSZ-encoded data.
+func ({{$r}} {{$t}}) DecodeSSZ(buf []byte, _ int) error {
+	if len(buf) < {{$r}}.EncodingSizeSSZ() {
+		return ssz.ErrLowBufferSize
+	}
+	copy({{$r}}[:], buf)
+	return nil
+}
+
+// EncodeSSZ encodes the {{$t}} object into SSZ format.
+func ({{$r}} {{$t}}) EncodeSSZ(dst []byte) ([]byte, error) {
+	return append(dst, {{$r}}[:{{$t}}Size]...), nil
+}
+
+// Clone returns a new, empty {{$t}} object.
+func ({{$r}} {{$t}}) Clone() clonable.Clonable {
+	return New{{$t}}()
+}
+
+// Static always returns true, {{$t}} has a fixed size.
+func ({{$t}}) Static() bool {
+	return true
+}
+
+// CopyTo copies the {{$t}} into dst.
+func ({{$r}} {{$t}}) CopyTo(dst {{$t}}) {
+	copy(dst[:], {{$r}}[:])
+}
+
+// Copy returns a copy of the {{$t}} object.
+func ({{$r}} {{$t}}) Copy() {{$t}} {
+	o := New{{$t}}()
+	copy(o, {{$r}})
+	return o
+}
+
+// Equal checks if the {{$t}} object is equal to another {{$t}} object.
+func ({{$r}} {{$t}}) Equal(other {{$t}}) bool {
+	return bytes.Equal({{$r}}, other)
+}
+
+// CopyHashBufferTo writes the field roots of the {{$t}} into o, which must be at least {{$t}}HashBufferSize long.
+// Containers caching their own merkle trees use it to rehash only the elements they touched.
+func ({{$r}} {{$t}}) CopyHashBufferTo(o []byte) error {
+	for i := 0; i < {{$t}}HashBufferSize; i++ {
+		o[i] = 0
+	}
+{{- range .Fields}}
+{{- if .FitsLeaf}}
+	copy(o[{{mul .Leaf 32}}:], {{$r}}[{{.Offset}}:{{.End}}])
+{{- else}}
+	{{.Param}}Root, err := merkle_tree.BytesRoot({{$r}}[{{.Offset}}:{{.End}}])
+	if err != nil {
+		return err
+	}
+	copy(o[{{mul .Leaf 32}}:], {{.Param}}Root[:])
+{{- end}}
+{{- end}}
+	return nil
+}
+
+// HashSSZ returns the hash of the {{$t}} object when encoded as SSZ.
+func ({{$r}} {{$t}}) HashSSZ() (o [32]byte, err error) {
+	leaves := make([]byte, {{$t}}HashBufferSize)
+	if err = {{$r}}.CopyHashBufferTo(leaves); err != nil {
+		return
+	}
+	err = merkle_tree.MerkleRootFromFlatLeaves(leaves, o[:])
+	return
+}
+
+{{- if .Limit}}
+
+// {{$t}}Codec lays out {{$t}} elements in a {{.Solid}}Slice, Get returning copies.
+var {{$t}}Codec = {{.Solid}}ElementCodec[{{$t}}]{
+	Size:             {{$t}}Size,
+	Put:              func(dst []byte, v {{$t}}) { copy(dst, v) },
+	Get:              func(src []byte) {{$t}} { return {{$t}}(src).Copy() },
+	HashBufferSize:   {{$t}}HashBufferSize,
+	CopyHashBufferTo: func(dst, src []byte) error { return {{$t}}(src).CopyHashBufferTo(dst) },
+}
+
+// New{{$t}}List returns an empty list of at most {{.Limit}} {{$t}}s, with a memoized merkle tree.
+func New{{$t}}List() *{{.Solid}}Slice[{{$t}}] {
+	return {{.Solid}}NewSlice({{$t}}Codec, {{.Limit}})
+}
+{{- end}}
+
+type {{lower $t}}JSON struct {
+{{- range .Fields}}
+	{{.Name}} {{.JSONType}} ` + "`" + `json:"{{.JSONTag}}"` + "`" + `
+{{- end}}
+}
+
+func ({{$r}} {{$t}}) MarshalJSON() ([]byte, error) {
+	return json.Marshal({{lower $t}}JSON{
+{{- range .Fields}}
+{{- if and .IsBytes (not .IsKnownBytes)}}
+		{{.Name}}: {{$r}}.Raw{{.Name}}(),
+{{- else}}
+		{{.Name}}: {{$r}}.{{.Name}}(),
+{{- end}}
+{{- end}}
+	})
+}
+
+func ({{$r}} *{{$t}}) UnmarshalJSON(buf []byte) error {
+	var tmp {{lower $t}}JSON
+	if err := json.Unmarshal(buf, &tmp); err != nil {
+		return err
+	}
+	if len(*{{$r}}) < {{$t}}Size {
+		*{{$r}} = New{{$t}}()
+	}
+{{- range .Fields}}
+{{- if and .IsBytes (not .IsKnownBytes)}}
+	if len(tmp.{{.Name}}) != {{.Size}} {
+		return fmt.Errorf("{{$t}}: {{.JSON}} must be {{.Size}} bytes, got %d", len(tmp.{{.Name}}))
+	}
+	copy({{$r}}.Raw{{.Name}}(), tmp.{{.Name}})
+{{- else}}
+	{{$r}}.Set{{.Name}}(tmp.{{.Name}})
+{{- end}}
+{{- end}}
+	return nil
+}
+`
+
+const testTemplate = `// Code generated by solidgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"testing"
+{{if .NeedsLibcommon}}
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+{{- end}}
+{{- if and .Limit .Solid}}
+	"github.com/ledgerwatch/erigon/cl/cltypes/solid"
+{{- end}}
+	"github.com/ledgerwatch/erigon/cl/merkle_tree"
+	"github.com/stretchr/testify/require"
+)
+{{$r := .Receiver}}{{$t := .Name}}
+func Test{{$t}}RoundTrip(t *testing.T) {
+{{- range .Fields}}
+	sample{{.Name}} := {{.Sample}}
+{{- end}}
+	{{$r}} := New{{$t}}FromParameters({{range .Fields}}sample{{.Name}}, {{end}})
+{{- range .Fields}}
+	require.Equal(t, sample{{.Name}}, {{$r}}.{{.Name}}())
+{{- end}}
+
+	encoded, err := {{$r}}.EncodeSSZ(nil)
+	require.NoError(t, err)
+	require.Len(t, encoded, {{$t}}Size)
+	decoded := New{{$t}}()
+	require.NoError(t, decoded.DecodeSSZ(encoded, 0))
+	require.True(t, {{$r}}.Equal(decoded))
+
+	js, err := {{$r}}.MarshalJSON()
+	require.NoError(t, err)
+	var fromJSON {{$t}}
+	require.NoError(t, fromJSON.UnmarshalJSON(js))
+	require.True(t, {{$r}}.Equal(fromJSON))
+
+	root, err := {{$r}}.HashSSZ()
+	require.NoError(t, err)
+	expected, err := merkle_tree.HashTreeRoot({{range .Fields}}{{.ReferenceLeaf (print "sample" .Name)}}, {{end}})
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+{{- if .Limit}}
+
+	list := New{{$t}}List()
+	for i := 0; i < 10; i++ {
+		list.Append({{$r}})
+	}
+	list.Set(3, New{{$t}}())
+	listRoot, err := list.HashSSZ()
+	require.NoError(t, err)
+	expectedListRoot, err := {{.Solid}}ReferenceHashSSZ(list)
+	require.NoError(t, err)
+	require.Equal(t, expectedListRoot, listRoot)
+{{- end}}
+}
+`
@@ -52,7 +52,7 @@ func TLS(tlsCACert, tlsCertFile, tlsKeyFile string) (credentials.TransportCreden
 	}), nil
 }
 
-func NewServer(rateLimit uint32, creds credentials.TransportCredentials) *grpc.Server {
+func NewServer(rateLimit uint32, creds credentials.TransportCredentials, extraOpts ...grpc.ServerOption) *grpc.Server {
 	var (
 		streamInterceptors []grpc.StreamServerInterceptor
 		unaryInterceptors  []grpc.UnaryServerInterceptor
@@ -79,7 +79,7 @@ func NewServer(rateLimit uint32, creds credentials.TransportCredentials) *grpc.S
 		grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(unaryInterceptors...)),
 		grpc.Creds(creds),
 	}
-	grpcServer := grpc.NewServer(opts...)
+	grpcServer := grpc.NewServer(append(opts, extraOpts...)...)
 	reflection.Register(grpcServer)
 
 	//if metrics.Enabled {
@@ -17,21 +17,26 @@
 package mdbx_test
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"net"
 	"runtime"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/ledgerwatch/log/v3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/test/bufconn"
 
 	"github.com/ledgerwatch/erigon-lib/gointerfaces"
 	remote "github.com/ledgerwatch/erigon-lib/gointerfaces/remoteproto"
 	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/iter"
 	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
 	"github.com/ledgerwatch/erigon-lib/kv/remotedb"
@@ -333,6 +338,309 @@ func TestRemoteKvRange(t *testing.T) {
 	require.NoError(err)
 }
 
+func TestRemoteKvRangePagination(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fix me on win please")
+	}
+	logger := log.New()
+	ctx, writeDB := context.Background(), memdb.NewTestDB(t)
+	grpcServer, conn := grpc.NewServer(), bufconn.Listen(1024*1024)
+	go func() {
+		kvServer := remotedbserver.NewKvServer(ctx, writeDB, nil, nil, nil, logger)
+		kvServer.SetPayloadLimits(remotedbserver.DefaultMaxKeySize, 2)
+		remote.RegisterKVServer(grpcServer, kvServer)
+		if err := grpcServer.Serve(conn); err != nil {
+			log.Error("private RPC server fail", "err", err)
+		}
+	}()
+	defer grpcServer.Stop()
+
+	cc, err := grpc.Dial("", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, url string) (net.Conn, error) { return conn.Dial() }))
+	require.NoError(t, err)
+	db, err := remotedb.NewRemote(gointerfaces.VersionFromProto(remotedbserver.KvServiceAPIVersion), logger, remote.NewKVClient(cc)).Open()
+	require.NoError(t, err)
+
+	require := require.New(t)
+	require.NoError(writeDB.Update(ctx, func(tx kv.RwTx) error {
+		for i := byte(1); i <= 5; i++ {
+			require.NoError(tx.Put(kv.HeaderNumber, []byte{i}, []byte{i}))
+		}
+		return nil
+	}))
+
+	// pages of 2 items are stitched together by the client
+	require.NoError(db.View(ctx, func(tx kv.Tx) error {
+		keys := func(it iter.KV, err error) (res []byte) {
+			require.NoError(err)
+			for it.HasNext() {
+				k, _, err := it.Next()
+				require.NoError(err)
+				res = append(res, k...)
+			}
+			return res
+		}
+		require.Equal([]byte{1, 2, 3, 4, 5}, keys(tx.Range(kv.HeaderNumber, nil, nil)))
+		require.Equal([]byte{2, 3, 4}, keys(tx.Range(kv.HeaderNumber, []byte{2}, []byte{5})))
+		require.Equal([]byte{1, 2, 3}, keys(tx.RangeAscend(kv.HeaderNumber, nil, nil, 3)))
+		require.Equal([]byte{5, 4, 3, 2, 1}, keys(tx.RangeDescend(kv.HeaderNumber, nil, nil, -1)))
+		return nil
+	}))
+
+	// pages of a DupSort table end within the values of a key having more of them than a page holds
+	require.NoError(writeDB.Update(ctx, func(tx kv.RwTx) error {
+		for _, kv_ := range [][2]byte{{1, 1}, {1, 2}, {1, 3}, {2, 1}, {3, 1}, {3, 2}} {
+			require.NoError(tx.Put(kv.AccountChangeSet, kv_[:1], kv_[1:]))
+		}
+		require.NoError(tx.Put(kv.Code, []byte{1}, []byte{1}))
+		require.NoError(tx.Put(kv.Code, []byte{1, 1}, []byte{2}))
+		return tx.Put(kv.Code, []byte{2}, []byte{3})
+	}))
+	require.NoError(db.View(ctx, func(tx kv.Tx) error {
+		pairs := func(it iter.KV, err error) (res []string) {
+			require.NoError(err)
+			for it.HasNext() {
+				k, v, err := it.Next()
+				require.NoError(err)
+				res = append(res, fmt.Sprintf("%x:%x", k, v))
+			}
+			return res
+		}
+		all := []string{"01:01", "01:02", "01:03", "02:01", "03:01", "03:02"}
+		require.Equal(all, pairs(tx.Range(kv.AccountChangeSet, nil, nil)))
+		require.Equal(all[:3], pairs(tx.Prefix(kv.AccountChangeSet, []byte{1})))
+		require.Equal(all[:4], pairs(tx.RangeAscend(kv.AccountChangeSet, nil, nil, 4)))
+		require.Equal([]string{"03:02", "03:01", "02:01", "01:03", "01:02", "01:01"}, pairs(tx.RangeDescend(kv.AccountChangeSet, nil, nil, -1)))
+
+		n := 0
+		require.NoError(tx.ForEach(kv.AccountChangeSet, nil, func(_, _ []byte) error {
+			n++
+			return nil
+		}))
+		require.Equal(len(all), n)
+
+		// descending pages resume from a key without repeating the longer keys it prefixes
+		require.Equal([]string{"02:03", "0101:02", "01:01"}, pairs(tx.RangeDescend(kv.Code, nil, nil, -1)))
+		return nil
+	}))
+}
+
+// countingStream counts the messages sent by the client over the remote tx stream
+type countingStream struct {
+	grpc.ClientStream
+	sent *atomic.Int64
+}
+
+func (s countingStream) SendMsg(m any) error {
+	s.sent.Add(1)
+	return s.ClientStream.SendMsg(m)
+}
+
+func TestRemoteKvTxCache(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fix me on win please")
+	}
+	logger := log.New()
+	ctx, writeDB := context.Background(), memdb.NewTestDB(t)
+	grpcServer, conn := grpc.NewServer(), bufconn.Listen(1024*1024)
+	go func() {
+		kvServer := remotedbserver.NewKvServer(ctx, writeDB, nil, nil, nil, logger)
+		remote.RegisterKVServer(grpcServer, kvServer)
+		if err := grpcServer.Serve(conn); err != nil {
+			log.Error("private RPC server fail", "err", err)
+		}
+	}()
+	defer grpcServer.Stop()
+
+	sent := &atomic.Int64{}
+	cc, err := grpc.Dial("", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, url string) (net.Conn, error) { return conn.Dial() }),
+		grpc.WithStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			s, err := streamer(ctx, desc, cc, method, opts...)
+			if err != nil {
+				return nil, err
+			}
+			return countingStream{ClientStream: s, sent: sent}, nil
+		}))
+	require.NoError(t, err)
+	db, err := remotedb.NewRemote(gointerfaces.VersionFromProto(remotedbserver.KvServiceAPIVersion), logger, remote.NewKVClient(cc)).WithTxCache().Open()
+	require.NoError(t, err)
+
+	require := require.New(t)
+	require.NoError(writeDB.Update(ctx, func(tx kv.RwTx) error {
+		require.NoError(tx.Put(kv.HeaderNumber, []byte{1}, []byte{1}))
+		require.NoError(tx.Put(kv.HeaderNumber, []byte{2}, []byte{2}))
+		require.NoError(tx.Put(kv.HeaderNumber, []byte{3}, []byte{3}))
+		wc, err := tx.RwCursorDupSort(kv.PlainState)
+		require.NoError(err)
+		require.NoError(wc.Append([]byte{1}, []byte{1}))
+		require.NoError(wc.Append([]byte{1}, []byte{2}))
+		return nil
+	}))
+
+	require.NoError(db.View(ctx, func(tx kv.Tx) error {
+		c, err := tx.Cursor(kv.HeaderNumber)
+		require.NoError(err)
+		defer c.Close()
+		for k, _, err := c.First(); k != nil; k, _, err = c.Next() {
+			require.NoError(err)
+		}
+
+		// values streamed by the cursor are served from the cache
+		before := sent.Load()
+		for i := byte(1); i <= 3; i++ {
+			v, err := tx.GetOne(kv.HeaderNumber, []byte{i})
+			require.NoError(err)
+			require.Equal([]byte{i}, v)
+			has, err := tx.Has(kv.HeaderNumber, []byte{i})
+			require.NoError(err)
+			require.True(has)
+		}
+		require.Equal(before, sent.Load())
+
+		// absent keys are looked up once
+		v, err := tx.GetOne(kv.HeaderNumber, []byte{4})
+		require.NoError(err)
+		require.Nil(v)
+		before = sent.Load()
+		v, err = tx.GetOne(kv.HeaderNumber, []byte{4})
+		require.NoError(err)
+		require.Nil(v)
+		has, err := tx.Has(kv.HeaderNumber, []byte{4})
+		require.NoError(err)
+		require.False(has)
+		require.Equal(before, sent.Load())
+
+		// cursors over DupSort tables don't feed the cache: GetOne returns the first duplicate
+		dc, err := tx.Cursor(kv.PlainState)
+		require.NoError(err)
+		defer dc.Close()
+		_, _, err = dc.First()
+		require.NoError(err)
+		_, v, err = dc.Next()
+		require.NoError(err)
+		require.Equal([]byte{2}, v)
+		v, err = tx.GetOne(kv.PlainState, []byte{1})
+		require.NoError(err)
+		require.Equal([]byte{1}, v)
+		return nil
+	}))
+
+	// the cache doesn't outlive the tx
+	require.NoError(db.View(ctx, func(tx kv.Tx) error {
+		before := sent.Load()
+		v, err := tx.GetOne(kv.HeaderNumber, []byte{1})
+		require.NoError(err)
+		require.Equal([]byte{1}, v)
+		require.Less(before, sent.Load())
+		return nil
+	}))
+}
+
+func TestRemoteKvTxCacheRenew(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fix me on win please")
+	}
+	logger := log.New()
+	ctx, writeDB := context.Background(), memdb.NewTestDB(t)
+	grpcServer, conn := grpc.NewServer(), bufconn.Listen(1024*1024)
+	go func() {
+		kvServer := remotedbserver.NewKvServer(ctx, writeDB, nil, nil, nil, logger)
+		kvServer.SetTxAgePolicy(50*time.Millisecond, remotedbserver.TxAgeRenew)
+		remote.RegisterKVServer(grpcServer, kvServer)
+		if err := grpcServer.Serve(conn); err != nil {
+			log.Error("private RPC server fail", "err", err)
+		}
+	}()
+	defer grpcServer.Stop()
+	cc, err := grpc.Dial("", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, url string) (net.Conn, error) { return conn.Dial() }))
+	require.NoError(t, err)
+	db, err := remotedb.NewRemote(gointerfaces.VersionFromProto(remotedbserver.KvServiceAPIVersion), logger, remote.NewKVClient(cc)).WithTxCache().Open()
+	require.NoError(t, err)
+
+	require := require.New(t)
+	put := func(v byte) {
+		require.NoError(writeDB.Update(ctx, func(tx kv.RwTx) error { return tx.Put(kv.HeaderNumber, []byte{1}, []byte{v}) }))
+	}
+	put(1)
+	require.NoError(db.View(ctx, func(tx kv.Tx) error {
+		v, err := tx.GetOne(kv.HeaderNumber, []byte{1})
+		require.NoError(err)
+		require.Equal([]byte{1}, v)
+		viewID := tx.ViewID()
+
+		// the renewed tx sees the new value, and so does the cache
+		put(2)
+		time.Sleep(100 * time.Millisecond)
+		_, err = tx.GetOne(kv.HeaderNumber, []byte{2}) // server renews the tx on the next request
+		require.NoError(err)
+		require.NotEqual(viewID, tx.ViewID())
+		v, err = tx.GetOne(kv.HeaderNumber, []byte{1})
+		require.NoError(err)
+		require.Equal([]byte{2}, v)
+		return nil
+	}))
+}
+
+func TestRemoteKvTxConcurrent(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fix me on win please")
+	}
+	logger := log.New()
+	ctx, writeDB := context.Background(), memdb.NewTestDB(t)
+	grpcServer, conn := grpc.NewServer(), bufconn.Listen(1024*1024)
+	go func() {
+		remote.RegisterKVServer(grpcServer, remotedbserver.NewKvServer(ctx, writeDB, nil, nil, nil, logger))
+		if err := grpcServer.Serve(conn); err != nil {
+			log.Error("private RPC server fail", "err", err)
+		}
+	}()
+	defer grpcServer.Stop()
+	cc, err := grpc.Dial("", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, url string) (net.Conn, error) { return conn.Dial() }))
+	require.NoError(t, err)
+	db, err := remotedb.NewRemote(gointerfaces.VersionFromProto(remotedbserver.KvServiceAPIVersion), logger, remote.NewKVClient(cc)).WithTxCache().Open()
+	require.NoError(t, err)
+
+	require := require.New(t)
+	const keys = 100
+	require.NoError(writeDB.Update(ctx, func(tx kv.RwTx) error {
+		for i := 0; i < keys; i++ {
+			if err := tx.Put(kv.HeaderNumber, []byte{byte(i)}, []byte{byte(i)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+
+	// one tx, shared by goroutines scanning with their own cursors and looking keys up
+	tx, err := db.BeginRo(ctx)
+	require.NoError(err)
+	defer tx.Rollback()
+	g := errgroup.Group{}
+	for i := 0; i < 8; i++ {
+		g.Go(func() error {
+			c, err := tx.Cursor(kv.HeaderNumber)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+			n := 0
+			for k, v, err := c.First(); k != nil; k, v, err = c.Next() {
+				if err != nil {
+					return err
+				}
+				if got, err := tx.GetOne(kv.HeaderNumber, k); err != nil || !bytes.Equal(v, got) {
+					return fmt.Errorf("GetOne(%x) = %x, %v, expected %x", k, got, err, v)
+				}
+				n++
+			}
+			if n != keys {
+				return fmt.Errorf("scanned %d keys, expected %d", n, keys)
+			}
+			return nil
+		})
+	}
+	require.NoError(g.Wait())
+}
+
 func setupDatabases(t *testing.T, logger log.Logger, f mdbx.TableCfgFunc) (writeDBs []kv.RwDB, readDBs []kv.RwDB) {
 	t.Helper()
 	ctx := context.Background()
@@ -634,3 +942,143 @@ func testMultiCursor(t *testing.T, db kv.RwDB, bucket1, bucket2 string) {
 //		})
 //	}
 //}
+
+func TestRemoteKvWarmUp(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fix me on win please")
+	}
+	logger := log.New()
+	ctx, writeDB := context.Background(), memdb.NewTestDB(t)
+	grpcServer, conn := grpc.NewServer(), bufconn.Listen(1024*1024)
+	go func() {
+		kvServer := remotedbserver.NewKvServer(ctx, writeDB, nil, nil, nil, logger)
+		kvServer.SetPayloadLimits(remotedbserver.DefaultMaxKeySize, 3)
+		remote.RegisterKVServer(grpcServer, kvServer)
+		if err := grpcServer.Serve(conn); err != nil {
+			log.Error("private RPC server fail", "err", err)
+		}
+	}()
+	defer grpcServer.Stop()
+
+	cc, err := grpc.Dial("", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, url string) (net.Conn, error) { return conn.Dial() }))
+	require.NoError(t, err)
+	db, err := remotedb.NewRemote(gointerfaces.VersionFromProto(remotedbserver.KvServiceAPIVersion), logger, remote.NewKVClient(cc)).Open()
+	require.NoError(t, err)
+
+	require := require.New(t)
+	require.NoError(writeDB.Update(ctx, func(tx kv.RwTx) error {
+		for i := byte(0); i < 10; i++ {
+			require.NoError(tx.Put(kv.Headers, []byte{0, i}, []byte{i}))
+		}
+		wc, err := tx.RwCursorDupSort(kv.AccountChangeSet)
+		require.NoError(err)
+		for i := byte(1); i <= 6; i++ { // dup-sorted values of a key are not split across batches
+			require.NoError(wc.Append([]byte{1}, []byte{i}))
+		}
+		require.NoError(wc.Append([]byte{2}, []byte{1}))
+		return nil
+	}))
+
+	localDB := memdb.NewTestDB(t)
+	cfg := remotedb.DefaultWarmUpCfg(kv.Headers, kv.AccountChangeSet)
+	cfg.BatchSize = 4
+	require.NoError(remotedb.WarmUp(ctx, db, localDB, cfg, logger))
+	count := func(table string) (n int) {
+		require.NoError(localDB.View(ctx, func(tx kv.Tx) error {
+			return tx.ForEach(table, nil, func(_, _ []byte) error {
+				n++
+				return nil
+			})
+		}))
+		return n
+	}
+	require.Equal(10, count(kv.Headers))
+	require.Equal(7, count(kv.AccountChangeSet))
+
+	// an interrupted warm-up resumes from its progress, and completed tables are skipped
+	require.NoError(localDB.Update(ctx, func(tx kv.RwTx) error {
+		for i := byte(5); i < 10; i++ {
+			require.NoError(tx.Delete(kv.Headers, []byte{0, i}))
+		}
+		return tx.Put(kv.RemoteWarmUpProgress, []byte(kv.Headers), []byte{0, 0, 5})
+	}))
+	require.NoError(writeDB.Update(ctx, func(tx kv.RwTx) error {
+		return tx.Put(kv.AccountChangeSet, []byte{3}, []byte{1})
+	}))
+	require.NoError(remotedb.WarmUp(ctx, db, localDB, cfg, logger))
+	require.Equal(10, count(kv.Headers))
+	require.Equal(7, count(kv.AccountChangeSet))
+}
+
+func TestRemoteKvRecordReplay(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fix me on win please")
+	}
+	logger := log.New()
+	ctx, writeDB := context.Background(), memdb.NewTestDB(t)
+	grpcServer, conn := grpc.NewServer(), bufconn.Listen(1024*1024)
+	go func() {
+		kvServer := remotedbserver.NewKvServer(ctx, writeDB, nil, nil, nil, logger)
+		remote.RegisterKVServer(grpcServer, kvServer)
+		if err := grpcServer.Serve(conn); err != nil {
+			log.Error("private RPC server fail", "err", err)
+		}
+	}()
+	defer grpcServer.Stop()
+
+	cc, err := grpc.Dial("", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, url string) (net.Conn, error) { return conn.Dial() }))
+	require.NoError(t, err)
+	require := require.New(t)
+	require.NoError(writeDB.Update(ctx, func(tx kv.RwTx) error {
+		for i := byte(0); i < 5; i++ {
+			require.NoError(tx.Put(kv.Headers, []byte{i}, []byte{i, i}))
+		}
+		return nil
+	}))
+
+	session := func(db kv.RoDB) (pairs []string, err error) {
+		err = db.View(ctx, func(tx kv.Tx) error {
+			v, err := tx.GetOne(kv.Headers, []byte{2})
+			if err != nil {
+				return err
+			}
+			pairs = append(pairs, fmt.Sprintf("%x", v))
+			it, err := tx.Range(kv.Headers, []byte{1}, []byte{4})
+			if err != nil {
+				return err
+			}
+			for it.HasNext() {
+				k, v, err := it.Next()
+				if err != nil {
+					return err
+				}
+				pairs = append(pairs, fmt.Sprintf("%x:%x", k, v))
+			}
+			return nil
+		})
+		return pairs, err
+	}
+
+	var recording bytes.Buffer
+	recorder := remotedb.NewRecorder(remote.NewKVClient(cc), &recording)
+	db, err := remotedb.NewRemote(gointerfaces.VersionFromProto(remotedbserver.KvServiceAPIVersion), logger, recorder).Open()
+	require.NoError(err)
+	require.True(db.EnsureVersionCompatibility())
+	recorded, err := session(db)
+	require.NoError(err)
+	require.Equal([]string{"0202", "01:0101", "02:0202", "03:0303"}, recorded)
+	require.NoError(recorder.Flush())
+
+	// the recording is served without the server
+	grpcServer.Stop()
+	replay, err := remotedb.NewReplay(bytes.NewReader(recording.Bytes()))
+	require.NoError(err)
+	db, err = remotedb.NewRemote(gointerfaces.VersionFromProto(remotedbserver.KvServiceAPIVersion), logger, replay).Open()
+	require.NoError(err)
+	require.True(db.EnsureVersionCompatibility())
+	replayed, err := session(db)
+	require.NoError(err)
+	require.Equal(recorded, replayed)
+	_, err = session(db)
+	require.ErrorIs(err, remotedb.ErrNotRecorded)
+}
@@ -22,8 +22,10 @@ import (
 	"encoding/binary"
 	"fmt"
 	"runtime"
+	"sync"
 	"unsafe"
 
+	"github.com/c2h5oh/datasize"
 	"github.com/ledgerwatch/log/v3"
 	"golang.org/x/sync/semaphore"
 	"google.golang.org/grpc"
@@ -40,11 +42,13 @@ import (
 
 // generate the messages and services
 type remoteOpts struct {
-	remoteKV    remote.KVClient
-	log         log.Logger
-	bucketsCfg  kv.TableCfg
-	DialAddress string
-	version     gointerfaces.Version
+	remoteKV     remote.KVClient
+	log          log.Logger
+	bucketsCfg   kv.TableCfg
+	DialAddress  string
+	version      gointerfaces.Version
+	txCache      bool
+	txCacheLimit datasize.ByteSize
 }
 
 var _ kv.TemporalTx = (*tx)(nil)
@@ -57,16 +61,23 @@ type DB struct {
 	opts         remoteOpts
 }
 
+// tx - remote read transaction. It may be used from several goroutines at once, e.g. by a long analytical scan reading
+// several tables from one snapshot, as long as each cursor is used by one goroutine at a time and Rollback is called
+// once all of them are done. Requests of all cursors go through the one Tx stream, one request at a time.
 type tx struct {
 	stream             remote.KV_TxClient
 	ctx                context.Context
 	streamCancelFn     context.CancelFunc
 	db                 *DB
 	statelessCursors   map[string]kv.Cursor
+	statelessLock      sync.Mutex // held during GetOne and Has, which share statelessCursors
 	cursors            []*remoteCursor
 	streams            []kv.Closer
 	viewID, id         uint64
 	streamingRequested bool
+	cache              *txCache // nil if disabled
+
+	streamLock sync.Mutex // guards stream round trips, viewID and cursors
 }
 
 type remoteCursor struct {
@@ -76,6 +87,8 @@ type remoteCursor struct {
 	bucketName string
 	bucketCfg  kv.TableCfgItem
 	id         uint32
+	cacheable  bool   // pairs returned by the cursor go to the tx cache
+	viewID     uint64 // view of the tx when the last reply was received
 }
 
 type remoteCursorDupSort struct {
@@ -91,6 +104,23 @@ func (opts remoteOpts) WithBucketsConfig(c kv.TableCfg) remoteOpts {
 	return opts
 }
 
+// WithTxCache makes every tx keep the values it has read, so GetOne/Has of keys already
+// returned by a cursor or a previous lookup of the same tx are served without a round trip.
+// Values are kept until the end of the tx, so it's not meant for txs scanning big tables
+// unless the cache is bounded by WithTxCacheLimit.
+func (opts remoteOpts) WithTxCache() remoteOpts {
+	opts.txCache = true
+	return opts
+}
+
+// WithTxCacheLimit enables the tx cache and bounds it: least recently used values are evicted
+// once the cached keys and values of a tx take more than limit.
+func (opts remoteOpts) WithTxCacheLimit(limit datasize.ByteSize) remoteOpts {
+	opts.txCache = true
+	opts.txCacheLimit = limit
+	return opts
+}
+
 func (opts remoteOpts) Open() (*DB, error) {
 	targetSemCount := int64(runtime.GOMAXPROCS(-1)) - 1
 	if targetSemCount <= 1 {
@@ -182,7 +212,11 @@ func (db *DB) BeginRo(ctx context.Context) (txn kv.Tx, err error) {
 		streamCancelFn()
 		return nil, err
 	}
-	return &tx{ctx: ctx, db: db, stream: stream, streamCancelFn: streamCancelFn, viewID: msg.ViewId, id: msg.TxId}, nil
+	t := &tx{ctx: ctx, db: db, stream: stream, streamCancelFn: streamCancelFn, viewID: msg.ViewId, id: msg.TxId}
+	if db.opts.txCache {
+		t.cache = newTxCache(int(db.opts.txCacheLimit.Bytes()), msg.ViewId)
+	}
+	return t, nil
 }
 func (db *DB) BeginTemporalRo(ctx context.Context) (kv.TemporalTx, error) {
 	t, err := db.BeginRo(ctx) //nolint:gocritic
@@ -228,7 +262,11 @@ func (db *DB) UpdateNosync(ctx context.Context, f func(tx kv.RwTx) error) (err e
 	return fmt.Errorf("remote db provider doesn't support .UpdateNosync method")
 }
 
-func (tx *tx) ViewID() uint64  { return tx.viewID }
+func (tx *tx) ViewID() uint64 {
+	tx.streamLock.Lock()
+	defer tx.streamLock.Unlock()
+	return tx.viewID
+}
 func (tx *tx) CollectMetrics() {}
 func (tx *tx) IncrementSequence(bucket string, amount uint64) (uint64, error) {
 	panic("not implemented yet")
@@ -329,15 +367,35 @@ func (tx *tx) ForAmount(bucket string, fromPrefix []byte, amount uint32, walker
 }
 
 func (tx *tx) GetOne(bucket string, k []byte) (val []byte, err error) {
+	if tx.cache != nil {
+		if v, _, ok := tx.cache.get(bucket, k); ok {
+			return v, nil
+		}
+	}
+	tx.statelessLock.Lock()
+	defer tx.statelessLock.Unlock()
 	c, err := tx.statelessCursor(bucket)
 	if err != nil {
 		return nil, err
 	}
-	_, val, err = c.SeekExact(k)
-	return val, err
+	kk, val, err := c.SeekExact(k)
+	if err != nil {
+		return nil, err
+	}
+	if tx.cache != nil {
+		tx.cache.put(c.(*remoteCursor).viewID, bucket, k, val, kk != nil)
+	}
+	return val, nil
 }
 
 func (tx *tx) Has(bucket string, k []byte) (bool, error) {
+	if tx.cache != nil {
+		if _, found, ok := tx.cache.get(bucket, k); ok {
+			return found, nil
+		}
+	}
+	tx.statelessLock.Lock()
+	defer tx.statelessLock.Unlock()
 	c, err := tx.statelessCursor(bucket)
 	if err != nil {
 		return false, err
@@ -346,25 +404,44 @@ func (tx *tx) Has(bucket string, k []byte) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	return bytes.Equal(k, kk), nil
+	has := bytes.Equal(k, kk)
+	if tx.cache != nil && !has {
+		tx.cache.put(c.(*remoteCursor).viewID, bucket, k, nil, false)
+	}
+	return has, nil
+}
+
+// cacheable - pairs returned by cursors can be cached only for tables without duplicates:
+// for DupSort tables GetOne returns the first value of the key, not the one the cursor is at.
+func (tx *tx) cacheable(bucket string) bool {
+	if tx.cache == nil {
+		return false
+	}
+	cfg, ok := tx.db.buckets[bucket]
+	return ok && cfg.Flags&kv.DupSort == 0
+}
+
+// cached - puts the pair returned by a cursor to the tx cache
+func (c *remoteCursor) cached(k, v []byte, err error) ([]byte, []byte, error) {
+	if err == nil && k != nil && c.cacheable {
+		c.tx.cache.put(c.viewID, c.bucketName, k, v, true)
+	}
+	return k, v, err
 }
 
 func (c *remoteCursor) SeekExact(k []byte) (key, val []byte, err error) {
-	return c.seekExact(k)
+	return c.cached(c.seekExact(k))
 }
 
 func (c *remoteCursor) Prev() ([]byte, []byte, error) {
-	return c.prev()
+	return c.cached(c.prev())
 }
 
 func (tx *tx) Cursor(bucket string) (kv.Cursor, error) {
 	b := tx.db.buckets[bucket]
-	c := &remoteCursor{tx: tx, ctx: tx.ctx, bucketName: bucket, bucketCfg: b, stream: tx.stream}
-	tx.cursors = append(tx.cursors, c)
-	if err := c.stream.Send(&remote.Cursor{Op: remote.Op_OPEN, BucketName: c.bucketName}); err != nil {
-		return nil, err
-	}
-	msg, err := c.stream.Recv()
+	c := &remoteCursor{tx: tx, ctx: tx.ctx, bucketName: bucket, bucketCfg: b, stream: tx.stream, cacheable: tx.cacheable(bucket)}
+	tx.addCursor(c)
+	msg, err := c.roundTrip(&remote.Cursor{Op: remote.Op_OPEN, BucketName: c.bucketName})
 	if err != nil {
 		return nil, err
 	}
@@ -372,6 +449,12 @@ func (tx *tx) Cursor(bucket string) (kv.Cursor, error) {
 	return c, nil
 }
 
+func (tx *tx) addCursor(c *remoteCursor) {
+	tx.streamLock.Lock()
+	defer tx.streamLock.Unlock()
+	tx.cursors = append(tx.cursors, c)
+}
+
 func (tx *tx) ListBuckets() ([]string, error) {
 	return nil, fmt.Errorf("function ListBuckets is not implemented for remoteTx")
 }
@@ -382,10 +465,7 @@ func (tx *tx) ListBuckets() ([]string, error) {
 // func (c *remoteCursor) Delete(k []byte) error                   { panic("not supported") }
 // func (c *remoteCursor) DeleteCurrent() error                    { panic("not supported") }
 func (c *remoteCursor) Count() (uint64, error) {
-	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_COUNT}); err != nil {
-		return 0, err
-	}
-	pair, err := c.stream.Recv()
+	pair, err := c.roundTrip(&remote.Cursor{Cursor: c.id, Op: remote.Op_COUNT})
 	if err != nil {
 		return 0, err
 	}
@@ -394,10 +474,7 @@ func (c *remoteCursor) Count() (uint64, error) {
 }
 
 func (c *remoteCursor) first() ([]byte, []byte, error) {
-	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_FIRST}); err != nil {
-		return []byte{}, nil, err
-	}
-	pair, err := c.stream.Recv()
+	pair, err := c.roundTrip(&remote.Cursor{Cursor: c.id, Op: remote.Op_FIRST})
 	if err != nil {
 		return []byte{}, nil, err
 	}
@@ -405,140 +482,98 @@ func (c *remoteCursor) first() ([]byte, []byte, error) {
 }
 
 func (c *remoteCursor) next() ([]byte, []byte, error) {
-	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_NEXT}); err != nil {
-		return []byte{}, nil, err
-	}
-	pair, err := c.stream.Recv()
+	pair, err := c.roundTrip(&remote.Cursor{Cursor: c.id, Op: remote.Op_NEXT})
 	if err != nil {
 		return []byte{}, nil, err
 	}
 	return pair.K, pair.V, nil
 }
 func (c *remoteCursor) nextDup() ([]byte, []byte, error) {
-	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_NEXT_DUP}); err != nil {
-		return []byte{}, nil, err
-	}
-	pair, err := c.stream.Recv()
+	pair, err := c.roundTrip(&remote.Cursor{Cursor: c.id, Op: remote.Op_NEXT_DUP})
 	if err != nil {
 		return []byte{}, nil, err
 	}
 	return pair.K, pair.V, nil
 }
 func (c *remoteCursor) nextNoDup() ([]byte, []byte, error) {
-	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_NEXT_NO_DUP}); err != nil {
-		return []byte{}, nil, err
-	}
-	pair, err := c.stream.Recv()
+	pair, err := c.roundTrip(&remote.Cursor{Cursor: c.id, Op: remote.Op_NEXT_NO_DUP})
 	if err != nil {
 		return []byte{}, nil, err
 	}
 	return pair.K, pair.V, nil
 }
 func (c *remoteCursor) prev() ([]byte, []byte, error) {
-	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_PREV}); err != nil {
-		return []byte{}, nil, err
-	}
-	pair, err := c.stream.Recv()
+	pair, err := c.roundTrip(&remote.Cursor{Cursor: c.id, Op: remote.Op_PREV})
 	if err != nil {
 		return []byte{}, nil, err
 	}
 	return pair.K, pair.V, nil
 }
 func (c *remoteCursor) prevDup() ([]byte, []byte, error) {
-	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_PREV_DUP}); err != nil {
-		return []byte{}, nil, err
-	}
-	pair, err := c.stream.Recv()
+	pair, err := c.roundTrip(&remote.Cursor{Cursor: c.id, Op: remote.Op_PREV_DUP})
 	if err != nil {
 		return []byte{}, nil, err
 	}
 	return pair.K, pair.V, nil
 }
 func (c *remoteCursor) prevNoDup() ([]byte, []byte, error) {
-	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_PREV_NO_DUP}); err != nil {
-		return []byte{}, nil, err
-	}
-	pair, err := c.stream.Recv()
+	pair, err := c.roundTrip(&remote.Cursor{Cursor: c.id, Op: remote.Op_PREV_NO_DUP})
 	if err != nil {
 		return []byte{}, nil, err
 	}
 	return pair.K, pair.V, nil
 }
 func (c *remoteCursor) last() ([]byte, []byte, error) {
-	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_LAST}); err != nil {
-		return []byte{}, nil, err
-	}
-	pair, err := c.stream.Recv()
+	pair, err := c.roundTrip(&remote.Cursor{Cursor: c.id, Op: remote.Op_LAST})
 	if err != nil {
 		return []byte{}, nil, err
 	}
 	return pair.K, pair.V, nil
 }
 func (c *remoteCursor) setRange(k []byte) ([]byte, []byte, error) {
-	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_SEEK, K: k}); err != nil {
-		return []byte{}, nil, err
-	}
-	pair, err := c.stream.Recv()
+	pair, err := c.roundTrip(&remote.Cursor{Cursor: c.id, Op: remote.Op_SEEK, K: k})
 	if err != nil {
 		return []byte{}, nil, err
 	}
 	return pair.K, pair.V, nil
 }
 func (c *remoteCursor) seekExact(k []byte) ([]byte, []byte, error) {
-	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_SEEK_EXACT, K: k}); err != nil {
-		return []byte{}, nil, err
-	}
-	pair, err := c.stream.Recv()
+	pair, err := c.roundTrip(&remote.Cursor{Cursor: c.id, Op: remote.Op_SEEK_EXACT, K: k})
 	if err != nil {
 		return []byte{}, nil, err
 	}
 	return pair.K, pair.V, nil
 }
 func (c *remoteCursor) getBothRange(k, v []byte) ([]byte, error) {
-	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_SEEK_BOTH, K: k, V: v}); err != nil {
-		return nil, err
-	}
-	pair, err := c.stream.Recv()
+	pair, err := c.roundTrip(&remote.Cursor{Cursor: c.id, Op: remote.Op_SEEK_BOTH, K: k, V: v})
 	if err != nil {
 		return nil, err
 	}
 	return pair.V, nil
 }
 func (c *remoteCursor) seekBothExact(k, v []byte) ([]byte, []byte, error) {
-	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_SEEK_BOTH_EXACT, K: k, V: v}); err != nil {
-		return []byte{}, nil, err
-	}
-	pair, err := c.stream.Recv()
+	pair, err := c.roundTrip(&remote.Cursor{Cursor: c.id, Op: remote.Op_SEEK_BOTH_EXACT, K: k, V: v})
 	if err != nil {
 		return []byte{}, nil, err
 	}
 	return pair.K, pair.V, nil
 }
 func (c *remoteCursor) firstDup() ([]byte, error) {
-	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_FIRST_DUP}); err != nil {
-		return nil, err
-	}
-	pair, err := c.stream.Recv()
+	pair, err := c.roundTrip(&remote.Cursor{Cursor: c.id, Op: remote.Op_FIRST_DUP})
 	if err != nil {
 		return nil, err
 	}
 	return pair.V, nil
 }
 func (c *remoteCursor) lastDup() ([]byte, error) {
-	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_LAST_DUP}); err != nil {
-		return nil, err
-	}
-	pair, err := c.stream.Recv()
+	pair, err := c.roundTrip(&remote.Cursor{Cursor: c.id, Op: remote.Op_LAST_DUP})
 	if err != nil {
 		return nil, err
 	}
 	return pair.V, nil
 }
 func (c *remoteCursor) getCurrent() ([]byte, []byte, error) {
-	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_CURRENT}); err != nil {
-		return []byte{}, nil, err
-	}
-	pair, err := c.stream.Recv()
+	pair, err := c.roundTrip(&remote.Cursor{Cursor: c.id, Op: remote.Op_CURRENT})
 	if err != nil {
 		return []byte{}, nil, err
 	}
@@ -546,26 +581,26 @@ func (c *remoteCursor) getCurrent() ([]byte, []byte, error) {
 }
 
 func (c *remoteCursor) Current() ([]byte, []byte, error) {
-	return c.getCurrent()
+	return c.cached(c.getCurrent())
 }
 
 // Seek - doesn't start streaming (because much of code does only several .seekInFiles calls without reading sequence of data)
 // .Next() - does request streaming (if configured by user)
 func (c *remoteCursor) Seek(seek []byte) ([]byte, []byte, error) {
-	return c.setRange(seek)
+	return c.cached(c.setRange(seek))
 }
 
 func (c *remoteCursor) First() ([]byte, []byte, error) {
-	return c.first()
+	return c.cached(c.first())
 }
 
 // Next - returns next data element from server, request streaming (if configured by user)
 func (c *remoteCursor) Next() ([]byte, []byte, error) {
-	return c.next()
+	return c.cached(c.next())
 }
 
 func (c *remoteCursor) Last() ([]byte, []byte, error) {
-	return c.last()
+	return c.cached(c.last())
 }
 
 func (tx *tx) closeGrpcStream() {
@@ -605,21 +640,38 @@ func (c *remoteCursor) Close() {
 	if c.stream == nil {
 		return
 	}
-	st := c.stream
+	_, _ = c.roundTrip(&remote.Cursor{Cursor: c.id, Op: remote.Op_CLOSE})
 	c.stream = nil
-	if err := st.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_CLOSE}); err == nil {
-		_, _ = st.Recv()
+}
+
+// roundTrip - sends the request of the cursor and receives its reply, other goroutines of the tx waiting meanwhile.
+// A reply with a view id tells that server renewed the tx, see `remotedbserver.TxAgeRenew`: values cached from the
+// previous view are dropped.
+func (c *remoteCursor) roundTrip(req *remote.Cursor) (*remote.Pair, error) {
+	c.tx.streamLock.Lock()
+	defer c.tx.streamLock.Unlock()
+	if err := c.stream.Send(req); err != nil {
+		return nil, err
+	}
+	pair, err := c.stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	if pair.ViewId != 0 && pair.ViewId != c.tx.viewID {
+		c.tx.viewID = pair.ViewId
+		if c.tx.cache != nil {
+			c.tx.cache.clear(pair.ViewId)
+		}
 	}
+	c.viewID = c.tx.viewID
+	return pair, nil
 }
 
 func (tx *tx) CursorDupSort(bucket string) (kv.CursorDupSort, error) {
 	b := tx.db.buckets[bucket]
 	c := &remoteCursor{tx: tx, ctx: tx.ctx, bucketName: bucket, bucketCfg: b, stream: tx.stream}
-	tx.cursors = append(tx.cursors, c)
-	if err := c.stream.Send(&remote.Cursor{Op: remote.Op_OPEN_DUP_SORT, BucketName: c.bucketName}); err != nil {
-		return nil, err
-	}
-	msg, err := c.stream.Recv()
+	tx.addCursor(c)
+	msg, err := c.roundTrip(&remote.Cursor{Op: remote.Op_OPEN_DUP_SORT, BucketName: c.bucketName})
 	if err != nil {
 		return nil, err
 	}
@@ -667,7 +719,7 @@ func (tx *tx) DomainGet(name kv.Domain, k, k2 []byte) (v []byte, step uint64, er
 
 func (tx *tx) DomainRange(name kv.Domain, fromKey, toKey []byte, ts uint64, asc order.By, limit int) (it iter.KV, err error) {
 	return iter.PaginateKV(func(pageToken string) (keys, vals [][]byte, nextPageToken string, err error) {
-		reply, err := tx.db.remoteKV.DomainRange(tx.ctx, &remote.DomainRangeReq{TxId: tx.id, Table: name.String(), FromKey: fromKey, ToKey: toKey, Ts: ts, OrderAscend: bool(asc), Limit: int64(limit)})
+		reply, err := tx.db.remoteKV.DomainRange(tx.ctx, &remote.DomainRangeReq{TxId: tx.id, Table: name.String(), FromKey: fromKey, ToKey: toKey, Ts: ts, OrderAscend: bool(asc), Limit: int64(limit), PageToken: pageToken})
 		if err != nil {
 			return nil, nil, "", err
 		}
@@ -683,7 +735,7 @@ func (tx *tx) HistorySeek(name kv.History, k []byte, ts uint64) (v []byte, ok bo
 }
 func (tx *tx) HistoryRange(name kv.History, fromTs, toTs int, asc order.By, limit int) (it iter.KV, err error) {
 	return iter.PaginateKV(func(pageToken string) (keys, vals [][]byte, nextPageToken string, err error) {
-		reply, err := tx.db.remoteKV.HistoryRange(tx.ctx, &remote.HistoryRangeReq{TxId: tx.id, Table: string(name), FromTs: int64(fromTs), ToTs: int64(toTs), OrderAscend: bool(asc), Limit: int64(limit)})
+		reply, err := tx.db.remoteKV.HistoryRange(tx.ctx, &remote.HistoryRangeReq{TxId: tx.id, Table: string(name), FromTs: int64(fromTs), ToTs: int64(toTs), OrderAscend: bool(asc), Limit: int64(limit), PageToken: pageToken})
 		if err != nil {
 			return nil, nil, "", err
 		}
@@ -693,7 +745,7 @@ func (tx *tx) HistoryRange(name kv.History, fromTs, toTs int, asc order.By, limi
 
 func (tx *tx) IndexRange(name kv.InvertedIdx, k []byte, fromTs, toTs int, asc order.By, limit int) (timestamps iter.U64, err error) {
 	return iter.PaginateU64(func(pageToken string) (arr []uint64, nextPageToken string, err error) {
-		req := &remote.IndexRangeReq{TxId: tx.id, Table: string(name), K: k, FromTs: int64(fromTs), ToTs: int64(toTs), OrderAscend: bool(asc), Limit: int64(limit)}
+		req := &remote.IndexRangeReq{TxId: tx.id, Table: string(name), K: k, FromTs: int64(fromTs), ToTs: int64(toTs), OrderAscend: bool(asc), Limit: int64(limit), PageToken: pageToken}
 		reply, err := tx.db.remoteKV.IndexRange(tx.ctx, req)
 		if err != nil {
 			return nil, "", err
@@ -711,8 +763,13 @@ func (tx *tx) Prefix(table string, prefix []byte) (iter.KV, error) {
 }
 
 func (tx *tx) rangeOrderLimit(table string, fromPrefix, toPrefix []byte, asc order.By, limit int) (iter.KV, error) {
+	return tx.rangePaged(table, fromPrefix, toPrefix, asc, limit, 0)
+}
+
+// rangePaged is rangeOrderLimit requesting pages of pageSize pairs, 0 being the server default.
+func (tx *tx) rangePaged(table string, fromPrefix, toPrefix []byte, asc order.By, limit int, pageSize int32) (iter.KV, error) {
 	return iter.PaginateKV(func(pageToken string) (keys [][]byte, values [][]byte, nextPageToken string, err error) {
-		req := &remote.RangeReq{TxId: tx.id, Table: table, FromPrefix: fromPrefix, ToPrefix: toPrefix, OrderAscend: bool(asc), Limit: int64(limit)}
+		req := &remote.RangeReq{TxId: tx.id, Table: table, FromPrefix: fromPrefix, ToPrefix: toPrefix, OrderAscend: bool(asc), Limit: int64(limit), PageToken: pageToken, PageSize: pageSize}
 		reply, err := tx.db.remoteKV.Range(tx.ctx, req)
 		if err != nil {
 			return nil, nil, "", err
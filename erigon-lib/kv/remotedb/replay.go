@@ -0,0 +1,488 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package remotedb
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	remote "github.com/ledgerwatch/erigon-lib/gointerfaces/remoteproto"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/typesproto"
+)
+
+var (
+	ErrNotRecorded    = errors.New("not recorded")
+	ErrReplayMismatch = errors.New("request differs from the recorded one")
+)
+
+// kinds of recorded messages
+const (
+	recordUnary     byte = iota // request and reply of a unary call
+	recordTxOpen                // opening of a Tx stream
+	recordTxSend                // cursor op sent on a Tx stream
+	recordTxRecv                // pair received on a Tx stream
+	recordCloseSend             // end of the cursor ops of a Tx stream
+)
+
+// maxRecordSize bounds the bytes of a record, a recorded message being at most as big as the gRPC client receives, see
+// grpcutil.Connect. It keeps a corrupt length in a recording from allocating more.
+const maxRecordSize = 200 * 1024 * 1024
+
+// kinds of recorded errors
+const (
+	errNone byte = iota
+	errEOF
+	errStatus // gRPC code and message
+)
+
+type record struct {
+	kind   byte
+	stream uint64 // Tx stream of the message, numbered from 1 in opening order
+	method string // of unary calls
+	req    []byte
+	reply  []byte
+	err    error
+}
+
+func marshalMsg(m proto.Message) []byte {
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	if err != nil {
+		panic(err) // messages of the generated KV client always marshal
+	}
+	return b
+}
+
+func appendBytes(buf, b []byte) []byte {
+	return append(binary.AppendUvarint(buf, uint64(len(b))), b...)
+}
+
+func (r *record) encode(buf []byte) []byte {
+	buf = append(buf, r.kind)
+	buf = binary.AppendUvarint(buf, r.stream)
+	buf = appendBytes(buf, []byte(r.method))
+	buf = appendBytes(buf, r.req)
+	buf = appendBytes(buf, r.reply)
+	switch {
+	case r.err == nil:
+		return append(buf, errNone)
+	case errors.Is(r.err, io.EOF):
+		return append(buf, errEOF)
+	default:
+		st := grpcstatus.Convert(r.err)
+		return appendBytes(binary.AppendUvarint(append(buf, errStatus), uint64(st.Code())), []byte(st.Message()))
+	}
+}
+
+func (r *record) decode(rd *bufio.Reader) error {
+	var err error
+	if r.kind, err = rd.ReadByte(); err != nil {
+		return err // io.EOF at the end of the recording
+	}
+	if err = r.decodeFields(rd); errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF // the recording ends within the record
+	}
+	return err
+}
+
+func (r *record) decodeFields(rd *bufio.Reader) error {
+	var err error
+	if r.stream, err = binary.ReadUvarint(rd); err != nil {
+		return err
+	}
+	left := uint64(maxRecordSize)
+	readBytes := func() ([]byte, error) {
+		n, err := binary.ReadUvarint(rd)
+		if err != nil {
+			return nil, err
+		}
+		if n > left {
+			return nil, fmt.Errorf("field of %d bytes exceeds the record size limit of %d", n, maxRecordSize)
+		}
+		left -= n
+		// read as it comes rather than allocated upfront, a truncated recording can't allocate more than it holds
+		b, err := io.ReadAll(io.LimitReader(rd, int64(n)))
+		if err == nil && uint64(len(b)) != n {
+			err = io.ErrUnexpectedEOF
+		}
+		return b, err
+	}
+	method, err := readBytes()
+	if err != nil {
+		return err
+	}
+	r.method = string(method)
+	if r.req, err = readBytes(); err != nil {
+		return err
+	}
+	if r.reply, err = readBytes(); err != nil {
+		return err
+	}
+	errKind, err := rd.ReadByte()
+	if err != nil {
+		return err
+	}
+	switch errKind {
+	case errNone:
+		r.err = nil
+	case errEOF:
+		r.err = io.EOF
+	case errStatus:
+		code, err := binary.ReadUvarint(rd)
+		if err != nil {
+			return err
+		}
+		msg, err := readBytes()
+		if err != nil {
+			return err
+		}
+		r.err = grpcstatus.Error(codes.Code(code), string(msg))
+	default:
+		return fmt.Errorf("unknown error kind %d", errKind)
+	}
+	return nil
+}
+
+// Recorder is a remote.KVClient recording the traffic of the wrapped client, to be served later by a Replay without
+// the database, e.g. to develop or benchmark against the data of an archive node without running one:
+//
+//	rec := remotedb.NewRecorder(remote.NewKVClient(conn), file)
+//	db, err := remotedb.NewRemote(version, logger, rec).Open()
+//	...
+//	err = rec.Flush()
+//
+// StateChanges streams are passed through without being recorded.
+type Recorder struct {
+	remoteKV remote.KVClient
+
+	mu      sync.Mutex
+	w       *bufio.Writer
+	buf     []byte
+	err     error // first write error, returned by Flush
+	streams uint64
+}
+
+var _ remote.KVClient = (*Recorder)(nil)
+
+func NewRecorder(remoteKV remote.KVClient, w io.Writer) *Recorder {
+	return &Recorder{remoteKV: remoteKV, w: bufio.NewWriter(w)}
+}
+
+func (r *Recorder) write(rec record) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	r.buf = rec.encode(r.buf[:0])
+	_, r.err = r.w.Write(r.buf)
+}
+
+// Flush writes the buffered records, and returns the first error met while recording.
+func (r *Recorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	return r.w.Flush()
+}
+
+func recordCall[Req, Reply proto.Message](r *Recorder, method string, req Req, call func() (Reply, error)) (Reply, error) {
+	reply, err := call()
+	rec := record{kind: recordUnary, method: method, req: marshalMsg(req), err: err}
+	if err == nil {
+		rec.reply = marshalMsg(reply)
+	}
+	r.write(rec)
+	return reply, err
+}
+
+func (r *Recorder) Version(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*typesproto.VersionReply, error) {
+	return recordCall(r, "Version", in, func() (*typesproto.VersionReply, error) { return r.remoteKV.Version(ctx, in, opts...) })
+}
+
+func (r *Recorder) Snapshots(ctx context.Context, in *remote.SnapshotsRequest, opts ...grpc.CallOption) (*remote.SnapshotsReply, error) {
+	return recordCall(r, "Snapshots", in, func() (*remote.SnapshotsReply, error) { return r.remoteKV.Snapshots(ctx, in, opts...) })
+}
+
+func (r *Recorder) Range(ctx context.Context, in *remote.RangeReq, opts ...grpc.CallOption) (*remote.Pairs, error) {
+	return recordCall(r, "Range", in, func() (*remote.Pairs, error) { return r.remoteKV.Range(ctx, in, opts...) })
+}
+
+func (r *Recorder) DomainGet(ctx context.Context, in *remote.DomainGetReq, opts ...grpc.CallOption) (*remote.DomainGetReply, error) {
+	return recordCall(r, "DomainGet", in, func() (*remote.DomainGetReply, error) { return r.remoteKV.DomainGet(ctx, in, opts...) })
+}
+
+func (r *Recorder) HistorySeek(ctx context.Context, in *remote.HistorySeekReq, opts ...grpc.CallOption) (*remote.HistorySeekReply, error) {
+	return recordCall(r, "HistorySeek", in, func() (*remote.HistorySeekReply, error) { return r.remoteKV.HistorySeek(ctx, in, opts...) })
+}
+
+func (r *Recorder) IndexRange(ctx context.Context, in *remote.IndexRangeReq, opts ...grpc.CallOption) (*remote.IndexRangeReply, error) {
+	return recordCall(r, "IndexRange", in, func() (*remote.IndexRangeReply, error) { return r.remoteKV.IndexRange(ctx, in, opts...) })
+}
+
+func (r *Recorder) HistoryRange(ctx context.Context, in *remote.HistoryRangeReq, opts ...grpc.CallOption) (*remote.Pairs, error) {
+	return recordCall(r, "HistoryRange", in, func() (*remote.Pairs, error) { return r.remoteKV.HistoryRange(ctx, in, opts...) })
+}
+
+func (r *Recorder) DomainRange(ctx context.Context, in *remote.DomainRangeReq, opts ...grpc.CallOption) (*remote.Pairs, error) {
+	return recordCall(r, "DomainRange", in, func() (*remote.Pairs, error) { return r.remoteKV.DomainRange(ctx, in, opts...) })
+}
+
+func (r *Recorder) StateChanges(ctx context.Context, in *remote.StateChangeRequest, opts ...grpc.CallOption) (remote.KV_StateChangesClient, error) {
+	return r.remoteKV.StateChanges(ctx, in, opts...)
+}
+
+func (r *Recorder) Tx(ctx context.Context, opts ...grpc.CallOption) (remote.KV_TxClient, error) {
+	stream, err := r.remoteKV.Tx(ctx, opts...)
+	r.mu.Lock()
+	r.streams++
+	id := r.streams
+	r.mu.Unlock()
+	r.write(record{kind: recordTxOpen, stream: id, err: err})
+	if err != nil {
+		return nil, err
+	}
+	return &recordedTx{KV_TxClient: stream, r: r, id: id}, nil
+}
+
+type recordedTx struct {
+	remote.KV_TxClient
+	r  *Recorder
+	id uint64
+}
+
+func (s *recordedTx) Send(c *remote.Cursor) error {
+	err := s.KV_TxClient.Send(c)
+	s.r.write(record{kind: recordTxSend, stream: s.id, req: marshalMsg(c), err: err})
+	return err
+}
+
+func (s *recordedTx) Recv() (*remote.Pair, error) {
+	pair, err := s.KV_TxClient.Recv()
+	rec := record{kind: recordTxRecv, stream: s.id, err: err}
+	if err == nil {
+		rec.reply = marshalMsg(pair)
+	}
+	s.r.write(rec)
+	return pair, err
+}
+
+func (s *recordedTx) CloseSend() error {
+	err := s.KV_TxClient.CloseSend()
+	s.r.write(record{kind: recordCloseSend, stream: s.id, err: err})
+	return err
+}
+
+// Replay is a remote.KVClient serving the traffic recorded by a Recorder. Replies of unary calls are matched by
+// request, and served in the recorded order, the last one being served again once they are exhausted. Tx streams are
+// served in the order they were opened, each replaying its recorded ops: they must be sent as recorded, or
+// ErrReplayMismatch is returned. Requests which weren't recorded fail with ErrNotRecorded.
+type Replay struct {
+	mu     sync.Mutex
+	unary  map[string][]record // by method and request
+	txs    [][]record          // by stream
+	nextTx int
+}
+
+var _ remote.KVClient = (*Replay)(nil)
+
+// NewReplay reads the records written by a Recorder.
+func NewReplay(r io.Reader) (*Replay, error) {
+	replay, rd := &Replay{unary: map[string][]record{}}, bufio.NewReader(r)
+	for {
+		var rec record
+		if err := rec.decode(rd); err != nil {
+			if errors.Is(err, io.EOF) && rd.Buffered() == 0 {
+				return replay, nil
+			}
+			return nil, fmt.Errorf("malformed recording: %w", err)
+		}
+		if rec.kind == recordUnary {
+			key := rec.method + string(rec.req)
+			replay.unary[key] = append(replay.unary[key], rec)
+			continue
+		}
+		if rec.stream == 0 || rec.stream > uint64(len(replay.txs))+1 {
+			return nil, fmt.Errorf("malformed recording: unknown stream %d", rec.stream)
+		}
+		if rec.stream > uint64(len(replay.txs)) {
+			replay.txs = append(replay.txs, nil)
+		}
+		replay.txs[rec.stream-1] = append(replay.txs[rec.stream-1], rec)
+	}
+}
+
+func replayCall[Reply proto.Message](r *Replay, method string, req proto.Message, reply Reply) (Reply, error) {
+	var zero Reply
+	key := method + string(marshalMsg(req))
+	r.mu.Lock()
+	recs := r.unary[key]
+	if len(recs) > 1 {
+		r.unary[key] = recs[1:]
+	}
+	r.mu.Unlock()
+	if len(recs) == 0 {
+		return zero, fmt.Errorf("%w: %s", ErrNotRecorded, method)
+	}
+	if recs[0].err != nil {
+		return zero, recs[0].err
+	}
+	if err := proto.Unmarshal(recs[0].reply, reply); err != nil {
+		return zero, err
+	}
+	return reply, nil
+}
+
+func (r *Replay) Version(_ context.Context, in *emptypb.Empty, _ ...grpc.CallOption) (*typesproto.VersionReply, error) {
+	return replayCall(r, "Version", in, &typesproto.VersionReply{})
+}
+
+func (r *Replay) Snapshots(_ context.Context, in *remote.SnapshotsRequest, _ ...grpc.CallOption) (*remote.SnapshotsReply, error) {
+	return replayCall(r, "Snapshots", in, &remote.SnapshotsReply{})
+}
+
+func (r *Replay) Range(_ context.Context, in *remote.RangeReq, _ ...grpc.CallOption) (*remote.Pairs, error) {
+	return replayCall(r, "Range", in, &remote.Pairs{})
+}
+
+func (r *Replay) DomainGet(_ context.Context, in *remote.DomainGetReq, _ ...grpc.CallOption) (*remote.DomainGetReply, error) {
+	return replayCall(r, "DomainGet", in, &remote.DomainGetReply{})
+}
+
+func (r *Replay) HistorySeek(_ context.Context, in *remote.HistorySeekReq, _ ...grpc.CallOption) (*remote.HistorySeekReply, error) {
+	return replayCall(r, "HistorySeek", in, &remote.HistorySeekReply{})
+}
+
+func (r *Replay) IndexRange(_ context.Context, in *remote.IndexRangeReq, _ ...grpc.CallOption) (*remote.IndexRangeReply, error) {
+	return replayCall(r, "IndexRange", in, &remote.IndexRangeReply{})
+}
+
+func (r *Replay) HistoryRange(_ context.Context, in *remote.HistoryRangeReq, _ ...grpc.CallOption) (*remote.Pairs, error) {
+	return replayCall(r, "HistoryRange", in, &remote.Pairs{})
+}
+
+func (r *Replay) DomainRange(_ context.Context, in *remote.DomainRangeReq, _ ...grpc.CallOption) (*remote.Pairs, error) {
+	return replayCall(r, "DomainRange", in, &remote.Pairs{})
+}
+
+func (r *Replay) StateChanges(context.Context, *remote.StateChangeRequest, ...grpc.CallOption) (remote.KV_StateChangesClient, error) {
+	return nil, fmt.Errorf("%w: StateChanges", ErrNotRecorded)
+}
+
+func (r *Replay) Tx(ctx context.Context, _ ...grpc.CallOption) (remote.KV_TxClient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.nextTx == len(r.txs) {
+		return nil, fmt.Errorf("%w: Tx", ErrNotRecorded)
+	}
+	recs := r.txs[r.nextTx]
+	r.nextTx++
+	if recs[0].err != nil {
+		return nil, recs[0].err
+	}
+	return &replayedTx{ctx: ctx, recs: recs[1:]}, nil
+}
+
+type replayedTx struct {
+	ctx  context.Context
+	recs []record
+}
+
+func (s *replayedTx) next(kind byte) (record, error) {
+	if len(s.recs) == 0 || s.recs[0].kind != kind {
+		return record{}, ErrReplayMismatch
+	}
+	rec := s.recs[0]
+	s.recs = s.recs[1:]
+	return rec, nil
+}
+
+func (s *replayedTx) Send(c *remote.Cursor) error {
+	if len(s.recs) > 0 && s.recs[0].kind == recordTxSend && !bytes.Equal(s.recs[0].req, marshalMsg(c)) {
+		return fmt.Errorf("%w: cursor op %s", ErrReplayMismatch, c.Op)
+	}
+	rec, err := s.next(recordTxSend)
+	if err != nil {
+		return err
+	}
+	return rec.err
+}
+
+func (s *replayedTx) Recv() (*remote.Pair, error) {
+	if err := s.ctx.Err(); err != nil {
+		return nil, grpcstatus.FromContextError(err).Err()
+	}
+	rec, err := s.next(recordTxRecv)
+	if err != nil {
+		return nil, err
+	}
+	if rec.err != nil {
+		return nil, rec.err
+	}
+	pair := &remote.Pair{}
+	if err := proto.Unmarshal(rec.reply, pair); err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+func (s *replayedTx) CloseSend() error {
+	rec, err := s.next(recordCloseSend)
+	if err != nil {
+		return err
+	}
+	return rec.err
+}
+
+func (s *replayedTx) Header() (metadata.MD, error) { return metadata.MD{}, nil }
+func (s *replayedTx) Trailer() metadata.MD         { return metadata.MD{} }
+func (s *replayedTx) Context() context.Context     { return s.ctx }
+
+func (s *replayedTx) SendMsg(m any) error {
+	c, ok := m.(*remote.Cursor)
+	if !ok {
+		return fmt.Errorf("unexpected message %T", m)
+	}
+	return s.Send(c)
+}
+
+func (s *replayedTx) RecvMsg(m any) error {
+	pair, ok := m.(*remote.Pair)
+	if !ok {
+		return fmt.Errorf("unexpected message %T", m)
+	}
+	received, err := s.Recv()
+	if err != nil {
+		return err
+	}
+	proto.Merge(pair, received)
+	return nil
+}
@@ -0,0 +1,48 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package remotedb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayMalformedRecording(t *testing.T) {
+	valid := (&record{kind: recordUnary, method: "Version", req: []byte{1}, reply: []byte{2, 3}}).encode(nil)
+	replay, err := NewReplay(bytes.NewReader(valid))
+	require.NoError(t, err)
+	require.Len(t, replay.unary, 1)
+
+	withMethodLen := func(n uint64, data ...byte) []byte {
+		return append(binary.AppendUvarint([]byte{recordUnary, 0}, n), data...)
+	}
+	for name, recording := range map[string][]byte{
+		"huge length":      withMethodLen(math.MaxUint64),
+		"over the limit":   withMethodLen(maxRecordSize + 1),
+		"past the end":     withMethodLen(1<<40, 'V'),
+		"truncated field":  withMethodLen(7, 'V', 'e'),
+		"truncated record": valid[:len(valid)-1],
+		"no stream":        {recordUnary},
+	} {
+		_, err := NewReplay(bytes.NewReader(recording))
+		require.ErrorContains(t, err, "malformed recording", name)
+	}
+}
@@ -0,0 +1,91 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package remotedb
+
+import (
+	"math"
+	"sync"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+)
+
+// txCache keeps the values already read by a remote tx, so that point lookups of keys
+// which were streamed by a cursor (or looked up before) don't go to the server again.
+// Remote tx is a snapshot of the db until server renews it (see `remotedbserver.TxAgeRenew`): the cache is cleared
+// when a reply tells the tx sees a new view, so cached values are never older than the values server reads.
+// If limit is set, least recently used entries are evicted once keys and values take more than limit bytes.
+// It's safe for concurrent use, values read from another view than the cached ones are not put.
+type txCache struct {
+	lock  sync.Mutex
+	lru   *simplelru.LRU[txCacheKey, txCacheEntry]
+	size  int
+	limit int // 0 - unlimited
+	view  uint64
+}
+
+type txCacheKey struct {
+	table, k string
+}
+
+type txCacheEntry struct {
+	v     []byte
+	found bool // false for keys known to be absent
+}
+
+func (k txCacheKey) size(e txCacheEntry) int { return len(k.table) + len(k.k) + len(e.v) }
+
+func newTxCache(limit int, view uint64) *txCache {
+	c := &txCache{limit: limit, view: view}
+	c.lru, _ = simplelru.NewLRU[txCacheKey, txCacheEntry](math.MaxInt, func(k txCacheKey, e txCacheEntry) {
+		c.size -= k.size(e)
+	})
+	return c
+}
+
+func (c *txCache) get(table string, k []byte) (v []byte, found, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, ok := c.lru.Get(txCacheKey{table: table, k: string(k)})
+	return e.v, e.found, ok
+}
+
+// put - caches the value of k read from the given view of the tx
+func (c *txCache) put(view uint64, table string, k, v []byte, found bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	key, e := txCacheKey{table: table, k: string(k)}, txCacheEntry{v: v, found: found}
+	if view != c.view || (c.limit > 0 && key.size(e) > c.limit) {
+		return
+	}
+	if old, ok := c.lru.Peek(key); ok {
+		c.size -= key.size(old)
+	}
+	c.lru.Add(key, e)
+	c.size += key.size(e)
+	for c.limit > 0 && c.size > c.limit {
+		c.lru.RemoveOldest()
+	}
+}
+
+// clear - drops the values of the previous views
+func (c *txCache) clear(view uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.lru.Purge()
+	c.size = 0
+	c.view = view
+}
@@ -0,0 +1,72 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package remotedb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxCacheLimit(t *testing.T) {
+	require := require.New(t)
+	c := newTxCache(12, 0) // 3 entries of table "t", 1 byte keys and 2 bytes values
+
+	c.put(0, "t", []byte{1}, []byte{1, 1}, true)
+	c.put(0, "t", []byte{2}, []byte{2, 2}, true)
+	c.put(0, "t", []byte{3}, nil, false)
+	require.Equal(10, c.size)
+
+	// touch 1, so 2 is the least recently used
+	v, found, ok := c.get("t", []byte{1})
+	require.True(ok)
+	require.True(found)
+	require.Equal([]byte{1, 1}, v)
+
+	c.put(0, "t", []byte{4}, []byte{4, 4}, true)
+	_, _, ok = c.get("t", []byte{2})
+	require.False(ok)
+	_, found, ok = c.get("t", []byte{3})
+	require.True(ok)
+	require.False(found)
+	require.LessOrEqual(c.size, 12)
+
+	// re-putting a key doesn't count it twice
+	c.put(0, "t", []byte{4}, []byte{4, 4}, true)
+	require.Equal(10, c.size)
+
+	// values bigger than the limit are not cached
+	c.put(0, "t", []byte{5}, make([]byte, 16), true)
+	_, _, ok = c.get("t", []byte{5})
+	require.False(ok)
+
+	unlimited := newTxCache(0, 0)
+	for i := 0; i < 1_000; i++ {
+		unlimited.put(0, "t", []byte{byte(i), byte(i >> 8)}, []byte{1}, true)
+	}
+	require.Equal(1_000, unlimited.lru.Len())
+
+	// values of a previous view are dropped, and not put afterwards
+	unlimited.clear(1)
+	require.Zero(unlimited.size)
+	unlimited.put(0, "t", []byte{1}, []byte{1}, true)
+	_, _, ok = unlimited.get("t", []byte{1})
+	require.False(ok)
+	unlimited.put(1, "t", []byte{1}, []byte{1}, true)
+	_, _, ok = unlimited.get("t", []byte{1})
+	require.True(ok)
+}
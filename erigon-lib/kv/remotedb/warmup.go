@@ -0,0 +1,165 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package remotedb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ledgerwatch/log/v3"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/iter"
+	"github.com/ledgerwatch/erigon-lib/kv/order"
+)
+
+// statuses of a table in kv.RemoteWarmUpProgress
+const (
+	warmUpInProgress byte = iota // followed by the next key to copy
+	warmUpDone
+)
+
+// WarmUpCfg configures WarmUp.
+type WarmUpCfg struct {
+	Tables []string // e.g. kv.Headers, kv.HeaderCanonical and kv.Senders
+	// Workers is the amount of tables copied concurrently, each over its own remote txs
+	Workers int
+	// PageSize is the amount of pairs requested per Range page, capped by the server
+	PageSize int32
+	// BatchSize is the amount of pairs written per local tx, along with the progress of their table
+	BatchSize int
+	LogEvery  time.Duration
+}
+
+func DefaultWarmUpCfg(tables ...string) WarmUpCfg {
+	return WarmUpCfg{Tables: tables, Workers: 4, PageSize: 64 * 1024, BatchSize: 100_000, LogEvery: 30 * time.Second}
+}
+
+// WarmUp copies tables of src, usually a remote DB, into dst, e.g. when a service cold-starts against a remote node.
+// Tables are copied concurrently in batches, each read by a short-lived src tx (long read txs grow the database of
+// the server) and committed to dst with the progress of its table in kv.RemoteWarmUpProgress: an interrupted warm-up
+// resumes from the last committed batch, and completed tables are skipped. The batches of a table may thus be read
+// from different views of src, which is fine for append-mostly tables followed by regular sync.
+func WarmUp(ctx context.Context, src kv.RoDB, dst kv.RwDB, cfg WarmUpCfg, logger log.Logger) error {
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(max(cfg.Workers, 1))
+	for _, table := range cfg.Tables {
+		table := table
+		g.Go(func() error {
+			if err := warmUpTable(ctx, src, dst, table, cfg, logger); err != nil {
+				return fmt.Errorf("warm-up of %s: %w", table, err)
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+func warmUpTable(ctx context.Context, src kv.RoDB, dst kv.RwDB, table string, cfg WarmUpCfg, logger log.Logger) error {
+	var from []byte
+	done := false
+	if err := dst.View(ctx, func(tx kv.Tx) error {
+		progress, err := tx.GetOne(kv.RemoteWarmUpProgress, []byte(table))
+		if len(progress) > 0 {
+			done, from = progress[0] == warmUpDone, common.Copy(progress[1:])
+		}
+		return err
+	}); err != nil {
+		return err
+	}
+	if done {
+		logger.Debug("[warmup] already copied", "table", table)
+		return nil
+	}
+
+	batchSize := max(cfg.BatchSize, 1)
+	keys, values := make([][]byte, 0, batchSize), make([][]byte, 0, batchSize)
+	logEvery := time.NewTicker(max(cfg.LogEvery, time.Second))
+	defer logEvery.Stop()
+	copied := 0
+	for !done {
+		keys, values = keys[:0], values[:0]
+		var next []byte
+		if err := src.View(ctx, func(tx kv.Tx) (err error) {
+			done, err = forEachFrom(tx, table, from, cfg.PageSize, func(k, v []byte) bool {
+				// batches end between keys, so that the values of a dup-sorted key are copied at once
+				if len(keys) >= batchSize && !bytes.Equal(k, keys[len(keys)-1]) {
+					next = common.Copy(k)
+					return false
+				}
+				keys, values = append(keys, common.Copy(k)), append(values, common.Copy(v))
+				return true
+			})
+			return err
+		}); err != nil {
+			return err
+		}
+		if err := dst.Update(ctx, func(tx kv.RwTx) error {
+			for i := range keys {
+				if err := tx.Put(table, keys[i], values[i]); err != nil {
+					return err
+				}
+			}
+			progress := append([]byte{warmUpInProgress}, next...)
+			if done {
+				progress = []byte{warmUpDone}
+			}
+			return tx.Put(kv.RemoteWarmUpProgress, []byte(table), progress)
+		}); err != nil {
+			return err
+		}
+		copied += len(keys)
+		from = next
+		select {
+		case <-logEvery.C:
+			logger.Info("[warmup] copying", "table", table, "pairs", copied, "next", fmt.Sprintf("%x", next))
+		default:
+		}
+	}
+	logger.Info("[warmup] copied", "table", table, "pairs", copied)
+	return nil
+}
+
+// forEachFrom walks the table from the given key until walker returns false, and returns whether it reached the end.
+// Tables are read by large Range pages if tx is remote.
+func forEachFrom(roTx kv.Tx, table string, from []byte, pageSize int32, walker func(k, v []byte) bool) (bool, error) {
+	var it iter.KV
+	var err error
+	if remoteTx, ok := roTx.(*tx); ok {
+		it, err = remoteTx.rangePaged(table, from, nil, order.Asc, -1, pageSize)
+	} else {
+		it, err = roTx.Range(table, from, nil)
+	}
+	if err != nil {
+		return false, err
+	}
+	defer it.Close()
+	for it.HasNext() {
+		k, v, err := it.Next()
+		if err != nil {
+			return false, err
+		}
+		if !walker(k, v) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
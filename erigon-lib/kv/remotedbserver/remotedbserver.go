@@ -17,6 +17,7 @@
 package remotedbserver
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"errors"
@@ -28,6 +29,10 @@ import (
 	"time"
 
 	"github.com/ledgerwatch/log/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/emptypb"
 
@@ -51,8 +56,46 @@ import (
 // It's done by `renew` method: after `renew` call reader will see all changes committed after last `renew` call.
 //
 // Erigon has much Historical data - which is immutable: reading of historical data for hours still gives you consistant data.
+//
+// Renewing is the default `TxAgePolicy`, see `SetTxAgePolicy` for clients which need one snapshot for longer.
 const MaxTxTTL = 60 * time.Second
 
+// TxAgePolicy - what server does with a remote Tx living longer than its max age (`MaxTxTTL` by default)
+type TxAgePolicy int
+
+const (
+	TxAgeRenew    TxAgePolicy = iota // renew tx on each max age: reader sees changes committed since (Read Committed)
+	TxAgeWarn                        // keep tx snapshot, and log a warning on each max age: DB may grow meanwhile
+	TxAgeRollback                    // close Tx stream with an error and rollback tx once it reaches max age
+)
+
+var txAgePolicyNames = [...]string{TxAgeRenew: "renew", TxAgeWarn: "warn", TxAgeRollback: "rollback"}
+
+func (p TxAgePolicy) String() string {
+	if int(p) < len(txAgePolicyNames) {
+		return txAgePolicyNames[p]
+	}
+	return fmt.Sprintf("TxAgePolicy(%d)", int(p))
+}
+
+// ParseTxAgePolicy - parses the name of a policy: renew, warn or rollback
+func ParseTxAgePolicy(name string) (TxAgePolicy, error) {
+	for p, policyName := range txAgePolicyNames {
+		if name == policyName {
+			return TxAgePolicy(p), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown tx age policy %q, expected one of %v", name, txAgePolicyNames)
+}
+
+// DefaultMaxCursorsPerTx - how many cursors a client can keep open in one remote Tx stream.
+// Every cursor pins server memory and is renewed on each `MaxTxTTL`, so a client leaking cursors must be stopped.
+const DefaultMaxCursorsPerTx = 1_024
+
+// DefaultMaxKeySize - requests with bigger keys, values or table names are rejected. It's far above any key MDBX
+// can store, and only protects server from allocating and comparing garbage sent by misbehaving clients.
+const DefaultMaxKeySize = 64 * 1024
+
 // KvServiceAPIVersion - use it to track changes in API
 // 1.1.0 - added pending transactions, add methods eth_getRawTransactionByHash, eth_retRawTransactionByBlockHashAndIndex, eth_retRawTransactionByBlockNumberAndIndex| Yes     |                                            |
 // 1.2.0 - Added separated services for mining and txpool methods
@@ -84,6 +127,17 @@ type KvServer struct {
 	trace     bool
 	rangeStep int // make sure `s.with` has limited time
 	logger    log.Logger
+
+	maxCursorsPerTx int           // 0 - unlimited
+	txIdleTimeout   time.Duration // 0 - disabled
+	maxTxsPerConn   int           // 0 - unlimited
+	txMaxAge        time.Duration // 0 - disabled
+	maxKeySize      int           // 0 - unlimited
+	pageSizeLimit   int32
+	txAgePolicy     TxAgePolicy
+
+	connTxsLock sync.Mutex
+	connTxs     map[any]int // open Tx streams by connection, see `connOf`
 }
 
 type threadSafeTx struct {
@@ -109,9 +163,126 @@ func NewKvServer(ctx context.Context, db kv.RoDB, snapshots Snapshots, borSnapsh
 		txs:                map[uint64]*threadSafeTx{},
 		txsMapLock:         &sync.RWMutex{},
 		logger:             logger,
+		maxCursorsPerTx:    DefaultMaxCursorsPerTx,
+		txMaxAge:           MaxTxTTL,
+		maxKeySize:         DefaultMaxKeySize,
+		pageSizeLimit:      PageSizeLimit,
+		connTxs:            map[any]int{},
 	}
 }
 
+// SetTxLimits - overrides `DefaultMaxCursorsPerTx`, and closes remote Tx streams (rolling back their read transaction)
+// if client doesn't send any request during txIdleTimeout: it protects server from clients which opened Tx and forgot
+// about it. Zero value disables the limit, the idle timeout is disabled by default.
+// Must be called before server starts serving requests.
+func (s *KvServer) SetTxLimits(maxCursorsPerTx int, txIdleTimeout time.Duration) {
+	s.maxCursorsPerTx = maxCursorsPerTx
+	s.txIdleTimeout = txIdleTimeout
+}
+
+// SetConnLimits - limits the amount of remote Tx streams a client connection can keep open at once. Zero value (the
+// default) disables the limit. Connections are told apart by `StatsHandler`, or by the peer address if the grpc server
+// doesn't use it.
+// Must be called before server starts serving requests.
+func (s *KvServer) SetConnLimits(maxTxsPerConn int) {
+	s.maxTxsPerConn = maxTxsPerConn
+}
+
+type connIDKey struct{}
+
+// connTagger - tags the context of each connection with its own id
+type connTagger struct {
+	ids atomic.Uint64
+}
+
+func (t *connTagger) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return context.WithValue(ctx, connIDKey{}, t.ids.Add(1))
+}
+func (*connTagger) HandleConn(context.Context, stats.ConnStats) {}
+func (*connTagger) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+func (*connTagger) HandleRPC(context.Context, stats.RPCStats) {}
+
+// StatsHandler - grpc server option telling apart the client connections by `SetConnLimits`. Without it, connections
+// share a limit if they share a peer address, e.g. the ones to a unix socket.
+func StatsHandler() grpc.ServerOption {
+	return grpc.StatsHandler(&connTagger{})
+}
+
+// connOf - identifies the client connection of a stream, nil if it's unknown
+func connOf(ctx context.Context) any {
+	if id := ctx.Value(connIDKey{}); id != nil {
+		return id
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return nil
+}
+
+// openConnTx - counts a Tx stream of the connection of ctx, and returns the func releasing it
+func (s *KvServer) openConnTx(ctx context.Context) (release func(), err error) {
+	conn := connOf(ctx)
+	if s.maxTxsPerConn <= 0 || conn == nil {
+		return func() {}, nil
+	}
+	s.connTxsLock.Lock()
+	defer s.connTxsLock.Unlock()
+	if s.connTxs[conn] >= s.maxTxsPerConn {
+		return nil, fmt.Errorf("connection reached limit of open txs: %d", s.maxTxsPerConn)
+	}
+	s.connTxs[conn]++
+	return func() {
+		s.connTxsLock.Lock()
+		defer s.connTxsLock.Unlock()
+		if s.connTxs[conn]--; s.connTxs[conn] == 0 {
+			delete(s.connTxs, conn)
+		}
+	}, nil
+}
+
+// SetTxAgePolicy - overrides `MaxTxTTL` and what happens to remote Tx living longer. Zero maxAge disables the limit:
+// tx keeps its snapshot as long as it lives.
+// Must be called before server starts serving requests.
+func (s *KvServer) SetTxAgePolicy(maxAge time.Duration, policy TxAgePolicy) {
+	s.txMaxAge = maxAge
+	s.txAgePolicy = policy
+}
+
+// SetPayloadLimits - overrides `DefaultMaxKeySize` (zero value disables the limit) and `PageSizeLimit`:
+// the max amount of items returned by one page of Range/IndexRange.
+// Must be called before server starts serving requests.
+func (s *KvServer) SetPayloadLimits(maxKeySize int, pageSizeLimit int32) {
+	s.maxKeySize = maxKeySize
+	if pageSizeLimit > 0 {
+		s.pageSizeLimit = pageSizeLimit
+	}
+}
+
+// checkPayload - rejects requests with too big keys, values or table names
+func (s *KvServer) checkPayload(table string, keys ...[]byte) error {
+	if s.maxKeySize <= 0 {
+		return nil
+	}
+	if len(table) > s.maxKeySize {
+		return fmt.Errorf("table name of %d bytes exceeds limit %d", len(table), s.maxKeySize)
+	}
+	for _, k := range keys {
+		if len(k) > s.maxKeySize {
+			return fmt.Errorf("key of %d bytes exceeds limit %d", len(k), s.maxKeySize)
+		}
+	}
+	return nil
+}
+
+// SetTrace - enables logging of txs lifecycle and of every op they serve (with txn and cursor ids,
+// table, key size, duration and result size) - to debug slow remote readers.
+// Must be called before server starts serving requests.
+func (s *KvServer) SetTrace(trace bool) {
+	s.trace = trace
+}
+
 // Version returns the service-side interface version number
 func (s *KvServer) Version(context.Context, *emptypb.Empty) (*types.VersionReply, error) {
 	dbSchemaVersion := &kv.DBSchemaVersion
@@ -214,6 +385,11 @@ func (s *KvServer) with(id uint64, f func(kv.Tx) error) error {
 }
 
 func (s *KvServer) Tx(stream remote.KV_TxServer) error {
+	release, err := s.openConnTx(stream.Context())
+	if err != nil {
+		return fmt.Errorf("server-side error: %w", err)
+	}
+	defer release()
 	id, errBegin := s.begin(stream.Context())
 	if errBegin != nil {
 		return fmt.Errorf("server-side error: %w", errBegin)
@@ -231,6 +407,9 @@ func (s *KvServer) Tx(stream remote.KV_TxServer) error {
 		return fmt.Errorf("server-side error: %w", err)
 	}
 
+	renewed := &renewedTxStream{KV_TxServer: stream}
+	stream = renewed
+
 	var CursorID uint32
 	type CursorInfo struct {
 		bucket string
@@ -239,23 +418,79 @@ func (s *KvServer) Tx(stream remote.KV_TxServer) error {
 	}
 	cursors := map[uint32]*CursorInfo{}
 
-	txTicker := time.NewTicker(MaxTxTTL)
-	defer txTicker.Stop()
+	var aged, expired <-chan time.Time // nil channel - never fires
+	if s.txMaxAge > 0 {
+		if s.txAgePolicy == TxAgeRollback {
+			expiryTimer := time.NewTimer(s.txMaxAge)
+			defer expiryTimer.Stop()
+			expired = expiryTimer.C
+		} else {
+			txTicker := time.NewTicker(s.txMaxAge)
+			defer txTicker.Stop()
+			aged = txTicker.C
+		}
+	}
+	started := time.Now()
+
+	// requests are received in background - to be able to close Tx of client which doesn't send any requests
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	requests, recvErrs := make(chan *remote.Cursor), make(chan error, 1)
+	go func() {
+		for {
+			in, err := stream.Recv()
+			if err != nil {
+				recvErrs <- err
+				return
+			}
+			select {
+			case requests <- in:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	var idle <-chan time.Time // nil channel - never fires
+	resetIdle := func() {}
+	if s.txIdleTimeout > 0 {
+		idleTimer := time.NewTimer(s.txIdleTimeout)
+		defer idleTimer.Stop()
+		idle = idleTimer.C
+		resetIdle = func() {
+			if !idleTimer.Stop() {
+				select {
+				case <-idleTimer.C:
+				default:
+				}
+			}
+			idleTimer.Reset(s.txIdleTimeout)
+		}
+	}
 
 	// send all items to client, if k==nil - still send it to client and break loop
 	for {
-		in, recvErr := stream.Recv()
-		if recvErr != nil {
+		var in *remote.Cursor
+		select {
+		case in = <-requests:
+			resetIdle()
+		case recvErr := <-recvErrs:
 			if errors.Is(recvErr, io.EOF) { // termination
 				return nil
 			}
 			return fmt.Errorf("server-side error: %w", recvErr)
+		case <-idle:
+			return fmt.Errorf("server-side error: txn %d has no requests during %s", id, s.txIdleTimeout)
+		case <-expired:
+			return fmt.Errorf("server-side error: txn %d lived longer than %s", id, s.txMaxAge)
 		}
 
-		//TODO: protect against client - which doesn't send any requests
 		select {
 		default:
-		case <-txTicker.C:
+		case <-aged:
+			if s.txAgePolicy == TxAgeWarn {
+				s.logger.Warn("[kv_server] long-living read tx keeps its snapshot", "txn", id, "age", time.Since(started))
+				break
+			}
 			for _, c := range cursors { // save positions of cursor, will restore after Tx reopening
 				k, v, err := c.c.Current()
 				if err != nil {
@@ -269,6 +504,7 @@ func (s *KvServer) Tx(stream remote.KV_TxServer) error {
 				return err
 			}
 			if err := s.with(id, func(tx kv.Tx) error {
+				renewed.viewID = tx.ViewID()
 				for _, c := range cursors { // restore all cursors position
 					var err error
 					c.c, err = tx.Cursor(c.bucket)
@@ -299,6 +535,10 @@ func (s *KvServer) Tx(stream remote.KV_TxServer) error {
 			}
 		}
 
+		if err := s.checkPayload(in.BucketName, in.K, in.V); err != nil {
+			return fmt.Errorf("server-side error: txn %d: %w", id, err)
+		}
+
 		var c kv.Cursor
 		if in.BucketName == "" {
 			cInfo, ok := cursors[in.Cursor]
@@ -307,6 +547,9 @@ func (s *KvServer) Tx(stream remote.KV_TxServer) error {
 			}
 			c = cInfo.c
 		}
+		if (in.Op == remote.Op_OPEN || in.Op == remote.Op_OPEN_DUP_SORT) && s.maxCursorsPerTx > 0 && len(cursors) >= s.maxCursorsPerTx {
+			return fmt.Errorf("server-side error: txn %d reached limit of open cursors: %d", id, s.maxCursorsPerTx)
+		}
 		switch in.Op {
 		case remote.Op_OPEN:
 			CursorID++
@@ -362,12 +605,51 @@ func (s *KvServer) Tx(stream remote.KV_TxServer) error {
 		default:
 		}
 
-		if err := handleOp(c, stream, in); err != nil {
+		if !s.trace {
+			if err := handleOp(c, stream, in); err != nil {
+				return fmt.Errorf("server-side error: %w", err)
+			}
+			continue
+		}
+		var table string
+		if cInfo, ok := cursors[in.Cursor]; ok {
+			table = cInfo.bucket
+		}
+		traced, start := &tracedTxStream{KV_TxServer: stream}, time.Now()
+		err := handleOp(c, traced, in)
+		s.logger.Info("[kv_server] op", "txn", id, "cursor", in.Cursor, "op", in.Op, "table", table,
+			"k", len(in.K), "v", len(in.V), "took", time.Since(start), "result", traced.size, "err", err)
+		if err != nil {
 			return fmt.Errorf("server-side error: %w", err)
 		}
 	}
 }
 
+// renewedTxStream - sends the view id of the renewed tx with the next reply, so that client can drop what it cached
+// from the previous one
+type renewedTxStream struct {
+	remote.KV_TxServer
+	viewID uint64 // 0 - not renewed since the last reply
+}
+
+func (s *renewedTxStream) Send(p *remote.Pair) error {
+	if s.viewID != 0 {
+		p.ViewId, s.viewID = s.viewID, 0
+	}
+	return s.KV_TxServer.Send(p)
+}
+
+// tracedTxStream - counts bytes of keys and values sent to client
+type tracedTxStream struct {
+	remote.KV_TxServer
+	size int
+}
+
+func (s *tracedTxStream) Send(p *remote.Pair) error {
+	s.size += len(p.K) + len(p.V)
+	return s.KV_TxServer.Send(p)
+}
+
 func handleOp(c kv.Cursor, stream remote.KV_TxServer, in *remote.Cursor) error {
 	var k, v []byte
 	var err error
@@ -534,6 +816,9 @@ func (s *StateChangePubSub) remove(id uint) {
 //
 
 func (s *KvServer) DomainGet(_ context.Context, req *remote.DomainGetReq) (reply *remote.DomainGetReply, err error) {
+	if err := s.checkPayload(req.Table, req.K, req.K2); err != nil {
+		return nil, err
+	}
 	domainName, err := kv.String2Domain(req.Table)
 	if err != nil {
 		return nil, err
@@ -562,6 +847,9 @@ func (s *KvServer) DomainGet(_ context.Context, req *remote.DomainGetReq) (reply
 	return reply, nil
 }
 func (s *KvServer) HistorySeek(_ context.Context, req *remote.HistorySeekReq) (reply *remote.HistorySeekReply, err error) {
+	if err := s.checkPayload(req.Table, req.K); err != nil {
+		return nil, err
+	}
 	reply = &remote.HistorySeekReply{}
 	if err := s.with(req.TxId, func(tx kv.Tx) error {
 		ttx, ok := tx.(kv.TemporalTx)
@@ -582,6 +870,9 @@ func (s *KvServer) HistorySeek(_ context.Context, req *remote.HistorySeekReq) (r
 const PageSizeLimit = 4 * 4096
 
 func (s *KvServer) IndexRange(_ context.Context, req *remote.IndexRangeReq) (*remote.IndexRangeReply, error) {
+	if err := s.checkPayload(req.Table, req.K); err != nil {
+		return nil, err
+	}
 	reply := &remote.IndexRangeReply{}
 	from, limit := int(req.FromTs), int(req.Limit)
 	if req.PageToken != "" {
@@ -591,8 +882,8 @@ func (s *KvServer) IndexRange(_ context.Context, req *remote.IndexRangeReq) (*re
 		}
 		from, limit = int(pagination.NextTimeStamp), int(pagination.Limit)
 	}
-	if req.PageSize <= 0 || req.PageSize > PageSizeLimit {
-		req.PageSize = PageSizeLimit
+	if req.PageSize <= 0 || req.PageSize > s.pageSizeLimit {
+		req.PageSize = s.pageSizeLimit
 	}
 
 	if err := s.with(req.TxId, func(tx kv.Tx) error {
@@ -605,7 +896,7 @@ func (s *KvServer) IndexRange(_ context.Context, req *remote.IndexRangeReq) (*re
 			return err
 		}
 		defer it.Close()
-		for it.HasNext() {
+		for len(reply.Timestamps) < int(req.PageSize) && it.HasNext() {
 			v, err := it.Next()
 			if err != nil {
 				return err
@@ -613,7 +904,7 @@ func (s *KvServer) IndexRange(_ context.Context, req *remote.IndexRangeReq) (*re
 			reply.Timestamps = append(reply.Timestamps, v)
 			limit--
 		}
-		if len(reply.Timestamps) == PageSizeLimit && it.HasNext() {
+		if len(reply.Timestamps) == int(req.PageSize) && it.HasNext() {
 			next, err := it.Next()
 			if err != nil {
 				return err
@@ -631,49 +922,85 @@ func (s *KvServer) IndexRange(_ context.Context, req *remote.IndexRangeReq) (*re
 }
 
 func (s *KvServer) Range(_ context.Context, req *remote.RangeReq) (*remote.Pairs, error) {
+	if err := s.checkPayload(req.Table, req.FromPrefix, req.ToPrefix); err != nil {
+		return nil, err
+	}
 	from, limit := req.FromPrefix, int(req.Limit)
+	var nextValue []byte // value of `from` a DupSort page resumes from, nil to resume from the first one
 	if req.PageToken != "" {
 		var pagination remote.ParisPagination
-		if err := unmarshalPagination(req.PageToken, &pagination); err != nil {
+		var err error
+		if nextValue, err = unmarshalRangePagination(req.PageToken, &pagination); err != nil {
 			return nil, err
 		}
 		from, limit = pagination.NextKey, int(pagination.Limit)
 	}
-	if req.PageSize <= 0 || req.PageSize > PageSizeLimit {
-		req.PageSize = PageSizeLimit
+	if req.PageSize <= 0 || req.PageSize > s.pageSizeLimit {
+		req.PageSize = s.pageSizeLimit
 	}
 
-	reply := &remote.Pairs{}
+	// pages of DupSort tables may end within the values of a key, their token holding the value to resume from
+	cfg := s.kv.AllTables()[req.Table]
+	dupSort := cfg.Flags&kv.DupSort != 0 && !cfg.AutoDupSortKeysConversion
+	resumed := req.PageToken != ""
+
+	reply, full := &remote.Pairs{}, false
+	done := func() bool { return full || limit == 0 }
+	add := func(k, v []byte) (err error) {
+		if len(reply.Keys) >= int(req.PageSize) {
+			full = true
+			var next []byte
+			if dupSort {
+				next = append([]byte{}, v...)
+			}
+			reply.NextPageToken, err = marshalRangePagination(&remote.ParisPagination{NextKey: k, Limit: int64(limit)}, next)
+			return err
+		}
+		reply.Keys = append(reply.Keys, k)
+		reply.Values = append(reply.Values, v)
+		if limit > 0 {
+			limit--
+		}
+		return nil
+	}
 	var err error
 	if err = s.with(req.TxId, func(tx kv.Tx) error {
+		if dupSort && nextValue != nil {
+			if from, err = rangeDups(tx, req.Table, from, nextValue, req.OrderAscend, done, add); err != nil {
+				return err
+			}
+			if done() || from == nil {
+				return nil
+			}
+			// the key went on with is out of the range, which doesn't include toPrefix
+			if c := bytes.Compare(from, req.ToPrefix); req.ToPrefix != nil && (req.OrderAscend && c >= 0 || !req.OrderAscend && c <= 0) {
+				return nil
+			}
+		}
 		var it iter.KV
 		if req.OrderAscend {
-			it, err = tx.RangeAscend(req.Table, from, req.ToPrefix, limit)
+			it, err = tx.RangeAscend(req.Table, from, req.ToPrefix, -1)
 			if err != nil {
 				return err
 			}
 		} else {
-			it, err = tx.RangeDescend(req.Table, from, req.ToPrefix, limit)
+			it, err = tx.RangeDescend(req.Table, from, req.ToPrefix, -1)
 			if err != nil {
 				return err
 			}
 		}
-		for it.HasNext() {
+		defer it.Close()
+		// limit is applied here, the pairs skipped below must not count
+		for !done() && it.HasNext() {
 			k, v, err := it.Next()
 			if err != nil {
 				return err
 			}
-			reply.Keys = append(reply.Keys, k)
-			reply.Values = append(reply.Values, v)
-			limit--
-		}
-		if len(reply.Keys) == PageSizeLimit && it.HasNext() {
-			nextK, _, err := it.Next()
-			if err != nil {
-				return err
+			// descending range starts from the last key having `from` as prefix, which was already sent
+			if resumed && !req.OrderAscend && bytes.Compare(k, from) > 0 {
+				continue
 			}
-			reply.NextPageToken, err = marshalPagination(&remote.ParisPagination{NextKey: nextK, Limit: int64(limit)})
-			if err != nil {
+			if err = add(k, v); err != nil {
 				return err
 			}
 		}
@@ -684,6 +1011,98 @@ func (s *KvServer) Range(_ context.Context, req *remote.RangeReq) (*remote.Pairs
 	return reply, nil
 }
 
+// rangeDups adds the values of key from value on, in the order of the range, until done. It returns the key the range
+// goes on with: the next one, or the previous one when descending, nil at the end of the table.
+func rangeDups(tx kv.Tx, table string, key, value []byte, ascend bool, done func() bool, add func(k, v []byte) error) ([]byte, error) {
+	c, err := tx.CursorDupSort(table)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	if k, _, err := c.SeekExact(key); err != nil || k == nil { // deleted since, e.g. by a renew of the tx
+		return key, err
+	}
+	v, err := c.SeekBothRange(key, value)
+	if err != nil {
+		return nil, err
+	}
+	if !ascend {
+		if v == nil { // the values left are all smaller than value
+			if _, _, err = c.SeekExact(key); err == nil {
+				v, err = c.LastDup()
+			}
+		} else if !bytes.Equal(v, value) {
+			_, v, err = c.PrevDup()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	for v != nil && !done() {
+		if err = add(key, v); err != nil {
+			return nil, err
+		}
+		if ascend {
+			_, v, err = c.NextDup()
+		} else {
+			_, v, err = c.PrevDup()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if done() {
+		return nil, nil
+	}
+	if _, _, err = c.SeekExact(key); err != nil {
+		return nil, err
+	}
+	var next []byte
+	if ascend {
+		next, _, err = c.NextNoDup()
+	} else {
+		next, _, err = c.PrevNoDup()
+	}
+	return next, err
+}
+
+// rangeNextValueField is the field of Range page tokens holding the value of NextKey the next page of a DupSort table
+// starts from, appended to remote.ParisPagination as an unknown field as the message has none.
+const rangeNextValueField protowire.Number = 3
+
+func marshalRangePagination(p *remote.ParisPagination, nextValue []byte) (string, error) {
+	if nextValue != nil {
+		p.ProtoReflect().SetUnknown(protowire.AppendBytes(protowire.AppendTag(nil, rangeNextValueField, protowire.BytesType), nextValue))
+	}
+	return marshalPagination(p)
+}
+
+func unmarshalRangePagination(pageToken string, p *remote.ParisPagination) (nextValue []byte, err error) {
+	if err = unmarshalPagination(pageToken, p); err != nil {
+		return nil, err
+	}
+	for b := p.ProtoReflect().GetUnknown(); len(b) > 0; {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		if num == rangeNextValueField && typ == protowire.BytesType {
+			var v []byte
+			if v, n = protowire.ConsumeBytes(b); n >= 0 {
+				nextValue = append([]byte{}, v...)
+			}
+		} else {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return nextValue, nil
+}
+
 // see: https://cloud.google.com/apis/design/design_patterns
 func marshalPagination(m proto.Message) (string, error) {
 	pageToken, err := proto.Marshal(m)
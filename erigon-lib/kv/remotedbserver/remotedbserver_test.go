@@ -18,14 +18,23 @@ package remotedbserver
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"net"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/ledgerwatch/log/v3"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
 
+	"github.com/ledgerwatch/erigon-lib/common"
+	remote "github.com/ledgerwatch/erigon-lib/gointerfaces/remoteproto"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
 )
@@ -139,3 +148,299 @@ func TestKVServerSnapshotsReturnsEmptyIfNoBlockSnapshots(t *testing.T) {
 	require.Empty(t, reply.BlocksFiles)
 	require.Empty(t, reply.HistoryFiles)
 }
+
+type testTxStream struct {
+	remote.KV_TxServer // nil - only methods below are used by KvServer.Tx
+	ctx                context.Context
+	in                 chan *remote.Cursor
+	out                chan *remote.Pair
+}
+
+func newTestTxStream(ctx context.Context) *testTxStream {
+	return &testTxStream{ctx: ctx, in: make(chan *remote.Cursor), out: make(chan *remote.Pair, 16)}
+}
+
+func (s *testTxStream) Context() context.Context { return s.ctx }
+func (s *testTxStream) Send(p *remote.Pair) error {
+	s.out <- p
+	return nil
+}
+func (s *testTxStream) Recv() (*remote.Cursor, error) {
+	select {
+	case in, ok := <-s.in:
+		if !ok {
+			return nil, io.EOF
+		}
+		return in, nil
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	}
+}
+
+func TestKvServerTxCursorsLimit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := NewKvServer(ctx, memdb.NewTestDB(t), nil, nil, nil, log.New())
+	s.SetTxLimits(2, 0)
+
+	stream := newTestTxStream(ctx)
+	done := make(chan error, 1)
+	go func() { done <- s.Tx(stream) }()
+	<-stream.out // tx id
+
+	for i := 0; i < 2; i++ {
+		stream.in <- &remote.Cursor{Op: remote.Op_OPEN, BucketName: kv.PlainState}
+		require.NotZero(t, (<-stream.out).CursorId)
+	}
+	stream.in <- &remote.Cursor{Op: remote.Op_OPEN, BucketName: kv.PlainState}
+	require.ErrorContains(t, <-done, "limit of open cursors")
+}
+
+func TestKvServerTxIdleTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := NewKvServer(ctx, memdb.NewTestDB(t), nil, nil, nil, log.New())
+	s.SetTxLimits(0, 50*time.Millisecond)
+
+	stream := newTestTxStream(ctx)
+	done := make(chan error, 1)
+	go func() { done <- s.Tx(stream) }()
+	<-stream.out // tx id
+
+	// requests keep tx alive
+	for i := 0; i < 3; i++ {
+		time.Sleep(20 * time.Millisecond)
+		stream.in <- &remote.Cursor{Op: remote.Op_OPEN, BucketName: kv.PlainState}
+		<-stream.out
+	}
+	select {
+	case err := <-done:
+		require.ErrorContains(t, err, "has no requests during")
+	case <-time.After(5 * time.Second):
+		t.Fatal("idle tx was not closed")
+	}
+	s.txsMapLock.RLock()
+	defer s.txsMapLock.RUnlock()
+	require.Empty(t, s.txs)
+}
+
+func TestKvServerConnLimits(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := NewKvServer(ctx, memdb.NewTestDB(t), nil, nil, nil, log.New())
+	s.SetConnLimits(2)
+
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer(StatsHandler())
+	remote.RegisterKVServer(grpcServer, s)
+	go grpcServer.Serve(listener) //nolint:errcheck
+	defer grpcServer.Stop()
+	dial := func() remote.KVClient {
+		conn, err := grpc.DialContext(ctx, "", grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }))
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		return remote.NewKVClient(conn)
+	}
+	openTx := func(client remote.KVClient) (remote.KV_TxClient, error) {
+		stream, err := client.Tx(ctx)
+		require.NoError(t, err)
+		_, err = stream.Recv() // tx id
+		return stream, err
+	}
+
+	client := dial()
+	first, err := openTx(client)
+	require.NoError(t, err)
+	_, err = openTx(client)
+	require.NoError(t, err)
+	_, err = openTx(client)
+	require.ErrorContains(t, err, "limit of open txs")
+	// other connections have their own limit
+	_, err = openTx(dial())
+	require.NoError(t, err)
+
+	require.NoError(t, first.CloseSend())
+	_, err = first.Recv()
+	require.ErrorIs(t, err, io.EOF)
+	_, err = openTx(client)
+	require.NoError(t, err)
+}
+
+func TestKvServerTxAgePolicy(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db := memdb.NewTestDB(t)
+	s := NewKvServer(ctx, db, nil, nil, nil, log.New())
+	s.SetTxLimits(0, 0)
+	s.SetTxAgePolicy(20*time.Millisecond, TxAgeWarn)
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error { return tx.Put(kv.Headers, []byte{0}, []byte{0}) }))
+
+	stream := newTestTxStream(ctx)
+	done := make(chan error, 1)
+	go func() { done <- s.Tx(stream) }()
+	<-stream.out // tx id
+	stream.in <- &remote.Cursor{Op: remote.Op_OPEN, BucketName: kv.Headers}
+	cursorID := (<-stream.out).CursorId
+	stream.in <- &remote.Cursor{Op: remote.Op_SEEK_EXACT, Cursor: cursorID, K: []byte{0}}
+	require.Equal(t, []byte{0}, (<-stream.out).V)
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error { return tx.Put(kv.Headers, []byte{1}, []byte{1}) }))
+
+	// tx older than its max age keeps its snapshot
+	for i := 0; i < 3; i++ {
+		time.Sleep(30 * time.Millisecond)
+		stream.in <- &remote.Cursor{Op: remote.Op_SEEK_EXACT, Cursor: cursorID, K: []byte{1}}
+		require.Nil(t, (<-stream.out).V)
+	}
+	close(stream.in)
+	require.NoError(t, <-done)
+
+	s.SetTxAgePolicy(50*time.Millisecond, TxAgeRollback)
+	stream = newTestTxStream(ctx)
+	go func() { done <- s.Tx(stream) }()
+	<-stream.out // tx id
+	select {
+	case err := <-done:
+		require.ErrorContains(t, err, "lived longer than")
+	case <-time.After(5 * time.Second):
+		t.Fatal("old tx was not closed")
+	}
+	s.txsMapLock.RLock()
+	defer s.txsMapLock.RUnlock()
+	require.Empty(t, s.txs)
+}
+
+func TestParseTxAgePolicy(t *testing.T) {
+	for _, policy := range []TxAgePolicy{TxAgeRenew, TxAgeWarn, TxAgeRollback} {
+		parsed, err := ParseTxAgePolicy(policy.String())
+		require.NoError(t, err)
+		require.Equal(t, policy, parsed)
+	}
+	_, err := ParseTxAgePolicy("forever")
+	require.Error(t, err)
+}
+
+func TestKvServerTxClientClose(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := NewKvServer(ctx, memdb.NewTestDB(t), nil, nil, nil, log.New())
+
+	stream := newTestTxStream(ctx)
+	done := make(chan error, 1)
+	go func() { done <- s.Tx(stream) }()
+	<-stream.out // tx id
+	close(stream.in)
+	require.NoError(t, <-done)
+}
+
+func TestKvServerTxTrace(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db := memdb.NewTestDB(t)
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+		return tx.Put(kv.HeaderNumber, []byte{1}, []byte{1, 2, 3})
+	}))
+
+	logger := log.New()
+	ops := make(chan []interface{}, 16)
+	logger.SetHandler(log.FuncHandler(func(r *log.Record) error {
+		if r.Msg == "[kv_server] op" {
+			ops <- r.Ctx
+		}
+		return nil
+	}))
+	s := NewKvServer(ctx, db, nil, nil, nil, logger)
+	s.SetTrace(true)
+
+	stream := newTestTxStream(ctx)
+	done := make(chan error, 1)
+	go func() { done <- s.Tx(stream) }()
+	<-stream.out // tx id
+
+	stream.in <- &remote.Cursor{Op: remote.Op_OPEN, BucketName: kv.HeaderNumber}
+	cursorID := (<-stream.out).CursorId
+	stream.in <- &remote.Cursor{Cursor: cursorID, Op: remote.Op_SEEK_EXACT, K: []byte{1}}
+	require.Equal(t, []byte{1, 2, 3}, (<-stream.out).V)
+	close(stream.in)
+	require.NoError(t, <-done)
+
+	fields := map[string]interface{}{}
+	ctxFields := <-ops
+	for i := 0; i+1 < len(ctxFields); i += 2 {
+		fields[ctxFields[i].(string)] = ctxFields[i+1]
+	}
+	require.Equal(t, cursorID, fields["cursor"])
+	require.Equal(t, remote.Op_SEEK_EXACT, fields["op"])
+	require.Equal(t, kv.HeaderNumber, fields["table"])
+	require.Equal(t, 1, fields["k"])
+	require.Equal(t, 4, fields["result"])
+}
+
+func TestKvServerPayloadLimits(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := NewKvServer(ctx, memdb.NewTestDB(t), nil, nil, nil, log.New())
+	s.SetPayloadLimits(16, 0)
+
+	stream := newTestTxStream(ctx)
+	done := make(chan error, 1)
+	go func() { done <- s.Tx(stream) }()
+	txID := (<-stream.out).TxId
+
+	_, err := s.Range(ctx, &remote.RangeReq{TxId: txID, Table: kv.PlainState, FromPrefix: make([]byte, 17)})
+	require.ErrorContains(t, err, "exceeds limit")
+	_, err = s.Range(ctx, &remote.RangeReq{TxId: txID, Table: kv.PlainState, FromPrefix: make([]byte, 16)})
+	require.NoError(t, err)
+
+	stream.in <- &remote.Cursor{Op: remote.Op_OPEN, BucketName: kv.PlainState}
+	cursorID := (<-stream.out).CursorId
+	stream.in <- &remote.Cursor{Cursor: cursorID, Op: remote.Op_SEEK, K: make([]byte, 17)}
+	require.ErrorContains(t, <-done, "exceeds limit")
+}
+
+func TestKvServerRangeDupSortPages(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db := memdb.NewTestDB(t)
+	// the key 02 has more values than a page holds
+	all := []string{"01:01", "02:01", "02:02", "02:03", "02:04", "02:05", "03:01"}
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+		for _, pair := range all {
+			k, v := pair[:2], pair[3:]
+			require.NoError(t, tx.Put(kv.AccountChangeSet, common.FromHex(k), common.FromHex(v)))
+		}
+		return nil
+	}))
+	s := NewKvServer(ctx, db, nil, nil, nil, log.New())
+	s.SetPayloadLimits(DefaultMaxKeySize, 2)
+
+	stream := newTestTxStream(ctx)
+	go func() { _ = s.Tx(stream) }()
+	txID := (<-stream.out).TxId
+
+	pairs := func(ascend bool, from, to []byte, limit int64) (res []string) {
+		req := &remote.RangeReq{TxId: txID, Table: kv.AccountChangeSet, FromPrefix: from, ToPrefix: to, OrderAscend: ascend, Limit: limit}
+		for {
+			reply, err := s.Range(ctx, req)
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(reply.Keys), 2)
+			for i := range reply.Keys {
+				res = append(res, fmt.Sprintf("%x:%x", reply.Keys[i], reply.Values[i]))
+			}
+			if reply.NextPageToken == "" {
+				return res
+			}
+			req.PageToken = reply.NextPageToken
+		}
+	}
+	require.Equal(t, all, pairs(true, nil, nil, -1))
+	require.Equal(t, all[:4], pairs(true, nil, nil, 4))
+	require.Equal(t, all[1:6], pairs(true, []byte{2}, []byte{3}, -1))
+	var reversed []string
+	for i := len(all) - 1; i >= 0; i-- {
+		reversed = append(reversed, all[i])
+	}
+	require.Equal(t, reversed, pairs(false, nil, nil, -1))
+	require.Equal(t, reversed[:5], pairs(false, nil, nil, 5))
+	require.Equal(t, reversed[1:6], pairs(false, []byte{2}, []byte{1}, -1))
+}
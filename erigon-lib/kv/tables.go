@@ -308,6 +308,9 @@ const (
 	// Progress of sync stages: stageName -> stageData
 	SyncStageProgress = "SyncStage"
 
+	// Progress of the copies of remote tables by remotedb.WarmUp: tableName -> status + next key
+	RemoteWarmUpProgress = "RemoteWarmUpProgress"
+
 	Clique             = "Clique"
 	CliqueSeparate     = "CliqueSeparate"
 	CliqueSnapshot     = "CliqueSnapshot"
@@ -511,6 +514,11 @@ const (
 
 	StatesProcessingProgress = "StatesProcessingProgress"
 
+	// [container id + chunk index] => [chunk of the SSZ encoding], [container id] => [encoding length]
+	SolidContainerChunks = "SolidContainerChunks"
+	// [sha256 of the leaves of a subtree] => [generation + internal nodes of the subtree]
+	SolidSubtreeCache = "SolidSubtreeCache"
+
 	//Diagnostics tables
 	DiagSystemInfo = "DiagSystemInfo"
 	DiagSyncStages = "DiagSyncStages"
@@ -576,6 +584,7 @@ var ChaindataTables = []string{
 	CliqueLastSnapshot,
 	CliqueSnapshot,
 	SyncStageProgress,
+	RemoteWarmUpProgress,
 	PlainState,
 	PlainContractCode,
 	AccountChangeSet,
@@ -721,6 +730,8 @@ var ChaindataTables = []string{
 	ActiveValidatorIndicies,
 	EffectiveBalancesDump,
 	BalancesDump,
+	SolidContainerChunks,
+	SolidSubtreeCache,
 }
 
 const (
@@ -143,7 +143,7 @@ type Ethereum struct {
 
 	// DB interfaces
 	chainDB    kv.RwDB
-	privateAPI *grpc.Server
+	privateAPI *privateapi.Listeners
 
 	engine consensus.Engine
 
@@ -363,6 +363,10 @@ func New(ctx context.Context, stack *node.Node, config *ethconfig.Config, logger
 	}
 
 	kvRPC := remotedbserver.NewKvServer(ctx, backend.chainDB, allSnapshots, allBorSnapshots, agg, logger)
+	kvRPC.SetTxLimits(stack.Config().PrivateApiMaxCursorsPerTx, stack.Config().PrivateApiTxIdleTimeout)
+	kvRPC.SetConnLimits(stack.Config().PrivateApiMaxTxsPerConn)
+	kvRPC.SetPayloadLimits(stack.Config().PrivateApiMaxKeySize, stack.Config().PrivateApiPageSizeLimit)
+	kvRPC.SetTxAgePolicy(stack.Config().PrivateApiTxMaxAge, stack.Config().PrivateApiTxAgePolicy)
 	backend.notifications.StateChangesConsumer = kvRPC
 	backend.kvRPC = kvRPC
 
@@ -752,25 +756,37 @@ func New(ctx context.Context, stack *node.Node, config *ethconfig.Config, logger
 	miningRPC = privateapi.NewMiningServer(ctx, backend, ethashApi, logger)
 
 	var creds credentials.TransportCredentials
-	if stack.Config().PrivateApiAddr != "" {
+	if stack.Config().PrivateApiAddr != "" || len(stack.Config().PrivateApiExtraListeners) > 0 {
 		if stack.Config().TLSConnection {
 			creds, err = grpcutil.TLS(stack.Config().TLSCACert, stack.Config().TLSCertFile, stack.Config().TLSKeyFile)
 			if err != nil {
 				return nil, err
 			}
 		}
-		backend.privateAPI, err = privateapi.StartGrpc(
-			kvRPC,
-			ethBackendRPC,
-			backend.txPoolGrpcServer,
-			miningRPC,
-			stack.Config().PrivateApiAddr,
-			stack.Config().PrivateApiRateLimit,
-			creds,
-			stack.Config().HealthCheck,
-			logger)
-		if err != nil {
-			return nil, fmt.Errorf("private api: %w", err)
+		backend.privateAPI = privateapi.NewListeners(kvRPC, ethBackendRPC, backend.txPoolGrpcServer, miningRPC, logger)
+		listeners := []privateapi.ListenerCfg{}
+		if stack.Config().PrivateApiAddr != "" {
+			listeners = append(listeners, privateapi.ListenerCfg{Addr: stack.Config().PrivateApiAddr, Creds: creds})
+		}
+		for _, extra := range stack.Config().PrivateApiExtraListeners {
+			listenAddr, withTLS := privateapi.ParseListenerAddr(extra.Addr)
+			if withTLS && creds == nil {
+				return nil, fmt.Errorf("private api: %s requires --tls", extra.Addr)
+			}
+			cfg := privateapi.ListenerCfg{Addr: listenAddr, RateLimit: extra.RateLimit, Allow: extra.Allow}
+			if withTLS {
+				cfg.Creds = creds
+			}
+			listeners = append(listeners, cfg)
+		}
+		for _, cfg := range listeners {
+			if cfg.RateLimit == 0 {
+				cfg.RateLimit = stack.Config().PrivateApiRateLimit
+			}
+			cfg.HealthCheck = stack.Config().HealthCheck
+			if err = backend.privateAPI.Add(cfg); err != nil {
+				return nil, fmt.Errorf("private api: %w", err)
+			}
 		}
 	}
 
@@ -1571,16 +1587,7 @@ func (s *Ethereum) Stop() error {
 		s.downloader.Close()
 	}
 	if s.privateAPI != nil {
-		shutdownDone := make(chan bool)
-		go func() {
-			defer close(shutdownDone)
-			s.privateAPI.GracefulStop()
-		}()
-		select {
-		case <-time.After(1 * time.Second): // shutdown deadline
-			s.privateAPI.Stop()
-		case <-shutdownDone:
-		}
+		s.privateAPI.Stop()
 	}
 	libcommon.SafeClose(s.sentriesClient.Hd.QuitPoWMining)
 	_ = s.engine.Close()
@@ -3,6 +3,8 @@ package privateapi
 import (
 	"fmt"
 	"net"
+	"net/netip"
+	"strings"
 
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/grpcutil"
 	remote "github.com/ledgerwatch/erigon-lib/gointerfaces/remoteproto"
@@ -19,13 +21,21 @@ import (
 func StartGrpc(kv *remotedbserver.KvServer, ethBackendSrv *EthBackendServer, txPoolServer txpool_proto.TxpoolServer,
 	miningServer txpool_proto.MiningServer, addr string, rateLimit uint32, creds credentials.TransportCredentials,
 	healthCheck bool, logger log.Logger) (*grpc.Server, error) {
-	logger.Info("Starting private RPC server", "on", addr)
-	lis, err := net.Listen("tcp", addr)
+	return startGrpc(kv, ethBackendSrv, txPoolServer, miningServer, ListenerCfg{Addr: addr, RateLimit: rateLimit, Creds: creds, HealthCheck: healthCheck}, logger)
+}
+
+func startGrpc(kv *remotedbserver.KvServer, ethBackendSrv *EthBackendServer, txPoolServer txpool_proto.TxpoolServer,
+	miningServer txpool_proto.MiningServer, cfg ListenerCfg, logger log.Logger) (*grpc.Server, error) {
+	logger.Info("Starting private RPC server", "on", cfg.Addr)
+	lis, err := listen(cfg.Addr)
 	if err != nil {
-		return nil, fmt.Errorf("could not create listener: %w, addr=%s", err, addr)
+		return nil, fmt.Errorf("could not create listener: %w, addr=%s", err, cfg.Addr)
+	}
+	if len(cfg.Allow) > 0 {
+		lis = &allowListener{Listener: lis, allow: cfg.Allow, logger: logger}
 	}
 
-	grpcServer := grpcutil.NewServer(rateLimit, creds)
+	grpcServer := grpcutil.NewServer(cfg.RateLimit, cfg.Creds, remotedbserver.StatsHandler())
 	remote.RegisterETHBACKENDServer(grpcServer, ethBackendSrv)
 	if txPoolServer != nil {
 		txpool_proto.RegisterTxpoolServer(grpcServer, txPoolServer)
@@ -36,12 +46,12 @@ func StartGrpc(kv *remotedbserver.KvServer, ethBackendSrv *EthBackendServer, txP
 
 	remote.RegisterKVServer(grpcServer, kv)
 	var healthServer *health.Server
-	if healthCheck {
+	if cfg.HealthCheck {
 		healthServer = health.NewServer()
 		grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
 	}
 	go func() {
-		if healthCheck {
+		if cfg.HealthCheck {
 			defer healthServer.Shutdown()
 		}
 		if err := grpcServer.Serve(lis); err != nil {
@@ -51,3 +61,50 @@ func StartGrpc(kv *remotedbserver.KvServer, ethBackendSrv *EthBackendServer, txP
 
 	return grpcServer, nil
 }
+
+// listen listens on a unix socket if addr is "unix://<path>", on a tcp address otherwise.
+func listen(addr string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(addr, "unix://"); ok {
+		return net.Listen("unix", path)
+	}
+	return net.Listen("tcp", addr)
+}
+
+// allowListener closes the connections from outside its allowed networks before they reach the grpc server.
+type allowListener struct {
+	net.Listener
+	allow  []netip.Prefix
+	logger log.Logger
+}
+
+func (l *allowListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if l.allowed(conn.RemoteAddr()) {
+			return conn, nil
+		}
+		l.logger.Debug("private RPC server rejected connection", "on", l.Addr(), "from", conn.RemoteAddr())
+		conn.Close()
+	}
+}
+
+func (l *allowListener) allowed(addr net.Addr) bool {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	ip, ok := netip.AddrFromSlice(tcpAddr.IP)
+	if !ok {
+		return false
+	}
+	ip = ip.Unmap()
+	for _, network := range l.allow {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package privateapi
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	txpool_proto "github.com/ledgerwatch/erigon-lib/gointerfaces/txpoolproto"
+	"github.com/ledgerwatch/erigon-lib/kv/remotedbserver"
+	"github.com/ledgerwatch/log/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// ShutdownDeadline is how long stopping a listener waits for in-flight RPCs before cancelling them.
+const ShutdownDeadline = time.Second
+
+// ListenerCfg configures one listener of the private API.
+type ListenerCfg struct {
+	Addr        string                           // "host:port", or "unix://<path>" for a unix socket
+	RateLimit   uint32                           // max concurrent streams per connection
+	Creds       credentials.TransportCredentials // nil to serve without TLS, e.g. on localhost or a unix socket
+	HealthCheck bool
+	Allow       []netip.Prefix // networks clients may connect from, empty allows all; tcp only
+}
+
+// Listeners serves the private API on several listeners at once, e.g. a plain localhost one for a local rpcdaemon
+// and a TLS one for remote services. Listeners are added and removed at runtime, all serving the same services: the
+// limits of the remote database, set on the KvServer, are shared by all the listeners, only RateLimit and Allow are
+// their own.
+type Listeners struct {
+	kv            *remotedbserver.KvServer
+	ethBackendSrv *EthBackendServer
+	txPoolServer  txpool_proto.TxpoolServer
+	miningServer  txpool_proto.MiningServer
+	logger        log.Logger
+
+	mu      sync.Mutex
+	servers map[string]*grpc.Server // by address
+}
+
+func NewListeners(kv *remotedbserver.KvServer, ethBackendSrv *EthBackendServer, txPoolServer txpool_proto.TxpoolServer,
+	miningServer txpool_proto.MiningServer, logger log.Logger) *Listeners {
+	return &Listeners{kv: kv, ethBackendSrv: ethBackendSrv, txPoolServer: txPoolServer, miningServer: miningServer,
+		logger: logger, servers: map[string]*grpc.Server{}}
+}
+
+// ParseListenerAddr parses an address of the node flags: "tls://host:port" is served with TLS, "unix://<path>" and
+// "host:port" without.
+func ParseListenerAddr(addr string) (listenAddr string, withTLS bool) {
+	if listenAddr, ok := strings.CutPrefix(addr, "tls://"); ok {
+		return listenAddr, true
+	}
+	return addr, false
+}
+
+// Add starts serving on cfg.Addr.
+func (l *Listeners) Add(cfg ListenerCfg) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.servers[cfg.Addr]; ok {
+		return fmt.Errorf("private api already listens on %s", cfg.Addr)
+	}
+	if len(cfg.Allow) > 0 && strings.HasPrefix(cfg.Addr, "unix://") {
+		return fmt.Errorf("private api: allow-list not supported on unix socket %s", cfg.Addr)
+	}
+	grpcServer, err := startGrpc(l.kv, l.ethBackendSrv, l.txPoolServer, l.miningServer, cfg, l.logger)
+	if err != nil {
+		return err
+	}
+	l.servers[cfg.Addr] = grpcServer
+	return nil
+}
+
+// Remove stops serving on addr, and returns false if it wasn't listened on.
+func (l *Listeners) Remove(addr string) bool {
+	l.mu.Lock()
+	grpcServer, ok := l.servers[addr]
+	delete(l.servers, addr)
+	l.mu.Unlock()
+	if ok {
+		l.logger.Info("Stopping private RPC server", "on", addr)
+		stopGracefully(grpcServer)
+	}
+	return ok
+}
+
+// Addrs returns the addresses listened on.
+func (l *Listeners) Addrs() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	addrs := make([]string, 0, len(l.servers))
+	for addr := range l.servers {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	return addrs
+}
+
+// Stop stops all the listeners.
+func (l *Listeners) Stop() {
+	l.mu.Lock()
+	servers := l.servers
+	l.servers = map[string]*grpc.Server{}
+	l.mu.Unlock()
+	var wg sync.WaitGroup
+	for _, grpcServer := range servers {
+		grpcServer := grpcServer
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stopGracefully(grpcServer)
+		}()
+	}
+	wg.Wait()
+}
+
+func stopGracefully(grpcServer *grpc.Server) {
+	shutdownDone := make(chan bool)
+	go func() {
+		defer close(shutdownDone)
+		grpcServer.GracefulStop()
+	}()
+	select {
+	case <-time.After(ShutdownDeadline):
+		grpcServer.Stop()
+	case <-shutdownDone:
+	}
+}
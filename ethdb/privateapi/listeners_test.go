@@ -0,0 +1,91 @@
+package privateapi
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"path/filepath"
+	"testing"
+	"time"
+
+	remote "github.com/ledgerwatch/erigon-lib/gointerfaces/remoteproto"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon-lib/kv/remotedbserver"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestListeners(t *testing.T) {
+	ctx, logger := context.Background(), log.New()
+	kvServer := remotedbserver.NewKvServer(ctx, memdb.NewTestDB(t), nil, nil, nil, logger)
+	listeners := NewListeners(kvServer, &EthBackendServer{}, nil, nil, logger)
+	defer listeners.Stop()
+
+	tcpAddr, unixAddr := "127.0.0.1:0", "unix://"+filepath.Join(t.TempDir(), "erigon.sock")
+	require.NoError(t, listeners.Add(ListenerCfg{Addr: tcpAddr}))
+	require.NoError(t, listeners.Add(ListenerCfg{Addr: unixAddr, HealthCheck: true}))
+	require.Error(t, listeners.Add(ListenerCfg{Addr: unixAddr}))
+	require.Equal(t, []string{tcpAddr, unixAddr}, listeners.Addrs())
+
+	cc, err := grpc.Dial(unixAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer cc.Close()
+	_, err = remote.NewKVClient(cc).Version(ctx, &emptypb.Empty{})
+	require.NoError(t, err)
+
+	require.True(t, listeners.Remove(unixAddr))
+	require.False(t, listeners.Remove(unixAddr))
+	require.Equal(t, []string{tcpAddr}, listeners.Addrs())
+	require.NoError(t, listeners.Add(ListenerCfg{Addr: unixAddr})) // the socket is released
+}
+
+func TestListenersAllow(t *testing.T) {
+	ctx, logger := context.Background(), log.New()
+	kvServer := remotedbserver.NewKvServer(ctx, memdb.NewTestDB(t), nil, nil, nil, logger)
+	listeners := NewListeners(kvServer, &EthBackendServer{}, nil, nil, logger)
+	defer listeners.Stop()
+
+	freeAddr := func() string {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer lis.Close()
+		return lis.Addr().String()
+	}
+	version := func(addr string) error {
+		cc, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		require.NoError(t, err)
+		defer cc.Close()
+		callCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		_, err = remote.NewKVClient(cc).Version(callCtx, &emptypb.Empty{})
+		return err
+	}
+
+	denyingAddr, allowingAddr := freeAddr(), freeAddr()
+	require.NoError(t, listeners.Add(ListenerCfg{Addr: denyingAddr, Allow: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}}))
+	require.NoError(t, listeners.Add(ListenerCfg{Addr: allowingAddr,
+		Allow: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("127.0.0.1/32")}}))
+	require.Error(t, version(denyingAddr))
+	require.NoError(t, version(allowingAddr))
+
+	require.Error(t, listeners.Add(ListenerCfg{Addr: "unix://" + filepath.Join(t.TempDir(), "erigon.sock"),
+		Allow: []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")}}))
+}
+
+func TestParseListenerAddr(t *testing.T) {
+	for addr, expected := range map[string]struct {
+		listenAddr string
+		withTLS    bool
+	}{
+		"127.0.0.1:9090":          {"127.0.0.1:9090", false},
+		"unix:///tmp/erigon.sock": {"unix:///tmp/erigon.sock", false},
+		"tls://0.0.0.0:9091":      {"0.0.0.0:9091", true},
+	} {
+		listenAddr, withTLS := ParseListenerAddr(addr)
+		require.Equal(t, expected.listenAddr, listenAddr, addr)
+		require.Equal(t, expected.withTLS, withTLS, addr)
+	}
+}
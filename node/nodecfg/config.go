@@ -18,15 +18,20 @@ package nodecfg
 
 import (
 	"fmt"
+	"net/netip"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/c2h5oh/datasize"
 	"github.com/ledgerwatch/erigon-lib/common/datadir"
 	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/remotedbserver"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli/httpcfg"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/paths"
@@ -147,6 +152,17 @@ type Config struct {
 	// empty string means not to start the listener
 	PrivateApiAddr      string
 	PrivateApiRateLimit uint32
+	// More listeners of the private api, see ParsePrivateApiListener
+	PrivateApiExtraListeners []PrivateApiListener
+	// Limits of the remote database served by the private api, zero value disables a limit
+	PrivateApiMaxCursorsPerTx int
+	PrivateApiTxIdleTimeout   time.Duration
+	PrivateApiMaxTxsPerConn   int
+	PrivateApiMaxKeySize      int
+	PrivateApiPageSizeLimit   int32
+	// Max age of a remote database transaction and what happens to older ones, zero max age means never
+	PrivateApiTxMaxAge    time.Duration
+	PrivateApiTxAgePolicy remotedbserver.TxAgePolicy
 
 	staticNodesWarning  bool
 	trustedNodesWarning bool
@@ -171,6 +187,59 @@ type Config struct {
 // IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
 // account the set data folders as well as the designated platform we're currently
 // running on.
+// PrivateApiListener is an extra listener of the private api, with its own limit and access control.
+type PrivateApiListener struct {
+	Addr      string         // see privateapi.ParseListenerAddr
+	RateLimit uint32         // 0 means PrivateApiRateLimit
+	Allow     []netip.Prefix // networks clients may connect from, empty allows all
+}
+
+// ParsePrivateApiListener parses "addr[?ratelimit=N][&allow=cidr]...", e.g.
+// "tls://0.0.0.0:9091?ratelimit=32&allow=10.0.0.0/8&allow=192.168.1.7". allow may be repeated and takes a network or
+// a single ip, it is not supported on unix sockets.
+func ParsePrivateApiListener(spec string) (PrivateApiListener, error) {
+	addr, rawOpts, _ := strings.Cut(spec, "?")
+	l := PrivateApiListener{Addr: addr}
+	if addr == "" {
+		return l, fmt.Errorf("%q: empty address", spec)
+	}
+	opts, err := url.ParseQuery(rawOpts)
+	if err != nil {
+		return l, fmt.Errorf("%q: %w", spec, err)
+	}
+	for name, values := range opts {
+		switch name {
+		case "ratelimit":
+			if len(values) != 1 {
+				return l, fmt.Errorf("%q: ratelimit given %d times", spec, len(values))
+			}
+			rateLimit, err := strconv.ParseUint(values[0], 10, 32)
+			if err != nil || rateLimit == 0 {
+				return l, fmt.Errorf("%q: ratelimit must be a positive integer, got %q", spec, values[0])
+			}
+			l.RateLimit = uint32(rateLimit)
+		case "allow":
+			if strings.HasPrefix(addr, "unix://") {
+				return l, fmt.Errorf("%q: allow is not supported on unix sockets", spec)
+			}
+			for _, value := range values {
+				network, err := netip.ParsePrefix(value)
+				if err != nil {
+					ip, ipErr := netip.ParseAddr(value)
+					if ipErr != nil {
+						return l, fmt.Errorf("%q: allow: %w", spec, err)
+					}
+					network = netip.PrefixFrom(ip, ip.BitLen())
+				}
+				l.Allow = append(l.Allow, network.Masked())
+			}
+		default:
+			return l, fmt.Errorf("%q: unknown option %q", spec, name)
+		}
+	}
+	return l, nil
+}
+
 func (c *Config) IPCEndpoint() string {
 	// Short circuit if IPC has not been enabled
 	if c.IPCPath == "" {
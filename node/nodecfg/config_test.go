@@ -18,8 +18,10 @@ package nodecfg_test
 
 import (
 	"context"
+	"net/netip"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"testing"
 
@@ -94,3 +96,44 @@ func TestIPCPathResolution(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the options of the extra private api listeners are parsed, and bad ones rejected.
+func TestParsePrivateApiListener(t *testing.T) {
+	var tests = []struct {
+		Spec     string
+		Listener nodecfg.PrivateApiListener
+		Err      bool
+	}{
+		{"127.0.0.1:9090", nodecfg.PrivateApiListener{Addr: "127.0.0.1:9090"}, false},
+		{"unix:///tmp/erigon.sock?ratelimit=8", nodecfg.PrivateApiListener{Addr: "unix:///tmp/erigon.sock", RateLimit: 8}, false},
+		{"tls://0.0.0.0:9091?ratelimit=32&allow=10.1.2.3/8&allow=192.168.1.7&allow=::1", nodecfg.PrivateApiListener{
+			Addr:      "tls://0.0.0.0:9091",
+			RateLimit: 32,
+			Allow:     []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.168.1.7/32"), netip.MustParsePrefix("::1/128")},
+		}, false},
+		{"", nodecfg.PrivateApiListener{}, true},
+		{"?ratelimit=8", nodecfg.PrivateApiListener{}, true},
+		{"127.0.0.1:9090?ratelimit=0", nodecfg.PrivateApiListener{}, true},
+		{"127.0.0.1:9090?ratelimit=-1", nodecfg.PrivateApiListener{}, true},
+		{"127.0.0.1:9090?ratelimit=8&ratelimit=9", nodecfg.PrivateApiListener{}, true},
+		{"127.0.0.1:9090?allow=10.0.0.0/33", nodecfg.PrivateApiListener{}, true},
+		{"127.0.0.1:9090?allow=localhost", nodecfg.PrivateApiListener{}, true},
+		{"127.0.0.1:9090?deny=10.0.0.0/8", nodecfg.PrivateApiListener{}, true},
+		{"127.0.0.1:9090?allow=10.0.0.0/8;", nodecfg.PrivateApiListener{}, true},
+		{"unix:///tmp/erigon.sock?allow=10.0.0.0/8", nodecfg.PrivateApiListener{}, true},
+	}
+	for _, test := range tests {
+		listener, err := nodecfg.ParsePrivateApiListener(test.Spec)
+		if test.Err {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %+v", test.Spec, listener)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.Spec, err)
+		} else if !reflect.DeepEqual(listener, test.Listener) {
+			t.Errorf("%q: listener mismatch: have %+v, want %+v", test.Spec, listener, test.Listener)
+		}
+	}
+}
@@ -18,6 +18,7 @@ package nodecfg
 
 import (
 	"github.com/ledgerwatch/erigon-lib/direct"
+	"github.com/ledgerwatch/erigon-lib/kv/remotedbserver"
 
 	"github.com/ledgerwatch/erigon/p2p"
 	"github.com/ledgerwatch/erigon/p2p/nat"
@@ -44,6 +45,12 @@ var DefaultConfig = Config{
 	HTTPTimeouts:     rpccfg.DefaultHTTPTimeouts,
 	WSPort:           DefaultWSPort,
 	WSModules:        []string{"net", "web3"},
+
+	PrivateApiMaxCursorsPerTx: remotedbserver.DefaultMaxCursorsPerTx,
+	PrivateApiMaxKeySize:      remotedbserver.DefaultMaxKeySize,
+	PrivateApiPageSizeLimit:   remotedbserver.PageSizeLimit,
+	PrivateApiTxMaxAge:        remotedbserver.MaxTxTTL,
+	PrivateApiTxAgePolicy:     remotedbserver.TxAgeRenew,
 	P2P: p2p.Config{
 		ListenAddr:      ":30303",
 		ProtocolVersion: []uint{direct.ETH68, direct.ETH67}, // No need to specify direct.ETH66, because 1 sentry is used for both 66 and 67
@@ -43,6 +43,14 @@ var DefaultFlags = []cli.Flag{
 	&DatabaseVerbosityFlag,
 	&PrivateApiAddr,
 	&PrivateApiRateLimit,
+	&PrivateApiExtraAddrs,
+	&PrivateApiMaxCursorsPerTx,
+	&PrivateApiTxIdleTimeout,
+	&PrivateApiMaxTxsPerConn,
+	&PrivateApiMaxKeySize,
+	&PrivateApiPageSizeLimit,
+	&PrivateApiTxMaxAge,
+	&PrivateApiTxAgePolicy,
 	&EtlBufferSizeFlag,
 	&TLSFlag,
 	&TLSCertFlag,
@@ -18,6 +18,7 @@ import (
 	"github.com/ledgerwatch/erigon-lib/etl"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon-lib/kv/remotedbserver"
 	"github.com/ledgerwatch/log/v3"
 	"github.com/spf13/pflag"
 	"github.com/urfave/cli/v2"
@@ -62,6 +63,43 @@ var (
 		Usage: "Amount of requests server handle simultaneously - requests over this limit will wait. Increase it - if clients see 'request timeout' while server load is low - it means your 'hot data' is small or have much RAM. ",
 		Value: kv.ReadersLimit - 128,
 	}
+	PrivateApiExtraAddrs = cli.StringFlag{
+		Name:  "private.api.extra.addrs",
+		Usage: "Comma separated list of more addresses to serve the private api on: host:port, unix:///path/to/socket, or tls://host:port to serve with the --tls certificate. An address can take options in url query form: ratelimit=N overrides --private.api.ratelimit, allow=cidr (repeatable, not on unix sockets) only accepts clients from that network. example: unix:///tmp/erigon.sock,tls://0.0.0.0:9091?ratelimit=32&allow=10.0.0.0/8",
+	}
+	PrivateApiMaxCursorsPerTx = cli.IntFlag{
+		Name:  "private.api.tx.maxcursors",
+		Usage: "Max amount of cursors a remote db transaction can keep open, 0 means unlimited",
+		Value: remotedbserver.DefaultMaxCursorsPerTx,
+	}
+	PrivateApiTxIdleTimeout = cli.DurationFlag{
+		Name:  "private.api.tx.idletimeout",
+		Usage: "Close remote db transactions which don't receive any request during this time, 0 means never",
+	}
+	PrivateApiMaxTxsPerConn = cli.IntFlag{
+		Name:  "private.api.conn.maxtxs",
+		Usage: "Max amount of remote db transactions a client connection can keep open, 0 means unlimited",
+	}
+	PrivateApiMaxKeySize = cli.IntFlag{
+		Name:  "private.api.maxkeysize",
+		Usage: "Reject remote db requests with bigger keys, values or table names, 0 means unlimited",
+		Value: remotedbserver.DefaultMaxKeySize,
+	}
+	PrivateApiPageSizeLimit = cli.IntFlag{
+		Name:  "private.api.pagesize",
+		Usage: "Max amount of items in one page of remote db ranges",
+		Value: remotedbserver.PageSizeLimit,
+	}
+	PrivateApiTxMaxAge = cli.DurationFlag{
+		Name:  "private.api.tx.maxage",
+		Usage: "Max age of remote db transactions, older ones are handled by --private.api.tx.agepolicy, 0 means never",
+		Value: remotedbserver.MaxTxTTL,
+	}
+	PrivateApiTxAgePolicy = cli.StringFlag{
+		Name:  "private.api.tx.agepolicy",
+		Usage: "What to do with remote db transactions older than --private.api.tx.maxage: renew (the client keeps reading a newer state), warn (log and keep the old state) or rollback (fail them)",
+		Value: remotedbserver.TxAgeRenew.String(),
+	}
 
 	PruneFlag = cli.StringFlag{
 		Name: "prune",
@@ -581,6 +619,29 @@ func setPrivateApi(ctx *cli.Context, cfg *nodecfg.Config) {
 		log.Warn("private.api.ratelimit is too big", "force", maxRateLimit)
 		cfg.PrivateApiRateLimit = maxRateLimit
 	}
+	cfg.PrivateApiExtraListeners = nil
+	for _, spec := range libcommon.CliString2Array(ctx.String(PrivateApiExtraAddrs.Name)) {
+		listener, err := nodecfg.ParsePrivateApiListener(spec)
+		if err != nil {
+			utils.Fatalf("Invalid %s: %v", PrivateApiExtraAddrs.Name, err)
+		}
+		if listener.RateLimit > maxRateLimit {
+			log.Warn("private.api.extra.addrs ratelimit is too big", "addr", listener.Addr, "force", maxRateLimit)
+			listener.RateLimit = maxRateLimit
+		}
+		cfg.PrivateApiExtraListeners = append(cfg.PrivateApiExtraListeners, listener)
+	}
+	cfg.PrivateApiMaxCursorsPerTx = ctx.Int(PrivateApiMaxCursorsPerTx.Name)
+	cfg.PrivateApiTxIdleTimeout = ctx.Duration(PrivateApiTxIdleTimeout.Name)
+	cfg.PrivateApiMaxTxsPerConn = ctx.Int(PrivateApiMaxTxsPerConn.Name)
+	cfg.PrivateApiMaxKeySize = ctx.Int(PrivateApiMaxKeySize.Name)
+	cfg.PrivateApiPageSizeLimit = int32(ctx.Int(PrivateApiPageSizeLimit.Name))
+	cfg.PrivateApiTxMaxAge = ctx.Duration(PrivateApiTxMaxAge.Name)
+	txAgePolicy, err := remotedbserver.ParseTxAgePolicy(ctx.String(PrivateApiTxAgePolicy.Name))
+	if err != nil {
+		utils.Fatalf("Invalid %s: %v", PrivateApiTxAgePolicy.Name, err)
+	}
+	cfg.PrivateApiTxAgePolicy = txAgePolicy
 	if ctx.Bool(TLSFlag.Name) {
 		certFile := ctx.String(TLSCertFlag.Name)
 		keyFile := ctx.String(TLSKeyFlag.Name)